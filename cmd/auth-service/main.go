@@ -22,7 +22,7 @@ func main() {
 
 	grpcServer := grpc.NewServer()
 
-	authService := service.NewAuthService()
+	authService := service.NewAuthService(cfg)
 	authHandler := handler.NewAuthHandler(authService)
 
 	auth.RegisterAuthServiceServer(grpcServer, authHandler)
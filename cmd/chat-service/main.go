@@ -1,29 +1,87 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net"
 
 	"golang-chat/internal/chat/handler"
+	"golang-chat/internal/chat/middleware"
+	"golang-chat/internal/chat/repository"
 	"golang-chat/internal/chat/service"
+	"golang-chat/internal/chat/storage"
+	"golang-chat/internal/rest-auth/database"
 	"golang-chat/pkg/config"
+	"golang-chat/proto/auth"
 	"golang-chat/proto/chat"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
 	cfg := config.Load()
 
+	// Подключаемся к базе данных через GORM
+	db, err := database.ConnectToPostgres(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer database.CloseDatabase(db)
+
+	// Выполняем автоматическую миграцию таблиц чатов и индекса поиска
+	if err := repository.Migrate(db); err != nil {
+		log.Printf("⚠️ Warning: Database migration failed: %v", err)
+		log.Println("🔄 Continuing without migration...")
+	} else {
+		log.Println("✅ Database migration completed successfully")
+	}
+
 	lis, err := net.Listen("tcp", cfg.ChatServicePort)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	// Подключаемся к Auth Service для проверки токенов
+	authConn, err := grpc.NewClient(cfg.AuthServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to auth service: %v", err)
+	}
+	defer authConn.Close()
+
+	authInterceptor := middleware.NewAuthInterceptor(auth.NewAccessServiceClient(authConn))
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(authInterceptor.Unary()),
+		grpc.StreamInterceptor(authInterceptor.Stream()),
+	)
 
-	chatService := service.NewChatService()
-	chatHandler := handler.NewChatHandler(chatService)
+	chatRepository := repository.NewGormChatRepository(db)
+	userDirectory := service.NewAuthUserDirectory(auth.NewUserServiceClient(authConn))
+	chatService := service.NewChatService(chatRepository, userDirectory, service.ChatServiceConfig{
+		MaxPinnedMessages:   cfg.MaxPinnedMessages,
+		MessageKeyRetention: cfg.MessageKeyRetention,
+		UserMessageLimit:    service.RateLimit{Rate: cfg.UserMessageRate, Burst: cfg.UserMessageBurst},
+		ChatMessageLimit:    service.RateLimit{Rate: cfg.ChatMessageRate, Burst: cfg.ChatMessageBurst},
+		WebhookMaxAttempts:  cfg.WebhookMaxAttempts,
+		WebhookRetryBase:    cfg.WebhookRetryBase,
+		WebhookTimeout:      cfg.WebhookTimeout,
+	})
+
+	// Фоновые задачи: истечение индикаторов набора и присутствия,
+	// отправка отложенных и удаление истекших сообщений, доставка webhook'ов
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go chatService.Run(ctx)
+
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+	attachmentService := service.NewAttachmentService(chatRepository, blobStore, cfg.AttachmentMaxSize)
+
+	chatHandler := handler.NewChatHandler(chatService, attachmentService)
 
 	chat.RegisterChatServiceServer(grpcServer, chatHandler)
 
@@ -32,3 +90,21 @@ func main() {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
+
+// newBlobStore создает хранилище вложений согласно BLOB_STORE
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.BlobStore {
+	case "local":
+		return storage.NewLocalBlobStore(cfg.BlobDir)
+	case "s3":
+		return storage.NewS3BlobStore(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", cfg.BlobStore)
+	}
+}
@@ -12,12 +12,15 @@ import (
 	"golang-chat/internal/rest-auth/service"
 	"golang-chat/internal/rest-auth/validation"
 	"golang-chat/pkg/config"
+	"golang-chat/proto/chat"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -51,6 +54,15 @@ func main() {
 	// Создаем Auth Handler
 	authHandler := handler.NewAuthHandler(authService, validator)
 
+	// Подключаемся к Chat Service для входящих webhook'ов ботов
+	chatConn, err := grpc.NewClient(cfg.ChatServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to chat service: %v", err)
+	}
+	defer chatConn.Close()
+
+	incomingWebhookHandler := handler.NewIncomingWebhookHandler(chat.NewChatServiceClient(chatConn), validator)
+
 	// Создаем Fiber приложение
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -96,6 +108,10 @@ func main() {
 	protected.Get("/profile", authHandler.GetProfile)
 	protected.Post("/logout", authHandler.Logout)
 
+	// Входящие webhook'и: внешние системы пишут в чаты от имени ботов
+	webhooks := app.Group("/api/webhooks")
+	webhooks.Post("/incoming/:chat_id", incomingWebhookHandler.Post)
+
 	// Запускаем HTTP сервер
 	log.Println("REST Auth Service starting on :8080")
 	log.Fatal(app.Listen(":8080"))
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 
+	"golang-chat/internal/auth/model"
 	"golang-chat/internal/auth/service"
 	"golang-chat/proto/auth"
 )
@@ -28,15 +29,7 @@ func (h *AuthHandler) Create(ctx context.Context, req *auth.CreateUserRequest) (
 		return &auth.CreateUserResponse{Error: err.Error()}, nil
 	}
 
-	return &auth.CreateUserResponse{
-		User: &auth.User{
-			Id:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		},
-	}, nil
+	return &auth.CreateUserResponse{User: toProtoUser(user)}, nil
 }
 
 func (h *AuthHandler) Get(ctx context.Context, req *auth.GetUserRequest) (*auth.GetUserResponse, error) {
@@ -45,15 +38,7 @@ func (h *AuthHandler) Get(ctx context.Context, req *auth.GetUserRequest) (*auth.
 		return &auth.GetUserResponse{Error: err.Error()}, nil
 	}
 
-	return &auth.GetUserResponse{
-		User: &auth.User{
-			Id:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		},
-	}, nil
+	return &auth.GetUserResponse{User: toProtoUser(user)}, nil
 }
 
 func (h *AuthHandler) GetList(ctx context.Context, req *auth.GetUserListRequest) (*auth.GetUserListResponse, error) {
@@ -71,6 +56,46 @@ func (h *AuthHandler) Delete(ctx context.Context, req *auth.DeleteUserRequest) (
 	return &auth.DeleteUserResponse{Error: "Not implemented yet"}, nil
 }
 
+func (h *AuthHandler) CreateBot(ctx context.Context, req *auth.CreateBotRequest) (*auth.CreateBotResponse, error) {
+	ownerID, err := h.authService.ValidateToken(req.AccessToken)
+	if err != nil {
+		return &auth.CreateBotResponse{Error: err.Error()}, nil
+	}
+
+	bot, token, err := h.authService.CreateBot(ownerID, req.Username)
+	if err != nil {
+		return &auth.CreateBotResponse{Error: err.Error()}, nil
+	}
+
+	return &auth.CreateBotResponse{Bot: toProtoUser(bot), BotToken: token}, nil
+}
+
+func (h *AuthHandler) RotateBotToken(ctx context.Context, req *auth.RotateBotTokenRequest) (*auth.RotateBotTokenResponse, error) {
+	ownerID, err := h.authService.ValidateToken(req.AccessToken)
+	if err != nil {
+		return &auth.RotateBotTokenResponse{Error: err.Error()}, nil
+	}
+
+	token, err := h.authService.RotateBotToken(ownerID, req.BotId)
+	if err != nil {
+		return &auth.RotateBotTokenResponse{Error: err.Error()}, nil
+	}
+
+	return &auth.RotateBotTokenResponse{BotToken: token}, nil
+}
+
+func toProtoUser(user *model.User) *auth.User {
+	return &auth.User{
+		Id:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IsBot:     user.IsBot,
+		OwnerId:   user.OwnerID,
+		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
 // Auth Service Methods
 func (h *AuthHandler) Login(ctx context.Context, req *auth.LoginRequest) (*auth.LoginResponse, error) {
 	accessToken, refreshToken, err := h.authService.Login(req.Username, req.Password)
@@ -96,7 +121,7 @@ func (h *AuthHandler) GetRefreshToken(ctx context.Context, req *auth.GetRefreshT
 
 // Access Service Methods
 func (h *AuthHandler) Check(ctx context.Context, req *auth.CheckAccessRequest) (*auth.CheckAccessResponse, error) {
-	userID, err := h.authService.ValidateToken(req.AccessToken)
+	userID, isBot, err := h.authService.Authenticate(req.AccessToken)
 	if err != nil {
 		return &auth.CheckAccessResponse{
 			HasAccess: false,
@@ -107,5 +132,6 @@ func (h *AuthHandler) Check(ctx context.Context, req *auth.CheckAccessRequest) (
 	return &auth.CheckAccessResponse{
 		HasAccess: true,
 		UserId:    userID,
+		IsBot:     isBot,
 	}, nil
 }
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"golang-chat/internal/auth/service"
+	"golang-chat/pkg/config"
+	"golang-chat/proto/auth"
+)
+
+func TestAuthHandler_BotTokens(t *testing.T) {
+	h := NewAuthHandler(service.NewAuthService(&config.Config{JWTSecret: "test-secret"}))
+	ctx := context.Background()
+
+	login := func(username string) string {
+		t.Helper()
+		h.Create(ctx, &auth.CreateUserRequest{Username: username, Email: username + "@example.com", Password: "Password1!"})
+		resp, _ := h.Login(ctx, &auth.LoginRequest{Username: username, Password: "Password1!"})
+		if resp.Error != "" {
+			t.Fatalf("Login failed: %s", resp.Error)
+		}
+		return resp.AccessToken
+	}
+	ownerToken := login("owner")
+	otherToken := login("mallory")
+
+	created, _ := h.CreateBot(ctx, &auth.CreateBotRequest{AccessToken: ownerToken, Username: "deploy"})
+	if created.Error != "" || !created.Bot.IsBot || created.BotToken == "" {
+		t.Fatalf("CreateBot failed: %+v", created)
+	}
+
+	check, _ := h.Check(ctx, &auth.CheckAccessRequest{AccessToken: created.BotToken})
+	if !check.HasAccess || !check.IsBot || check.UserId != created.Bot.Id {
+		t.Errorf("Expected Check to accept the bot token, got %+v", check)
+	}
+	check, _ = h.Check(ctx, &auth.CheckAccessRequest{AccessToken: ownerToken})
+	if !check.HasAccess || check.IsBot {
+		t.Errorf("Expected Check to accept the user token, got %+v", check)
+	}
+
+	if resp, _ := h.RotateBotToken(ctx, &auth.RotateBotTokenRequest{AccessToken: otherToken, BotId: created.Bot.Id}); resp.Error == "" {
+		t.Error("Expected a non-owner to be refused")
+	}
+
+	rotated, _ := h.RotateBotToken(ctx, &auth.RotateBotTokenRequest{AccessToken: ownerToken, BotId: created.Bot.Id})
+	if rotated.Error != "" || rotated.BotToken == "" {
+		t.Fatalf("RotateBotToken failed: %+v", rotated)
+	}
+	if check, _ := h.Check(ctx, &auth.CheckAccessRequest{AccessToken: created.BotToken}); check.HasAccess || check.Error == "" {
+		t.Errorf("Expected the old token to be rejected, got %+v", check)
+	}
+	if check, _ := h.Check(ctx, &auth.CheckAccessRequest{AccessToken: rotated.BotToken}); !check.HasAccess || !check.IsBot {
+		t.Errorf("Expected the new token to be accepted, got %+v", check)
+	}
+}
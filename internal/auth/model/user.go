@@ -2,11 +2,18 @@ package model
 
 import "time"
 
+// BotTokenPrefix - префикс токенов ботов; по нему AccessService.Check
+// отличает их от JWT токенов пользователей
+const BotTokenPrefix = "bot_"
+
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Не сериализуется в JSON
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	Password     string    `json:"-"` // Не сериализуется в JSON
+	IsBot        bool      `json:"is_bot"`
+	OwnerID      string    `json:"owner_id,omitempty"` // Пользователь, создавший бота
+	BotTokenHash string    `json:"-"`                  // SHA-256 токена бота в hex; сам токен не хранится
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -1,7 +1,13 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"golang-chat/internal/auth/model"
@@ -11,21 +17,35 @@ import (
 	"github.com/google/uuid"
 )
 
+const botTokenBytes = 32
+
+var (
+	ErrBotNotFound     = errors.New("bot not found")
+	ErrInvalidBotToken = errors.New("invalid bot token")
+	ErrNotBotOwner     = errors.New("only the owner can manage this bot")
+	ErrBotOwner        = errors.New("bots cannot create bots")
+)
+
 type AuthService struct {
 	config *config.Config
+	mu     sync.RWMutex
 	users  map[string]*model.User
 }
 
-func NewAuthService() *AuthService {
+func NewAuthService(config *config.Config) *AuthService {
 	return &AuthService{
-		users: make(map[string]*model.User),
+		config: config,
+		users:  make(map[string]*model.User),
 	}
 }
 
 func (s *AuthService) CreateUser(username, email, password string) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Проверка существования пользователя
 	for _, user := range s.users {
-		if user.Username == username || user.Email == email {
+		if user.Username == username || !user.IsBot && user.Email == email {
 			return nil, errors.New("user already exists")
 		}
 	}
@@ -44,6 +64,9 @@ func (s *AuthService) CreateUser(username, email, password string) (*model.User,
 }
 
 func (s *AuthService) GetUser(id string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	user, exists := s.users[id]
 	if !exists {
 		return nil, errors.New("user not found")
@@ -53,12 +76,15 @@ func (s *AuthService) GetUser(id string) (*model.User, error) {
 
 func (s *AuthService) Login(username, password string) (string, string, error) {
 	var user *model.User
+	s.mu.RLock()
 	for _, u := range s.users {
-		if u.Username == username && u.Password == password {
+		// У ботов нет пароля: они входят только по токену бота
+		if !u.IsBot && u.Username == username && u.Password == password {
 			user = u
 			break
 		}
 	}
+	s.mu.RUnlock()
 
 	if user == nil {
 		return "", "", errors.New("invalid credentials")
@@ -77,6 +103,111 @@ func (s *AuthService) Login(username, password string) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
+// CreateBot создает бота, принадлежащего ownerID, и возвращает его вместе
+// с токеном. Токен не хранится и показывается только здесь и в RotateBotToken.
+func (s *AuthService) CreateBot(ownerID, username string) (*model.User, string, error) {
+	if username == "" {
+		return nil, "", errors.New("username is required")
+	}
+
+	token, hash, err := newBotToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, exists := s.users[ownerID]
+	if !exists {
+		return nil, "", errors.New("user not found")
+	}
+	if owner.IsBot {
+		return nil, "", ErrBotOwner
+	}
+
+	for _, user := range s.users {
+		if user.Username == username {
+			return nil, "", errors.New("user already exists")
+		}
+	}
+
+	bot := &model.User{
+		ID:           uuid.New().String(),
+		Username:     username,
+		IsBot:        true,
+		OwnerID:      ownerID,
+		BotTokenHash: hash,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	s.users[bot.ID] = bot
+	return bot, token, nil
+}
+
+// RotateBotToken выдает боту новый токен; прежний сразу перестает действовать.
+// Доступно только владельцу бота.
+func (s *AuthService) RotateBotToken(ownerID, botID string) (string, error) {
+	token, hash, err := newBotToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bot, exists := s.users[botID]
+	if !exists || !bot.IsBot {
+		return "", ErrBotNotFound
+	}
+	if bot.OwnerID != ownerID {
+		return "", ErrNotBotOwner
+	}
+
+	bot.BotTokenHash = hash
+	bot.UpdatedAt = time.Now()
+	return token, nil
+}
+
+// Authenticate проверяет access токен пользователя или токен бота
+// и возвращает ID владельца токена и признак бота
+func (s *AuthService) Authenticate(token string) (string, bool, error) {
+	if !strings.HasPrefix(token, model.BotTokenPrefix) {
+		userID, err := s.ValidateToken(token)
+		return userID, false, err
+	}
+
+	hash := hashBotToken(token)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.IsBot && user.BotTokenHash == hash {
+			return user.ID, true, nil
+		}
+	}
+
+	return "", false, ErrInvalidBotToken
+}
+
+// newBotToken генерирует долгоживущий токен бота и его хеш для хранения
+func newBotToken() (string, string, error) {
+	buf := make([]byte, botTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := model.BotTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashBotToken(token), nil
+}
+
+func hashBotToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *AuthService) ValidateToken(tokenString string) (string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(s.config.JWTSecret), nil
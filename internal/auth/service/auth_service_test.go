@@ -0,0 +1,116 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang-chat/internal/auth/model"
+	"golang-chat/pkg/config"
+)
+
+func newTestAuthService(t *testing.T) (*AuthService, *model.User) {
+	t.Helper()
+
+	s := NewAuthService(&config.Config{JWTSecret: "test-secret"})
+	owner, err := s.CreateUser("owner", "owner@example.com", "Password1!")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return s, owner
+}
+
+func TestAuthService_CreateBot(t *testing.T) {
+	s, owner := newTestAuthService(t)
+
+	bot, token, err := s.CreateBot(owner.ID, "deploy")
+	if err != nil {
+		t.Fatalf("CreateBot failed: %v", err)
+	}
+	if !bot.IsBot || bot.OwnerID != owner.ID || bot.Username != "deploy" {
+		t.Errorf("Unexpected bot: %+v", bot)
+	}
+
+	// Токен: префикс и 32 случайных байта в base64url; хранится только хеш
+	secret, found := strings.CutPrefix(token, model.BotTokenPrefix)
+	if raw, err := base64.RawURLEncoding.DecodeString(secret); !found || err != nil || len(raw) != botTokenBytes {
+		t.Errorf("Unexpected token format: %q", token)
+	}
+	if bot.BotTokenHash != hashBotToken(token) || strings.Contains(bot.BotTokenHash, secret) {
+		t.Errorf("Expected only the SHA-256 of the token to be stored, got %q", bot.BotTokenHash)
+	}
+	if len(bot.BotTokenHash) != 64 {
+		t.Errorf("Expected a hex SHA-256 hash, got %q", bot.BotTokenHash)
+	}
+
+	if _, other, _ := s.CreateBot(owner.ID, "monitor"); other == token {
+		t.Error("Expected bots to get different tokens")
+	}
+
+	if _, _, err := s.CreateBot(owner.ID, "deploy"); err == nil {
+		t.Error("Expected an error for a taken username")
+	}
+	if _, _, err := s.CreateBot(bot.ID, "nested"); !errors.Is(err, ErrBotOwner) {
+		t.Errorf("Expected ErrBotOwner, got %v", err)
+	}
+	if _, _, err := s.Login("deploy", ""); err == nil {
+		t.Error("Expected bots not to log in with a password")
+	}
+}
+
+func TestAuthService_Authenticate(t *testing.T) {
+	s, owner := newTestAuthService(t)
+	bot, token, _ := s.CreateBot(owner.ID, "deploy")
+
+	userID, isBot, err := s.Authenticate(token)
+	if err != nil || userID != bot.ID || !isBot {
+		t.Errorf("Expected the bot to authenticate, got %q, %v, %v", userID, isBot, err)
+	}
+
+	accessToken, _, err := s.Login("owner", "Password1!")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	userID, isBot, err = s.Authenticate(accessToken)
+	if err != nil || userID != owner.ID || isBot {
+		t.Errorf("Expected the user to authenticate, got %q, %v, %v", userID, isBot, err)
+	}
+
+	if _, _, err := s.Authenticate(model.BotTokenPrefix + "unknown"); !errors.Is(err, ErrInvalidBotToken) {
+		t.Errorf("Expected ErrInvalidBotToken, got %v", err)
+	}
+}
+
+func TestAuthService_RotateBotToken(t *testing.T) {
+	s, owner := newTestAuthService(t)
+	bot, oldToken, _ := s.CreateBot(owner.ID, "deploy")
+
+	other, err := s.CreateUser("mallory", "mallory@example.com", "Password1!")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := s.RotateBotToken(other.ID, bot.ID); !errors.Is(err, ErrNotBotOwner) {
+		t.Errorf("Expected ErrNotBotOwner for another user, got %v", err)
+	}
+	if _, _, err := s.Authenticate(oldToken); err != nil {
+		t.Errorf("Expected a refused rotation to keep the token, got %v", err)
+	}
+	if _, err := s.RotateBotToken(owner.ID, owner.ID); !errors.Is(err, ErrBotNotFound) {
+		t.Errorf("Expected ErrBotNotFound for a user, got %v", err)
+	}
+
+	newToken, err := s.RotateBotToken(owner.ID, bot.ID)
+	if err != nil {
+		t.Fatalf("RotateBotToken failed: %v", err)
+	}
+	if newToken == oldToken {
+		t.Error("Expected a new token")
+	}
+	if _, _, err := s.Authenticate(oldToken); !errors.Is(err, ErrInvalidBotToken) {
+		t.Errorf("Expected the old token to stop working, got %v", err)
+	}
+	if userID, isBot, err := s.Authenticate(newToken); err != nil || userID != bot.ID || !isBot {
+		t.Errorf("Expected the new token to work, got %q, %v, %v", userID, isBot, err)
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"io"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/chat"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// downloadChunkSize - размер chunk'а при отдаче вложения
+const downloadChunkSize = 64 << 10
+
+// UploadAttachment принимает вложение потоком: сначала info, затем chunk'и
+func (h *ChatHandler) UploadAttachment(stream chat.ChatService_UploadAttachmentServer) error {
+	userID, err := currentUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	info := first.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "first message must contain attachment info")
+	}
+
+	attachment, err := h.attachmentService.UploadAttachment(stream.Context(), service.UploadAttachmentInput{
+		ChatID:   info.ChatId,
+		UserID:   userID,
+		FileName: info.FileName,
+		Size:     info.Size,
+	}, &uploadReader{stream: stream})
+
+	var protocolErr *uploadProtocolError
+	if errors.As(err, &protocolErr) {
+		return status.Error(codes.InvalidArgument, protocolErr.Error())
+	}
+	if err != nil {
+		return stream.SendAndClose(&chat.UploadAttachmentResponse{Error: err.Error()})
+	}
+
+	return stream.SendAndClose(&chat.UploadAttachmentResponse{Attachment: toProtoAttachment(attachment)})
+}
+
+// DownloadAttachment отдает вложение потоком: сначала info, затем chunk'и
+func (h *ChatHandler) DownloadAttachment(req *chat.DownloadAttachmentRequest, stream chat.ChatService_DownloadAttachmentServer) error {
+	userID, err := currentUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	attachment, content, err := h.attachmentService.OpenAttachment(stream.Context(), req.AttachmentId, userID)
+	if err != nil {
+		return toStatusError(err)
+	}
+	defer content.Close()
+
+	if err := stream.Send(&chat.DownloadAttachmentResponse{
+		Data: &chat.DownloadAttachmentResponse_Info{Info: toProtoAttachment(attachment)},
+	}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&chat.DownloadAttachmentResponse{
+				Data: &chat.DownloadAttachmentResponse_Chunk{Chunk: buf[:n]},
+			}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+}
+
+// uploadReader превращает поток chunk'ов UploadAttachment в io.Reader
+type uploadReader struct {
+	stream  chat.ChatService_UploadAttachmentServer
+	pending []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err // io.EOF - клиент закончил загрузку
+		}
+
+		if req.GetInfo() != nil {
+			return 0, &uploadProtocolError{"attachment info must be sent only once"}
+		}
+		r.pending = req.GetChunk()
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+type uploadProtocolError struct {
+	message string
+}
+
+func (e *uploadProtocolError) Error() string {
+	return e.message
+}
+
+func toProtoAttachment(attachment *model.Attachment) *chat.Attachment {
+	return &chat.Attachment{
+		Id:         attachment.ID,
+		ChatId:     attachment.ChatID,
+		UploaderId: attachment.UploaderID,
+		FileName:   attachment.FileName,
+		Size:       attachment.Size,
+		MimeType:   attachment.MimeType,
+		Sha256:     attachment.SHA256,
+		Width:      int32(attachment.Width),
+		Height:     int32(attachment.Height),
+		CreatedAt:  attachment.CreatedAt.Format(timeLayout),
+	}
+}
@@ -2,44 +2,87 @@ package handler
 
 import (
 	"context"
+	"errors"
 
+	"golang-chat/internal/chat/middleware"
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
 	"golang-chat/internal/chat/service"
+	"golang-chat/internal/chat/storage"
 	"golang-chat/proto/chat"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const timeLayout = "2006-01-02T15:04:05Z"
+
 type ChatHandler struct {
 	chat.UnimplementedChatServiceServer
 
-	chatService *service.ChatService
+	chatService       *service.ChatService
+	attachmentService *service.AttachmentService
 }
 
-func NewChatHandler(chatService *service.ChatService) *ChatHandler {
+func NewChatHandler(chatService *service.ChatService, attachmentService *service.AttachmentService) *ChatHandler {
 	return &ChatHandler{
-		chatService: chatService,
+		chatService:       chatService,
+		attachmentService: attachmentService,
 	}
 }
 
 func (h *ChatHandler) CreateChat(ctx context.Context, req *chat.CreateChatRequest) (*chat.CreateChatResponse, error) {
-	chatModel, err := h.chatService.CreateChat(req.Name, req.CreatedBy, req.Participants)
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.CreateChat(service.CreateChatInput{
+		Name:            req.Name,
+		CreatedBy:       userID,
+		Participants:    req.Participants,
+		IsPrivate:       req.IsPrivate,
+		IsAnnouncement:  req.IsAnnouncement,
+		MaxParticipants: int(req.MaxParticipants),
+	})
 	if err != nil {
 		return &chat.CreateChatResponse{Error: err.Error()}, nil
 	}
 
 	return &chat.CreateChatResponse{
-		Chat: &chat.Chat{
-			Id:           chatModel.ID,
-			Name:         chatModel.Name,
-			CreatedBy:    chatModel.CreatedBy,
-			CreatedAt:    chatModel.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			Participants: chatModel.Participants,
-		},
+		Chat: toProtoChat(chatModel),
+	}, nil
+}
+
+func (h *ChatHandler) OpenDirectChat(ctx context.Context, req *chat.OpenDirectChatRequest) (*chat.OpenDirectChatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, created, err := h.chatService.OpenDirectChat(ctx, userID, req.PeerUserId)
+	if err != nil {
+		return &chat.OpenDirectChatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.OpenDirectChatResponse{
+		Chat:    toProtoChat(chatModel),
+		Created: created,
 	}, nil
 }
 
 func (h *ChatHandler) ConnectChat(ctx context.Context, req *chat.ConnectChatRequest) (*chat.ConnectChatResponse, error) {
-	err := h.chatService.ConnectChat(req.ChatId, req.UserId)
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.chatService.ConnectChat(req.ChatId, userID)
 	if err != nil {
-		return &chat.ConnectChatResponse{Error: err.Error()}, nil
+		return &chat.ConnectChatResponse{
+			Error:   err.Error(),
+			Pending: errors.Is(err, service.ErrJoinRequestPending),
+		}, nil
 	}
 
 	return &chat.ConnectChatResponse{
@@ -47,41 +90,391 @@ func (h *ChatHandler) ConnectChat(ctx context.Context, req *chat.ConnectChatRequ
 	}, nil
 }
 
+func (h *ChatHandler) AddParticipant(ctx context.Context, req *chat.AddParticipantRequest) (*chat.AddParticipantResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.AddParticipant(ctx, req.ChatId, userID, req.UserId); err != nil {
+		return &chat.AddParticipantResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.AddParticipantResponse{Success: true}, nil
+}
+
 func (h *ChatHandler) SendMessage(ctx context.Context, req *chat.SendMessageRequest) (*chat.SendMessageResponse, error) {
-	message, err := h.chatService.SendMessage(req.ChatId, req.UserId, req.Content)
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sendAt, err := parseTimeFilter("send_at", req.SendAt)
 	if err != nil {
 		return &chat.SendMessageResponse{Error: err.Error()}, nil
 	}
 
+	poll, err := fromProtoNewPoll(req.Poll)
+	if err != nil {
+		return &chat.SendMessageResponse{Error: err.Error()}, nil
+	}
+
+	input := service.SendMessageInput{
+		ChatID:          req.ChatId,
+		UserID:          userID,
+		Type:            req.Type,
+		Content:         req.Content,
+		Metadata:        fromProtoMetadata(req.Metadata),
+		ReplyTo:         req.ReplyToId,
+		ClientMessageID: req.ClientMessageId,
+		ExpiresAfter:    secondsDuration(req.ExpiresAfterSeconds),
+		Poll:            poll,
+	}
+
+	if sendAt != nil {
+		scheduled, err := h.chatService.ScheduleMessage(ctx, input, *sendAt)
+		if err != nil {
+			if st, ok := rateLimitStatus(ctx, err); ok {
+				return nil, st
+			}
+			return &chat.SendMessageResponse{Error: err.Error()}, nil
+		}
+
+		return &chat.SendMessageResponse{
+			Scheduled: toProtoScheduledMessage(scheduled),
+		}, nil
+	}
+
+	message, err := h.chatService.SendMessage(ctx, input)
+	if err != nil {
+		if st, ok := rateLimitStatus(ctx, err); ok {
+			return nil, st
+		}
+		return &chat.SendMessageResponse{Error: err.Error(), CommandError: toProtoCommandError(err)}, nil
+	}
+
+	// /topic и /invite выполняются без сообщения от имени автора
+	if message == nil {
+		return &chat.SendMessageResponse{}, nil
+	}
+
 	return &chat.SendMessageResponse{
-		Message: &chat.Message{
-			Id:        message.ID,
-			ChatId:    message.ChatID,
-			UserId:    message.UserID,
-			Content:   message.Content,
-			CreatedAt: message.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		},
+		Message: toProtoMessage(message),
+	}, nil
+}
+
+func (h *ChatHandler) RenameChat(ctx context.Context, req *chat.RenameChatRequest) (*chat.RenameChatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.RenameChat(req.ChatId, userID, req.Name)
+	if err != nil {
+		return &chat.RenameChatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.RenameChatResponse{
+		Chat: toProtoChat(chatModel),
 	}, nil
 }
 
 func (h *ChatHandler) GetMessages(ctx context.Context, req *chat.GetMessagesRequest) (*chat.GetMessagesResponse, error) {
-	messages, err := h.chatService.GetMessages(req.ChatId, int(req.Limit), int(req.Offset))
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, hasMore, err := h.chatService.GetMessages(userID, repository.MessageQuery{
+		ChatID:    req.ChatId,
+		BeforeSeq: req.BeforeSeq,
+		AfterSeq:  req.AfterSeq,
+		Limit:     int(req.Limit),
+		Offset:    int(req.Offset),
+	})
 	if err != nil {
 		return &chat.GetMessagesResponse{Error: err.Error()}, nil
 	}
 
 	var protoMessages []*chat.Message
 	for _, msg := range messages {
-		protoMessages = append(protoMessages, &chat.Message{
-			Id:        msg.ID,
-			ChatId:    msg.ChatID,
-			UserId:    msg.UserID,
-			Content:   msg.Content,
-			CreatedAt: msg.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		})
+		protoMessages = append(protoMessages, toProtoMessage(msg))
 	}
 
 	return &chat.GetMessagesResponse{
 		Messages: protoMessages,
+		HasMore:  hasMore,
+	}, nil
+}
+
+func (h *ChatHandler) GetThread(ctx context.Context, req *chat.GetThreadRequest) (*chat.GetThreadResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	root, replies, err := h.chatService.GetThread(req.MessageId, userID)
+	if err != nil {
+		return &chat.GetThreadResponse{Error: err.Error()}, nil
+	}
+
+	var protoReplies []*chat.Message
+	for _, reply := range replies {
+		protoReplies = append(protoReplies, toProtoMessage(reply))
+	}
+
+	return &chat.GetThreadResponse{
+		Root:    toProtoMessage(root),
+		Replies: protoReplies,
 	}, nil
 }
+
+func (h *ChatHandler) EditMessage(ctx context.Context, req *chat.EditMessageRequest) (*chat.EditMessageResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.chatService.EditMessage(req.MessageId, userID, req.Content)
+	if err != nil {
+		return &chat.EditMessageResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.EditMessageResponse{
+		Message: toProtoMessage(message),
+	}, nil
+}
+
+func (h *ChatHandler) DeleteMessage(ctx context.Context, req *chat.DeleteMessageRequest) (*chat.DeleteMessageResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.chatService.DeleteMessage(req.MessageId, userID)
+	if err != nil {
+		return &chat.DeleteMessageResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.DeleteMessageResponse{
+		Message: toProtoMessage(message),
+	}, nil
+}
+
+func (h *ChatHandler) GetMessageHistory(ctx context.Context, req *chat.GetMessageHistoryRequest) (*chat.GetMessageHistoryResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := h.chatService.GetMessageHistory(req.MessageId, userID)
+	if err != nil {
+		return &chat.GetMessageHistoryResponse{Error: err.Error()}, nil
+	}
+
+	var protoRevisions []*chat.MessageRevision
+	for _, revision := range revisions {
+		protoRevisions = append(protoRevisions, &chat.MessageRevision{
+			Content:  revision.Content,
+			EditedBy: revision.EditedBy,
+			EditedAt: revision.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	return &chat.GetMessageHistoryResponse{
+		Revisions: protoRevisions,
+	}, nil
+}
+
+// SubscribeChat стримит события чата, пока клиент не отключится
+// или не будет отключен как медленный потребитель
+func (h *ChatHandler) SubscribeChat(req *chat.SubscribeChatRequest, stream chat.ChatService_SubscribeChatServer) error {
+	userID, err := currentUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	sub, err := h.chatService.SubscribeChat(req.ChatId, userID)
+	if err != nil {
+		return toStatusError(err)
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return toStatusError(sub.Err())
+			}
+
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// currentUser возвращает пользователя, аутентифицированного AuthInterceptor.
+// Поля user_id/created_by из запросов не используются.
+func currentUser(ctx context.Context) (string, error) {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return userID, nil
+}
+
+func toProtoChat(chatModel *model.Chat) *chat.Chat {
+	protoChat := &chat.Chat{
+		Id:              chatModel.ID,
+		Name:            chatModel.Name,
+		CreatedBy:       chatModel.CreatedBy,
+		CreatedAt:       chatModel.CreatedAt.Format(timeLayout),
+		Participants:    chatModel.ParticipantIDs(),
+		IsPrivate:       chatModel.IsPrivate,
+		MaxParticipants: int32(chatModel.MaxParticipants),
+		IsDirect:        chatModel.IsDirect(),
+		IsAnnouncement:  chatModel.IsAnnouncement,
+		SlowModeSeconds: int32(chatModel.SlowModeSeconds),
+		Topic:           chatModel.Topic,
+	}
+
+	if chatModel.ArchivedAt != nil {
+		protoChat.ArchivedAt = chatModel.ArchivedAt.Format(timeLayout)
+	}
+
+	for _, participant := range chatModel.Participants {
+		protoChat.Members = append(protoChat.Members, &chat.Participant{
+			UserId:      participant.UserID,
+			Role:        participant.Role,
+			JoinedAt:    participant.JoinedAt.Format(timeLayout),
+			LastReadSeq: participant.LastReadSeq,
+		})
+	}
+
+	return protoChat
+}
+
+func toProtoMessage(message *model.Message) *chat.Message {
+	protoMessage := &chat.Message{
+		Id:         message.ID,
+		ChatId:     message.ChatID,
+		UserId:     message.UserID,
+		Content:    message.Content,
+		CreatedAt:  message.CreatedAt.Format(timeLayout),
+		Seq:        message.Seq,
+		Type:       message.Type,
+		Deleted:    message.IsDeleted(),
+		ReplyCount: int32(message.ReplyCount),
+		ReadCount:  int32(message.ReadCount),
+	}
+
+	if message.ReplyTo != nil {
+		protoMessage.ReplyToId = *message.ReplyTo
+	}
+
+	if message.EditedAt != nil {
+		protoMessage.EditedAt = message.EditedAt.Format(timeLayout)
+	}
+
+	if message.ExpiresAt != nil {
+		protoMessage.ExpiresAt = message.ExpiresAt.Format(timeLayout)
+	}
+
+	if message.Metadata != nil && !message.IsDeleted() {
+		protoMessage.Metadata = toProtoMetadata(message.Metadata)
+	}
+
+	protoMessage.Reactions = toProtoReactionCounts(message.Reactions)
+	protoMessage.Mentions = message.Mentions
+
+	if message.Poll != nil {
+		protoMessage.Poll = toProtoPoll(message.Poll)
+	}
+
+	return protoMessage
+}
+
+func toProtoMetadata(metadata *model.Metadata) *chat.MessageMetadata {
+	return &chat.MessageMetadata{
+		FileName:     metadata.FileName,
+		FileSize:     metadata.FileSize,
+		MimeType:     metadata.MimeType,
+		Width:        int32(metadata.Width),
+		Height:       int32(metadata.Height),
+		Event:        metadata.Event,
+		TargetUserId: metadata.TargetUserID,
+		Role:         metadata.Role,
+		OldName:      metadata.OldName,
+		NewName:      metadata.NewName,
+		AttachmentId: metadata.AttachmentID,
+		Topic:        metadata.Topic,
+		Command:      toProtoCommandCall(metadata.Command),
+	}
+}
+
+func fromProtoMetadata(metadata *chat.MessageMetadata) *model.Metadata {
+	if metadata == nil {
+		return nil
+	}
+
+	return &model.Metadata{
+		FileName:     metadata.FileName,
+		FileSize:     metadata.FileSize,
+		MimeType:     metadata.MimeType,
+		Width:        int(metadata.Width),
+		Height:       int(metadata.Height),
+		Event:        metadata.Event,
+		TargetUserID: metadata.TargetUserId,
+		Role:         metadata.Role,
+		OldName:      metadata.OldName,
+		NewName:      metadata.NewName,
+		AttachmentID: metadata.AttachmentId,
+		Topic:        metadata.Topic,
+	}
+}
+
+func toProtoEvent(event *model.ChatEvent) *chat.ChatEvent {
+	protoEvent := &chat.ChatEvent{
+		Type:      event.Type,
+		ChatId:    event.ChatID,
+		UserId:    event.UserID,
+		CreatedAt: event.CreatedAt.Format(timeLayout),
+	}
+
+	if event.Message != nil {
+		protoEvent.Message = toProtoMessage(event.Message)
+	}
+
+	if event.Presence != nil {
+		protoEvent.Presence = toProtoPresence(event.Presence)
+	}
+
+	if event.Reaction != nil {
+		protoEvent.Reaction = &chat.Reaction{
+			MessageId: event.Reaction.MessageID,
+			UserId:    event.Reaction.UserID,
+			Emoji:     event.Reaction.Emoji,
+		}
+	}
+
+	return protoEvent
+}
+
+// toStatusError переводит ошибки сервиса в gRPC статусы для стриминговых методов
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrChatNotFound), errors.Is(err, repository.ErrAttachmentNotFound),
+		errors.Is(err, storage.ErrBlobNotFound), errors.Is(err, service.ErrChatDeleted):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrNotParticipant), errors.Is(err, service.ErrRemovedFromChat),
+		errors.Is(err, service.ErrLeftChat):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrSlowConsumer):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) RegisterCommand(ctx context.Context, req *chat.RegisterCommandRequest) (*chat.RegisterCommandResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := h.chatService.RegisterCommand(ctx, service.RegisterCommandInput{
+		ChatID:      req.ChatId,
+		BotID:       userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Args:        fromProtoCommandArgs(req.Args),
+	})
+	if err != nil {
+		return &chat.RegisterCommandResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.RegisterCommandResponse{Command: toProtoCommand(command)}, nil
+}
+
+func (h *ChatHandler) UnregisterCommand(ctx context.Context, req *chat.UnregisterCommandRequest) (*chat.UnregisterCommandResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.UnregisterCommand(req.ChatId, userID, req.Name); err != nil {
+		return &chat.UnregisterCommandResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.UnregisterCommandResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) ListCommands(ctx context.Context, req *chat.ListCommandsRequest) (*chat.ListCommandsResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := h.chatService.ListCommands(req.ChatId, userID)
+	if err != nil {
+		return &chat.ListCommandsResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListCommandsResponse{}
+	for _, command := range commands {
+		response.Commands = append(response.Commands, toProtoCommand(command))
+	}
+
+	return response, nil
+}
+
+func toProtoCommand(command *model.Command) *chat.Command {
+	protoCommand := &chat.Command{
+		Name:        command.Name,
+		Description: command.Description,
+		BotId:       command.BotID,
+		BuiltIn:     command.BuiltIn,
+		Usage:       command.Usage(),
+	}
+
+	for _, arg := range command.Args {
+		protoCommand.Args = append(protoCommand.Args, &chat.CommandArg{
+			Name:        arg.Name,
+			Type:        arg.Type,
+			Required:    arg.Required,
+			Description: arg.Description,
+		})
+	}
+
+	return protoCommand
+}
+
+func fromProtoCommandArgs(args []*chat.CommandArg) model.CommandArgs {
+	var result model.CommandArgs
+	for _, arg := range args {
+		result = append(result, &model.CommandArg{
+			Name:        arg.Name,
+			Type:        arg.Type,
+			Required:    arg.Required,
+			Description: arg.Description,
+		})
+	}
+	return result
+}
+
+func toProtoCommandCall(call *model.CommandCall) *chat.CommandCall {
+	if call == nil {
+		return nil
+	}
+
+	return &chat.CommandCall{
+		Name:  call.Name,
+		BotId: call.BotID,
+		Args:  call.Args,
+	}
+}
+
+// toProtoCommandError возвращает структурированную ошибку slash-команды
+// или nil для остальных ошибок SendMessage
+func toProtoCommandError(err error) *chat.CommandError {
+	var commandErr *service.CommandError
+	if !errors.As(err, &commandErr) {
+		return nil
+	}
+
+	return &chat.CommandError{
+		Code:    commandErr.Code,
+		Command: commandErr.Command,
+		Usage:   commandErr.Usage,
+		Message: commandErr.Error(),
+	}
+}
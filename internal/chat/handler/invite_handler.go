@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) CreateInvite(ctx context.Context, req *chat.CreateInviteRequest) (*chat.CreateInviteResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := h.chatService.CreateInvite(service.CreateInviteInput{
+		ChatID:    req.ChatId,
+		UserID:    userID,
+		MaxUses:   int(req.MaxUses),
+		ExpiresIn: time.Duration(req.ExpiresInSeconds) * time.Second,
+	})
+	if err != nil {
+		return &chat.CreateInviteResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.CreateInviteResponse{Invite: toProtoInvite(invite)}, nil
+}
+
+func (h *ChatHandler) RevokeInvite(ctx context.Context, req *chat.RevokeInviteRequest) (*chat.RevokeInviteResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.RevokeInvite(req.ChatId, userID, req.Token); err != nil {
+		return &chat.RevokeInviteResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.RevokeInviteResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) JoinByInvite(ctx context.Context, req *chat.JoinByInviteRequest) (*chat.JoinByInviteResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.JoinByInvite(req.Token, userID)
+	if err != nil {
+		return &chat.JoinByInviteResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.JoinByInviteResponse{Chat: toProtoChat(chatModel)}, nil
+}
+
+func (h *ChatHandler) ListJoinRequests(ctx context.Context, req *chat.ListJoinRequestsRequest) (*chat.ListJoinRequestsResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := h.chatService.ListJoinRequests(req.ChatId, userID)
+	if err != nil {
+		return &chat.ListJoinRequestsResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListJoinRequestsResponse{}
+	for _, request := range requests {
+		response.Requests = append(response.Requests, &chat.JoinRequest{
+			UserId:    request.UserID,
+			CreatedAt: request.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) ApproveJoinRequest(ctx context.Context, req *chat.ApproveJoinRequestRequest) (*chat.ApproveJoinRequestResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Reject {
+		err = h.chatService.RejectJoinRequest(req.ChatId, userID, req.UserId)
+	} else {
+		err = h.chatService.ApproveJoinRequest(req.ChatId, userID, req.UserId)
+	}
+	if err != nil {
+		return &chat.ApproveJoinRequestResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.ApproveJoinRequestResponse{Success: true}, nil
+}
+
+func toProtoInvite(invite *model.Invite) *chat.Invite {
+	protoInvite := &chat.Invite{
+		Token:     invite.Token,
+		ChatId:    invite.ChatID,
+		CreatedBy: invite.CreatedBy,
+		MaxUses:   int32(invite.MaxUses),
+		Uses:      int32(invite.Uses),
+		Revoked:   invite.IsRevoked(),
+		CreatedAt: invite.CreatedAt.Format(timeLayout),
+	}
+
+	if invite.ExpiresAt != nil {
+		protoInvite.ExpiresAt = invite.ExpiresAt.Format(timeLayout)
+	}
+
+	return protoInvite
+}
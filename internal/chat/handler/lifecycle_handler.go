@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) LeaveChat(ctx context.Context, req *chat.LeaveChatRequest) (*chat.LeaveChatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.LeaveChat(req.ChatId, userID); err != nil {
+		return &chat.LeaveChatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.LeaveChatResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) DeleteChat(ctx context.Context, req *chat.DeleteChatRequest) (*chat.DeleteChatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.DeleteChat(req.ChatId, userID); err != nil {
+		return &chat.DeleteChatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.DeleteChatResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) ArchiveChat(ctx context.Context, req *chat.ArchiveChatRequest) (*chat.ArchiveChatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.ArchiveChat(req.ChatId, userID, req.Archived)
+	if err != nil {
+		return &chat.ArchiveChatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.ArchiveChatResponse{Chat: toProtoChat(chatModel)}, nil
+}
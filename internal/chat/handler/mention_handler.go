@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/repository"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) ListMentions(ctx context.Context, req *chat.ListMentionsRequest) (*chat.ListMentionsResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, hasMore, err := h.chatService.ListMentions(repository.MentionQuery{
+		UserID:     userID,
+		ChatID:     req.ChatId,
+		UnreadOnly: req.UnreadOnly,
+		Limit:      int(req.Limit),
+		Offset:     int(req.Offset),
+	})
+	if err != nil {
+		return &chat.ListMentionsResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListMentionsResponse{HasMore: hasMore}
+	for _, mention := range mentions {
+		response.Mentions = append(response.Mentions, &chat.MentionedMessage{
+			Message: toProtoMessage(mention.Message),
+			Unread:  mention.Unread,
+		})
+	}
+
+	return response, nil
+}
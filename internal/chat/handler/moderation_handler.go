@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) KickParticipant(ctx context.Context, req *chat.KickParticipantRequest) (*chat.KickParticipantResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.KickParticipant(req.ChatId, userID, req.UserId); err != nil {
+		return &chat.KickParticipantResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.KickParticipantResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) BanParticipant(ctx context.Context, req *chat.BanParticipantRequest) (*chat.BanParticipantResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.BanParticipant(req.ChatId, userID, req.UserId, req.Reason); err != nil {
+		return &chat.BanParticipantResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.BanParticipantResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) UnbanParticipant(ctx context.Context, req *chat.UnbanParticipantRequest) (*chat.UnbanParticipantResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.UnbanParticipant(req.ChatId, userID, req.UserId); err != nil {
+		return &chat.UnbanParticipantResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.UnbanParticipantResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) SetParticipantRole(ctx context.Context, req *chat.SetParticipantRoleRequest) (*chat.SetParticipantRoleResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.SetParticipantRole(req.ChatId, userID, req.UserId, req.Role); err != nil {
+		return &chat.SetParticipantRoleResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.SetParticipantRoleResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) TransferOwnership(ctx context.Context, req *chat.TransferOwnershipRequest) (*chat.TransferOwnershipResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.TransferOwnership(req.ChatId, userID, req.NewOwnerId); err != nil {
+		return &chat.TransferOwnershipResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.TransferOwnershipResponse{Success: true}, nil
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) PinMessage(ctx context.Context, req *chat.PinMessageRequest) (*chat.PinMessageResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pin, err := h.chatService.PinMessage(req.MessageId, userID)
+	if err != nil {
+		return &chat.PinMessageResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.PinMessageResponse{Pin: toProtoPin(pin)}, nil
+}
+
+func (h *ChatHandler) UnpinMessage(ctx context.Context, req *chat.UnpinMessageRequest) (*chat.UnpinMessageResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.UnpinMessage(req.MessageId, userID); err != nil {
+		return &chat.UnpinMessageResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.UnpinMessageResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) ListPinnedMessages(ctx context.Context, req *chat.ListPinnedMessagesRequest) (*chat.ListPinnedMessagesResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := h.chatService.ListPinnedMessages(req.ChatId, userID)
+	if err != nil {
+		return &chat.ListPinnedMessagesResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListPinnedMessagesResponse{}
+	for _, pin := range pins {
+		response.Pins = append(response.Pins, toProtoPin(pin))
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) SetAnnouncementMode(ctx context.Context, req *chat.SetAnnouncementModeRequest) (*chat.SetAnnouncementModeResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.SetAnnouncementMode(req.ChatId, userID, req.Enabled)
+	if err != nil {
+		return &chat.SetAnnouncementModeResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.SetAnnouncementModeResponse{Chat: toProtoChat(chatModel)}, nil
+}
+
+func toProtoPin(pin *model.Pin) *chat.PinnedMessage {
+	protoPin := &chat.PinnedMessage{
+		PinnedBy: pin.PinnedBy,
+		PinnedAt: pin.CreatedAt.Format(timeLayout),
+	}
+
+	if pin.Message != nil {
+		protoPin.Message = toProtoMessage(pin.Message)
+	}
+
+	return protoPin
+}
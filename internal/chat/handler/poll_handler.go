@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) VotePoll(ctx context.Context, req *chat.VotePollRequest) (*chat.PollResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]int, len(req.Options))
+	for i, option := range req.Options {
+		options[i] = int(option)
+	}
+
+	poll, err := h.chatService.VotePoll(req.MessageId, userID, options)
+	if err != nil {
+		return &chat.PollResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.PollResponse{Poll: toProtoPoll(poll)}, nil
+}
+
+func (h *ChatHandler) RetractVote(ctx context.Context, req *chat.RetractVoteRequest) (*chat.PollResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := h.chatService.RetractVote(req.MessageId, userID)
+	if err != nil {
+		return &chat.PollResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.PollResponse{Poll: toProtoPoll(poll)}, nil
+}
+
+func (h *ChatHandler) ClosePoll(ctx context.Context, req *chat.ClosePollRequest) (*chat.PollResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := h.chatService.ClosePoll(req.MessageId, userID)
+	if err != nil {
+		return &chat.PollResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.PollResponse{Poll: toProtoPoll(poll)}, nil
+}
+
+// fromProtoNewPoll переводит опрос из SendMessageRequest в настройки для сервиса
+func fromProtoNewPoll(poll *chat.NewPoll) (*model.Poll, error) {
+	if poll == nil {
+		return nil, nil
+	}
+
+	closesAt, err := parseTimeFilter("closes_at", poll.ClosesAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Poll{
+		Options:        poll.Options,
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		ClosesAt:       closesAt,
+	}, nil
+}
+
+func toProtoPoll(poll *model.Poll) *chat.Poll {
+	protoPoll := &chat.Poll{
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		TotalVoters:    int32(poll.TotalVoters),
+	}
+
+	for i, text := range poll.Options {
+		option := &chat.PollOption{Text: text}
+		if i < len(poll.Votes) {
+			option.Votes = int32(poll.Votes[i])
+		}
+		if i < len(poll.Voters) {
+			option.VoterIds = poll.Voters[i]
+		}
+		protoPoll.Options = append(protoPoll.Options, option)
+	}
+
+	for _, option := range poll.MyVotes {
+		protoPoll.MyVotes = append(protoPoll.MyVotes, int32(option))
+	}
+
+	if poll.ClosesAt != nil {
+		protoPoll.ClosesAt = poll.ClosesAt.Format(timeLayout)
+	}
+
+	if poll.ClosedAt != nil {
+		protoPoll.ClosedAt = poll.ClosedAt.Format(timeLayout)
+	}
+
+	return protoPoll
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) SetTyping(ctx context.Context, req *chat.SetTypingRequest) (*chat.SetTypingResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.SetTyping(req.ChatId, userID, !req.Stopped); err != nil {
+		return &chat.SetTypingResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.SetTypingResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) Heartbeat(ctx context.Context, req *chat.HeartbeatRequest) (*chat.HeartbeatResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.Heartbeat(userID, req.Away); err != nil {
+		return &chat.HeartbeatResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.HeartbeatResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) GetChatPresence(ctx context.Context, req *chat.GetChatPresenceRequest) (*chat.GetChatPresenceResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	presences, err := h.chatService.GetChatPresence(req.ChatId, userID)
+	if err != nil {
+		return &chat.GetChatPresenceResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.GetChatPresenceResponse{}
+	for _, presence := range presences {
+		response.Presences = append(response.Presences, toProtoPresence(presence))
+	}
+
+	return response, nil
+}
+
+func toProtoPresence(presence *model.Presence) *chat.Presence {
+	protoPresence := &chat.Presence{
+		UserId: presence.UserID,
+		Status: presence.Status,
+		Typing: presence.Typing,
+	}
+
+	if !presence.LastSeen.IsZero() {
+		protoPresence.LastSeen = presence.LastSeen.Format(timeLayout)
+	}
+
+	return protoPresence
+}
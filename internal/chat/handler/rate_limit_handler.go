@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/chat"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// retryAfterHeader - заголовок ответа с числом секунд до повтора
+const retryAfterHeader = "retry-after"
+
+func (h *ChatHandler) SetSlowMode(ctx context.Context, req *chat.SetSlowModeRequest) (*chat.SetSlowModeResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chatModel, err := h.chatService.SetSlowMode(req.ChatId, userID, int(req.Seconds))
+	if err != nil {
+		return &chat.SetSlowModeResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.SetSlowModeResponse{Chat: toProtoChat(chatModel)}, nil
+}
+
+// rateLimitStatus превращает отказ ограничителя в RESOURCE_EXHAUSTED.
+// Время ожидания передается и заголовком retry-after (целые секунды,
+// с округлением вверх), и точно - в деталях RetryInfo.
+func rateLimitStatus(ctx context.Context, err error) (error, bool) {
+	var limited *service.RateLimitError
+	if !errors.As(err, &limited) {
+		return nil, false
+	}
+
+	seconds := int(math.Ceil(limited.RetryAfter.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, strconv.Itoa(max(seconds, 1))))
+
+	st, detailErr := status.New(codes.ResourceExhausted, limited.Error()).
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(limited.RetryAfter)})
+	if detailErr != nil {
+		return status.Error(codes.ResourceExhausted, limited.Error()), true
+	}
+
+	return st.Err(), true
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) AddReaction(ctx context.Context, req *chat.AddReactionRequest) (*chat.AddReactionResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := h.chatService.AddReaction(req.MessageId, userID, req.Emoji)
+	if err != nil {
+		return &chat.AddReactionResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.AddReactionResponse{Reactions: toProtoReactionCounts(reactions)}, nil
+}
+
+func (h *ChatHandler) RemoveReaction(ctx context.Context, req *chat.RemoveReactionRequest) (*chat.RemoveReactionResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := h.chatService.RemoveReaction(req.MessageId, userID, req.Emoji)
+	if err != nil {
+		return &chat.RemoveReactionResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.RemoveReactionResponse{Reactions: toProtoReactionCounts(reactions)}, nil
+}
+
+func toProtoReactionCounts(counts []*model.ReactionCount) []*chat.ReactionCount {
+	var protoCounts []*chat.ReactionCount
+	for _, count := range counts {
+		protoCounts = append(protoCounts, &chat.ReactionCount{
+			Emoji:       count.Emoji,
+			Count:       int32(count.Count),
+			ReactedByMe: count.ReactedByMe,
+		})
+	}
+	return protoCounts
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) MarkRead(ctx context.Context, req *chat.MarkReadRequest) (*chat.MarkReadResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.MarkRead(req.ChatId, userID, req.UpToMessageId); err != nil {
+		return &chat.MarkReadResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.MarkReadResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) ListMyChats(ctx context.Context, req *chat.ListMyChatsRequest) (*chat.ListMyChatsResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := h.chatService.ListMyChats(ctx, userID, req.IncludeArchived)
+	if err != nil {
+		return &chat.ListMyChatsResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListMyChatsResponse{}
+	for _, summary := range summaries {
+		protoSummary := &chat.ChatSummary{
+			Chat:        toProtoChat(summary.Chat),
+			UnreadCount: int32(summary.UnreadCount),
+			LastReadSeq: summary.LastReadSeq,
+		}
+		if summary.LastMessage != nil {
+			protoSummary.LastMessage = toProtoMessage(summary.LastMessage)
+		}
+		response.Chats = append(response.Chats, protoSummary)
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) GetReadReceipts(ctx context.Context, req *chat.GetReadReceiptsRequest) (*chat.GetReadReceiptsResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, err := h.chatService.GetReadReceipts(req.MessageId, userID)
+	if err != nil {
+		return &chat.GetReadReceiptsResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.GetReadReceiptsResponse{}
+	for _, receipt := range receipts {
+		protoReceipt := &chat.ReadReceipt{UserId: receipt.UserID}
+		if receipt.ReadAt != nil {
+			protoReceipt.ReadAt = receipt.ReadAt.Format(timeLayout)
+		}
+		response.Receipts = append(response.Receipts, protoReceipt)
+	}
+
+	return response, nil
+}
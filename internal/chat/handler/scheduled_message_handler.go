@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) ListScheduledMessages(ctx context.Context, req *chat.ListScheduledMessagesRequest) (*chat.ListScheduledMessagesResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := h.chatService.ListScheduledMessages(userID, req.ChatId)
+	if err != nil {
+		return &chat.ListScheduledMessagesResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListScheduledMessagesResponse{}
+	for _, message := range scheduled {
+		response.Messages = append(response.Messages, toProtoScheduledMessage(message))
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) CancelScheduledMessage(ctx context.Context, req *chat.CancelScheduledMessageRequest) (*chat.CancelScheduledMessageResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.CancelScheduledMessage(req.Id, userID); err != nil {
+		return &chat.CancelScheduledMessageResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.CancelScheduledMessageResponse{Success: true}, nil
+}
+
+func toProtoScheduledMessage(scheduled *model.ScheduledMessage) *chat.ScheduledMessage {
+	protoMessage := &chat.ScheduledMessage{
+		Id:                  scheduled.ID,
+		ChatId:              scheduled.ChatID,
+		Content:             scheduled.Content,
+		Type:                scheduled.Type,
+		SendAt:              scheduled.SendAt.Format(timeLayout),
+		ExpiresAfterSeconds: int64(scheduled.ExpiresAfter / time.Second),
+		CreatedAt:           scheduled.CreatedAt.Format(timeLayout),
+	}
+
+	if scheduled.ReplyTo != nil {
+		protoMessage.ReplyToId = *scheduled.ReplyTo
+	}
+
+	if scheduled.Metadata != nil {
+		protoMessage.Metadata = toProtoMetadata(scheduled.Metadata)
+	}
+
+	return protoMessage
+}
+
+// secondsDuration переводит секунды из запроса в time.Duration без
+// переполнения: слишком большие значения отклонит проверка сервиса
+func secondsDuration(seconds int64) time.Duration {
+	const maxSeconds = math.MaxInt64 / int64(time.Second)
+	return time.Duration(min(max(seconds, -maxSeconds), maxSeconds)) * time.Second
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang-chat/internal/chat/repository"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) SearchMessages(ctx context.Context, req *chat.SearchMessagesRequest) (*chat.SearchMessagesResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := parseTimeFilter("before", req.Before)
+	if err != nil {
+		return &chat.SearchMessagesResponse{Error: err.Error()}, nil
+	}
+
+	after, err := parseTimeFilter("after", req.After)
+	if err != nil {
+		return &chat.SearchMessagesResponse{Error: err.Error()}, nil
+	}
+
+	hits, hasMore, err := h.chatService.SearchMessages(repository.SearchQuery{
+		UserID:     userID,
+		Text:       req.Query,
+		ChatID:     req.ChatId,
+		FromUserID: req.FromUserId,
+		Before:     before,
+		After:      after,
+		Limit:      int(req.Limit),
+		Offset:     int(req.Offset),
+	})
+	if err != nil {
+		return &chat.SearchMessagesResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.SearchMessagesResponse{HasMore: hasMore}
+	for _, hit := range hits {
+		response.Hits = append(response.Hits, &chat.SearchHit{
+			Message: toProtoMessage(hit.Message),
+			Snippet: hit.Snippet,
+		})
+	}
+
+	return response, nil
+}
+
+// parseTimeFilter разбирает необязательную границу времени в формате timeLayout
+func parseTimeFilter(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected format %s", name, timeLayout)
+	}
+
+	return &parsed, nil
+}
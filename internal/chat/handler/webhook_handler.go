@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/chat"
+)
+
+func (h *ChatHandler) CreateWebhook(ctx context.Context, req *chat.CreateWebhookRequest) (*chat.CreateWebhookResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := h.chatService.CreateWebhook(ctx, service.CreateWebhookInput{
+		ChatID: req.ChatId,
+		UserID: userID,
+		URL:    req.Url,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		return &chat.CreateWebhookResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.CreateWebhookResponse{Webhook: toProtoWebhook(webhook)}, nil
+}
+
+func (h *ChatHandler) ListWebhooks(ctx context.Context, req *chat.ListWebhooksRequest) (*chat.ListWebhooksResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := h.chatService.ListWebhooks(req.ChatId, userID)
+	if err != nil {
+		return &chat.ListWebhooksResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListWebhooksResponse{}
+	for _, webhook := range webhooks {
+		response.Webhooks = append(response.Webhooks, toProtoWebhook(webhook))
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) DeleteWebhook(ctx context.Context, req *chat.DeleteWebhookRequest) (*chat.DeleteWebhookResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.chatService.DeleteWebhook(req.Id, userID); err != nil {
+		return &chat.DeleteWebhookResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.DeleteWebhookResponse{Success: true}, nil
+}
+
+func (h *ChatHandler) ListWebhookDeliveries(ctx context.Context, req *chat.ListWebhookDeliveriesRequest) (*chat.ListWebhookDeliveriesResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, hasMore, err := h.chatService.ListWebhookDeliveries(userID, repository.WebhookDeliveryQuery{
+		WebhookID: req.WebhookId,
+		Status:    req.Status,
+		Limit:     int(req.Limit),
+		Offset:    int(req.Offset),
+	})
+	if err != nil {
+		return &chat.ListWebhookDeliveriesResponse{Error: err.Error()}, nil
+	}
+
+	response := &chat.ListWebhookDeliveriesResponse{HasMore: hasMore}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, toProtoWebhookDelivery(delivery))
+	}
+
+	return response, nil
+}
+
+func (h *ChatHandler) RetryWebhookDelivery(ctx context.Context, req *chat.RetryWebhookDeliveryRequest) (*chat.RetryWebhookDeliveryResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := h.chatService.RetryWebhookDelivery(req.Id, userID)
+	if err != nil {
+		return &chat.RetryWebhookDeliveryResponse{Error: err.Error()}, nil
+	}
+
+	return &chat.RetryWebhookDeliveryResponse{Delivery: toProtoWebhookDelivery(delivery)}, nil
+}
+
+func toProtoWebhook(webhook *model.Webhook) *chat.Webhook {
+	return &chat.Webhook{
+		Id:        webhook.ID,
+		ChatId:    webhook.ChatID,
+		Url:       webhook.URL,
+		Secret:    webhook.Secret,
+		Events:    webhook.Events,
+		CreatedBy: webhook.CreatedBy,
+		CreatedAt: webhook.CreatedAt.Format(timeLayout),
+	}
+}
+
+func toProtoWebhookDelivery(delivery *model.WebhookDelivery) *chat.WebhookDelivery {
+	protoDelivery := &chat.WebhookDelivery{
+		Id:             delivery.ID,
+		WebhookId:      delivery.WebhookID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		ResponseStatus: int32(delivery.ResponseStatus),
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt.Format(timeLayout),
+	}
+
+	if delivery.NextAttemptAt != nil {
+		protoDelivery.NextAttemptAt = delivery.NextAttemptAt.Format(timeLayout)
+	}
+
+	if delivery.LastAttemptAt != nil {
+		protoDelivery.LastAttemptAt = delivery.LastAttemptAt.Format(timeLayout)
+	}
+
+	return protoDelivery
+}
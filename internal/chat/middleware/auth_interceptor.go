@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"golang-chat/proto/auth"
+	"golang-chat/proto/chat"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationHeader - ключ метаданных gRPC с bearer токеном
+const AuthorizationHeader = "authorization"
+
+type userIDKey struct{}
+
+// botMethods - методы ChatService, доступные ботам. Бот работает только
+// в чатах, куда его добавил администратор: он читает и пишет сообщения,
+// ставит реакции, регистрирует slash-команды и может покинуть чат, но не
+// создает чаты, не вступает в них сам и не управляет участниками и настройками.
+// SendMessage для ботов ограничен в ChatService: без send_at и из встроенных
+// команд доступна только /me.
+var botMethods = map[string]bool{
+	chat.ChatService_SendMessage_FullMethodName:       true,
+	chat.ChatService_GetMessages_FullMethodName:       true,
+	chat.ChatService_GetThread_FullMethodName:         true,
+	chat.ChatService_EditMessage_FullMethodName:       true,
+	chat.ChatService_DeleteMessage_FullMethodName:     true,
+	chat.ChatService_AddReaction_FullMethodName:       true,
+	chat.ChatService_RemoveReaction_FullMethodName:    true,
+	chat.ChatService_SubscribeChat_FullMethodName:     true,
+	chat.ChatService_MarkRead_FullMethodName:          true,
+	chat.ChatService_ListMyChats_FullMethodName:       true,
+	chat.ChatService_RegisterCommand_FullMethodName:   true,
+	chat.ChatService_UnregisterCommand_FullMethodName: true,
+	chat.ChatService_ListCommands_FullMethodName:      true,
+	chat.ChatService_LeaveChat_FullMethodName:         true,
+}
+
+// AuthInterceptor проверяет access токен каждого вызова через AccessService.Check
+// и кладет идентификатор пользователя в контекст
+type AuthInterceptor struct {
+	accessClient auth.AccessServiceClient
+}
+
+// NewAuthInterceptor создает новый interceptor
+func NewAuthInterceptor(accessClient auth.AccessServiceClient) *AuthInterceptor {
+	return &AuthInterceptor{accessClient: accessClient}
+}
+
+// Unary возвращает interceptor для unary методов
+func (i *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		userID, err := i.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ContextWithUserID(ctx, userID), req)
+	}
+}
+
+// Stream возвращает interceptor для стриминговых методов
+func (i *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		userID, err := i.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{
+			ServerStream: ss,
+			ctx:          ContextWithUserID(ss.Context(), userID),
+		})
+	}
+}
+
+// authorize извлекает bearer токен из метаданных и проверяет его в Auth Service.
+// Ботам доступны только botMethods.
+func (i *AuthInterceptor) authorize(ctx context.Context, method string) (string, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := i.accessClient.Check(ctx, &auth.CheckAccessRequest{
+		AccessToken: token,
+		Endpoint:    method,
+	})
+	if err != nil {
+		return "", status.Errorf(codes.Unavailable, "access check failed: %v", err)
+	}
+
+	if !resp.HasAccess || resp.UserId == "" {
+		return "", status.Errorf(codes.Unauthenticated, "access denied: %s", resp.Error)
+	}
+
+	if resp.IsBot && !botMethods[method] {
+		return "", status.Errorf(codes.PermissionDenied, "bots cannot call %s", method)
+	}
+
+	return resp.UserId, nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "missing metadata")
+	}
+
+	values := md.Get(AuthorizationHeader)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization header")
+	}
+
+	token, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found || strings.TrimSpace(token) == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization header must be a bearer token")
+	}
+
+	return strings.TrimSpace(token), nil
+}
+
+// authenticatedStream подменяет контекст стрима на контекст с пользователем
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// ContextWithUserID добавляет идентификатор пользователя в контекст
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext получает идентификатор аутентифицированного пользователя
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+	return userID, ok && userID != ""
+}
@@ -0,0 +1,254 @@
+package middleware_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"golang-chat/internal/chat/handler"
+	"golang-chat/internal/chat/middleware"
+	"golang-chat/internal/chat/repository"
+	"golang-chat/internal/chat/service"
+	"golang-chat/proto/auth"
+	"golang-chat/proto/chat"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAccessServer - поддельный AccessService: токен "token-<user>" принадлежит пользователю <user>
+type fakeAccessServer struct {
+	auth.UnimplementedAccessServiceServer
+
+	endpoints []string
+}
+
+func (s *fakeAccessServer) Check(ctx context.Context, req *auth.CheckAccessRequest) (*auth.CheckAccessResponse, error) {
+	s.endpoints = append(s.endpoints, req.Endpoint)
+
+	switch req.AccessToken {
+	case "token-alice":
+		return &auth.CheckAccessResponse{HasAccess: true, UserId: "alice"}, nil
+	case "token-bob":
+		return &auth.CheckAccessResponse{HasAccess: true, UserId: "bob"}, nil
+	case "bot_deploy":
+		return &auth.CheckAccessResponse{HasAccess: true, UserId: "deploy-bot", IsBot: true}, nil
+	default:
+		return &auth.CheckAccessResponse{HasAccess: false, Error: "invalid token"}, nil
+	}
+}
+
+// fakeUserServer - поддельный UserService с пользователями alice, bob и deploy-bot
+type fakeUserServer struct {
+	auth.UnimplementedUserServiceServer
+}
+
+func (s *fakeUserServer) Get(ctx context.Context, req *auth.GetUserRequest) (*auth.GetUserResponse, error) {
+	switch req.Id {
+	case "alice", "bob":
+		return &auth.GetUserResponse{User: &auth.User{Id: req.Id, Username: req.Id}}, nil
+	case "deploy-bot":
+		return &auth.GetUserResponse{User: &auth.User{Id: req.Id, Username: "deploy", IsBot: true}}, nil
+	default:
+		return &auth.GetUserResponse{Error: "user not found"}, nil
+	}
+}
+
+// serve запускает gRPC сервер на bufconn и возвращает подключенного клиента
+func serve(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1024 * 1024)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func setupChatClient(t *testing.T) (chat.ChatServiceClient, *fakeAccessServer) {
+	fakeAuth := &fakeAccessServer{}
+	authServer := grpc.NewServer()
+	auth.RegisterAccessServiceServer(authServer, fakeAuth)
+	auth.RegisterUserServiceServer(authServer, &fakeUserServer{})
+	authConn := serve(t, authServer)
+
+	interceptor := middleware.NewAuthInterceptor(auth.NewAccessServiceClient(authConn))
+	chatServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	chatRepository := repository.NewInMemoryChatRepository()
+	chatService := service.NewChatService(chatRepository, service.NewAuthUserDirectory(auth.NewUserServiceClient(authConn)), service.ChatServiceConfig{})
+	attachmentService := service.NewAttachmentService(chatRepository, nil, 0)
+	chat.RegisterChatServiceServer(chatServer, handler.NewChatHandler(chatService, attachmentService))
+
+	return chat.NewChatServiceClient(serve(t, chatServer)), fakeAuth
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), middleware.AuthorizationHeader, "Bearer "+token)
+}
+
+func TestAuthInterceptor_RejectsMissingAndInvalidTokens(t *testing.T) {
+	client, _ := setupChatClient(t)
+
+	_, err := client.CreateChat(context.Background(), &chat.CreateChatRequest{Name: "general"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without token, got %v", err)
+	}
+
+	_, err = client.CreateChat(withToken("bogus"), &chat.CreateChatRequest{Name: "general"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for invalid token, got %v", err)
+	}
+}
+
+func TestAuthInterceptor_UsesTokenIdentity(t *testing.T) {
+	client, fakeAuth := setupChatClient(t)
+
+	created, err := client.CreateChat(withToken("token-alice"), &chat.CreateChatRequest{
+		Name:      "general",
+		CreatedBy: "mallory",
+	})
+	if err != nil || created.Error != "" {
+		t.Fatalf("CreateChat failed: %v %s", err, created.GetError())
+	}
+
+	if created.Chat.CreatedBy != "alice" {
+		t.Errorf("Expected chat to be created by alice, got %s", created.Chat.CreatedBy)
+	}
+
+	if fakeAuth.endpoints[0] != chat.ChatService_CreateChat_FullMethodName {
+		t.Errorf("Expected endpoint %s, got %s", chat.ChatService_CreateChat_FullMethodName, fakeAuth.endpoints[0])
+	}
+
+	// bob не участник, и подставленный user_id ему не поможет
+	sent, err := client.SendMessage(withToken("token-bob"), &chat.SendMessageRequest{
+		ChatId:  created.Chat.Id,
+		UserId:  "alice",
+		Content: "spoofed",
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if sent.Error == "" {
+		t.Error("Expected bob to be rejected as non-participant")
+	}
+
+	sent, err = client.SendMessage(withToken("token-alice"), &chat.SendMessageRequest{
+		ChatId:  created.Chat.Id,
+		Content: "hello",
+	})
+	if err != nil || sent.Error != "" {
+		t.Fatalf("SendMessage failed: %v %s", err, sent.GetError())
+	}
+	if sent.Message.UserId != "alice" {
+		t.Errorf("Expected message from alice, got %s", sent.Message.UserId)
+	}
+}
+
+func TestAuthInterceptor_Stream(t *testing.T) {
+	client, _ := setupChatClient(t)
+
+	created, err := client.CreateChat(withToken("token-alice"), &chat.CreateChatRequest{Name: "general"})
+	if err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+
+	stream, err := client.SubscribeChat(context.Background(), &chat.SubscribeChatRequest{ChatId: created.Chat.Id})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated stream without token, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(withToken("token-bob"))
+	defer cancel()
+
+	stream, err = client.SubscribeChat(ctx, &chat.SubscribeChatRequest{ChatId: created.Chat.Id, UserId: "alice"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for non-participant, got %v", err)
+	}
+}
+
+func TestAuthInterceptor_BotMethods(t *testing.T) {
+	client, _ := setupChatClient(t)
+	bot := withToken("bot_deploy")
+
+	_, err := client.CreateChat(bot, &chat.CreateChatRequest{Name: "bots only"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a bot creating a chat, got %v", err)
+	}
+
+	created, err := client.CreateChat(withToken("token-alice"), &chat.CreateChatRequest{Name: "deploys"})
+	if err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+
+	_, err = client.ConnectChat(bot, &chat.ConnectChatRequest{ChatId: created.Chat.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a bot joining by itself, got %v", err)
+	}
+
+	added, err := client.AddParticipant(withToken("token-alice"), &chat.AddParticipantRequest{ChatId: created.Chat.Id, UserId: "deploy-bot"})
+	if err != nil || added.Error != "" {
+		t.Fatalf("AddParticipant failed: %v %s", err, added.GetError())
+	}
+
+	sent, err := client.SendMessage(bot, &chat.SendMessageRequest{ChatId: created.Chat.Id, Content: "v1.2.3 deployed"})
+	if err != nil || sent.Error != "" {
+		t.Fatalf("SendMessage failed: %v %s", err, sent.GetError())
+	}
+	if sent.Message.UserId != "deploy-bot" {
+		t.Errorf("Expected message from the bot, got %s", sent.Message.UserId)
+	}
+
+	_, err = client.AddParticipant(bot, &chat.AddParticipantRequest{ChatId: created.Chat.Id, UserId: "bob"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a bot adding participants, got %v", err)
+	}
+
+	registered, err := client.RegisterCommand(bot, &chat.RegisterCommandRequest{
+		ChatId: created.Chat.Id,
+		Name:   "deploy",
+		Args:   []*chat.CommandArg{{Name: "service", Type: "string", Required: true}},
+	})
+	if err != nil || registered.Error != "" {
+		t.Fatalf("RegisterCommand failed: %v %s", err, registered.GetError())
+	}
+
+	notBot, err := client.RegisterCommand(withToken("token-alice"), &chat.RegisterCommandRequest{ChatId: created.Chat.Id, Name: "status"})
+	if err != nil || notBot.Error == "" {
+		t.Errorf("Expected an error for a user registering a command, got %v %+v", err, notBot)
+	}
+
+	invoked, err := client.SendMessage(withToken("token-alice"), &chat.SendMessageRequest{ChatId: created.Chat.Id, Content: "/deploy api"})
+	if err != nil || invoked.Error != "" {
+		t.Fatalf("SendMessage /deploy failed: %v %s", err, invoked.GetError())
+	}
+	if call := invoked.Message.GetMetadata().GetCommand(); call.GetBotId() != "deploy-bot" || call.GetArgs()["service"] != "api" {
+		t.Errorf("Unexpected command call: %+v", call)
+	}
+
+	unknown, err := client.SendMessage(withToken("token-alice"), &chat.SendMessageRequest{ChatId: created.Chat.Id, Content: "/rollback"})
+	if err != nil || unknown.CommandError.GetCode() != "unknown_command" || unknown.Message != nil {
+		t.Errorf("Expected unknown_command, got %v %+v", err, unknown)
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Attachment - файл, загруженный в чат. Содержимое лежит в BlobStore под
+// ключом SHA256, поэтому одинаковые файлы из разных чатов хранятся один раз,
+// а доступ проверяется по ChatID конкретной записи.
+type Attachment struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid"`
+	ChatID     string    `json:"chat_id" gorm:"type:uuid;index"`
+	UploaderID string    `json:"uploader_id" gorm:"type:uuid"`
+	SHA256     string    `json:"sha256" gorm:"column:sha256;size:64;not null;index"`
+	Size       int64     `json:"size" gorm:"not null"`
+	MimeType   string    `json:"mime_type" gorm:"size:255;not null"` // Определяется по содержимому
+	FileName   string    `json:"file_name" gorm:"size:255;not null"`
+	Width      int       `json:"width,omitempty"` // Только для распознанных изображений
+	Height     int       `json:"height,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Attachment) TableName() string {
+	return "chat_attachments"
+}
+
+// IsImage сообщает, распознано ли вложение как изображение с известными размерами
+func (a *Attachment) IsImage() bool {
+	return a.Width > 0 && a.Height > 0
+}
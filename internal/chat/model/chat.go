@@ -1,19 +1,207 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// Роли участников чата (совпадают с CHECK в scripts/init.sql).
+// Владелец чата (Chat.CreatedBy) всегда имеет роль admin и стоит выше
+// остальных администраторов.
+const (
+	RoleMember    = "member"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// ValidRole проверяет, что роль входит в список допустимых
+func ValidRole(role string) bool {
+	return role == RoleMember || role == RoleModerator || role == RoleAdmin
+}
 
 type Chat struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	CreatedBy    string    `json:"created_by"`
-	CreatedAt    time.Time `json:"created_at"`
-	Participants []string  `json:"participants"`
+	ID           string         `json:"id" gorm:"primaryKey;type:uuid"`
+	Name         string         `json:"name" gorm:"not null;size:255"`
+	CreatedBy    string         `json:"created_by" gorm:"type:uuid;index"` // Текущий владелец чата
+	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	LastSeq      int64          `json:"-" gorm:"not null;default:0"` // Последний выданный Message.Seq
+	Participants []*Participant `json:"participants" gorm:"-"`       // Заполняется репозиторием из chat_participants
+
+	// В закрытый чат можно попасть только по приглашению или после
+	// одобрения заявки администратором
+	IsPrivate       bool `json:"is_private" gorm:"default:false;index"`
+	MaxParticipants int  `json:"max_participants" gorm:"not null;default:100"`
+
+	// В канале объявлений писать могут только администраторы;
+	// остальные участники читают и ставят реакции
+	IsAnnouncement bool `json:"is_announcement" gorm:"not null;default:false"`
+
+	// Тема чата (колонка description); пусто - тема не задана
+	Topic string `json:"topic,omitempty" gorm:"column:description"`
+
+	// Медленный режим: участник ниже модератора может отправлять не больше
+	// одного сообщения в SlowModeSeconds секунд; 0 - режим выключен
+	SlowModeSeconds int `json:"slow_mode_seconds" gorm:"not null;default:0"`
+
+	// Ключ пары пользователей личного чата (см. DirectChatKey); nil у групповых чатов.
+	// Уникальный индекс гарантирует один личный чат на пару.
+	DirectKey *string `json:"-" gorm:"uniqueIndex;size:80"`
+
+	// Архивный чат доступен только для чтения и не попадает в список
+	// чатов по умолчанию; nil - чат активен
+	ArchivedAt *time.Time `json:"archived_at,omitempty" gorm:"index"`
 }
 
-type Message struct {
-	ID        string    `json:"id"`
-	ChatID    string    `json:"chat_id"`
-	UserID    string    `json:"user_id"`
-	Content   string    `json:"content"`
+// TableName указывает имя таблицы для GORM
+func (Chat) TableName() string {
+	return "chats"
+}
+
+// IsOwner сообщает, является ли пользователь владельцем чата
+func (c *Chat) IsOwner(userID string) bool {
+	return c.CreatedBy == userID
+}
+
+// IsDirect сообщает, является ли чат личным (1:1)
+func (c *Chat) IsDirect() bool {
+	return c.DirectKey != nil
+}
+
+// IsArchived сообщает, находится ли чат в архиве
+func (c *Chat) IsArchived() bool {
+	return c.ArchivedAt != nil
+}
+
+// DirectPeer возвращает собеседника userID в личном чате
+// (пустую строку для группового чата)
+func (c *Chat) DirectPeer(userID string) string {
+	if c.DirectKey == nil {
+		return ""
+	}
+
+	first, second, _ := strings.Cut(*c.DirectKey, directKeySeparator)
+	if first == userID {
+		return second
+	}
+	return first
+}
+
+// ParticipantIDs возвращает идентификаторы участников в порядке присоединения
+func (c *Chat) ParticipantIDs() []string {
+	ids := make([]string, 0, len(c.Participants))
+	for _, participant := range c.Participants {
+		ids = append(ids, participant.UserID)
+	}
+	return ids
+}
+
+const directKeySeparator = ":"
+
+// DirectChatKey строит ключ личного чата, не зависящий от порядка пользователей
+func DirectChatKey(userA, userB string) string {
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+	return userA + directKeySeparator + userB
+}
+
+// Participant - запись в таблице chat_participants
+type Participant struct {
+	ChatID   string    `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	UserID   string    `json:"user_id" gorm:"primaryKey;type:uuid;index"`
+	Role     string    `json:"role" gorm:"default:'member';size:20"`
+	JoinedAt time.Time `json:"joined_at" gorm:"autoCreateTime"`
+
+	// Отметка прочтения: все сообщения с Seq <= LastReadSeq считаются прочитанными
+	LastReadSeq int64      `json:"last_read_seq" gorm:"not null;default:0"`
+	LastReadAt  *time.Time `json:"last_read_at,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Participant) TableName() string {
+	return "chat_participants"
+}
+
+// Ban - запрет пользователю присоединяться к чату
+type Ban struct {
+	ChatID    string    `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	BannedBy  string    `json:"banned_by" gorm:"type:uuid"`
+	Reason    string    `json:"reason"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// TableName указывает имя таблицы для GORM
+func (Ban) TableName() string {
+	return "chat_bans"
+}
+
+// Типы сообщений (совпадают с CHECK в scripts/init.sql и repository.Migrate)
+const (
+	MessageTypeText    = "text"
+	MessageTypeImage   = "image"
+	MessageTypeFile    = "file"
+	MessageTypePoll    = "poll"
+	MessageTypeAction  = "action"  // Действие от третьего лица (/me)
+	MessageTypeCommand = "command" // Вызов команды бота, создается сервисом
+	MessageTypeSystem  = "system"
+)
+
+// Message - сообщение чата. Seq монотонно возрастает в пределах чата
+// и используется как курсор пагинации.
+// Треды одноуровневые: ReplyTo всегда указывает на корневое сообщение.
+// Удаленное сообщение остается в истории как "надгробие": DeletedAt
+// заполнен, Content пуст.
+type Message struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid"`
+	ChatID    string     `json:"chat_id" gorm:"type:uuid;index;uniqueIndex:idx_messages_chat_seq,priority:1"`
+	Seq       int64      `json:"seq" gorm:"not null;uniqueIndex:idx_messages_chat_seq,priority:2"`
+	UserID    string     `json:"user_id" gorm:"type:uuid;index"` // Для системных сообщений - инициатор действия
+	Type      string     `json:"type" gorm:"column:message_type;size:20;default:'text'"`
+	Content   string     `json:"content" gorm:"not null"` // Для image/file - подпись, может быть пустой
+	Metadata  *Metadata  `json:"metadata,omitempty" gorm:"type:jsonb"`
+	ReplyTo   *string    `json:"reply_to,omitempty" gorm:"column:reply_to;type:uuid;index"` // Корневое сообщение треда
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"` // После этого момента сообщение удаляется автоматически
+
+	ReplyCount int `json:"reply_count,omitempty" gorm:"-"` // Заполняется сервисом для корневых сообщений
+	ReadCount  int `json:"read_count,omitempty" gorm:"-"`  // Сколько участников, кроме автора, прочитали сообщение
+
+	Reactions []*ReactionCount `json:"reactions,omitempty" gorm:"-"` // Заполняется сервисом для запросившего пользователя
+
+	// Упомянутые участники. CreateMessage сохраняет их в message_mentions,
+	// при чтении сообщений заполняет сервис.
+	Mentions []string `json:"mentions,omitempty" gorm:"-"`
+
+	// Опрос сообщения типа poll. CreateMessage сохраняет его в message_polls,
+	// при чтении сообщений сервис заполняет его вместе с итогами.
+	Poll *Poll `json:"poll,omitempty" gorm:"-"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Message) TableName() string {
+	return "messages"
+}
+
+// IsDeleted сообщает, удалено ли сообщение
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// MessageRevision - предыдущая версия текста сообщения
+type MessageRevision struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid"`
+	MessageID string    `json:"message_id" gorm:"type:uuid;index"`
+	Content   string    `json:"content" gorm:"not null"`
+	EditedBy  string    `json:"edited_by" gorm:"type:uuid"`
+	CreatedAt time.Time `json:"created_at"` // Когда текст был заменен
+}
+
+// TableName указывает имя таблицы для GORM
+func (MessageRevision) TableName() string {
+	return "message_revisions"
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Типы аргументов команд
+const (
+	CommandArgString = "string" // Одно слово
+	CommandArgNumber = "number"
+	CommandArgBool   = "bool"
+	CommandArgUser   = "user" // ID пользователя
+	CommandArgText   = "text" // Остаток строки; допускается только последним
+)
+
+// ValidCommandArgType проверяет, что тип аргумента входит в список допустимых
+func ValidCommandArgType(argType string) bool {
+	switch argType {
+	case CommandArgString, CommandArgNumber, CommandArgBool, CommandArgUser, CommandArgText:
+		return true
+	default:
+		return false
+	}
+}
+
+// Command - slash-команда чата. Встроенные команды (/me, /topic, /invite)
+// описываются сервисом; команды ботов хранятся в chat_commands и действуют
+// только в чате, где бот зарегистрировал их и остается участником.
+type Command struct {
+	ChatID      string      `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	Name        string      `json:"name" gorm:"primaryKey;size:32"` // Без "/", в нижнем регистре
+	BotID       string      `json:"bot_id,omitempty" gorm:"type:uuid;index"`
+	Description string      `json:"description" gorm:"size:255"`
+	Args        CommandArgs `json:"args,omitempty" gorm:"type:jsonb"` // Позиционные аргументы
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+
+	BuiltIn bool `json:"built_in,omitempty" gorm:"-"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Command) TableName() string {
+	return "chat_commands"
+}
+
+// Usage возвращает синтаксис команды: /name <обязательный> [необязательный]
+func (c *Command) Usage() string {
+	var usage strings.Builder
+	usage.WriteString("/" + c.Name)
+	for _, arg := range c.Args {
+		if arg.Required {
+			usage.WriteString(" <" + arg.Name + ">")
+		} else {
+			usage.WriteString(" [" + arg.Name + "]")
+		}
+	}
+	return usage.String()
+}
+
+// CommandArg - описание позиционного аргумента команды
+type CommandArg struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CommandArgs - схема аргументов команды (колонка chat_commands.args)
+type CommandArgs []*CommandArg
+
+// Value сохраняет схему как JSON
+func (a CommandArgs) Value() (driver.Value, error) {
+	data, err := json.Marshal([]*CommandArg(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает схему из JSON-колонки
+func (a *CommandArgs) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("unsupported command args type %T", value)
+	}
+}
+
+// CommandCall - вызов команды бота (Metadata.Command сообщения типа command).
+// Args содержит разобранные по схеме значения: имя аргумента -> значение.
+type CommandCall struct {
+	Name  string            `json:"name"`
+	BotID string            `json:"bot_id"`
+	Args  map[string]string `json:"args,omitempty"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Типы событий чата, которые рассылаются подписчикам
+const (
+	EventMessageCreated    = "message.created"
+	EventMessageEdited     = "message.edited"
+	EventMessageDeleted    = "message.deleted"
+	EventMessageRead       = "message.read" // Message - последнее прочитанное сообщение
+	EventParticipantJoined = "participant.joined"
+	EventParticipantLeft   = "participant.left"
+	EventParticipantRole   = "participant.role_changed"
+	EventReactionAdded     = "reaction.added"   // Reaction - добавленная реакция
+	EventReactionRemoved   = "reaction.removed" // Reaction - снятая реакция
+	EventMessagePinned     = "message.pinned"   // Message - закрепленное сообщение
+	EventMessageUnpinned   = "message.unpinned"
+	EventMessageMentioned  = "message.mentioned" // Message - сообщение с упоминаниями в Message.Mentions
+	EventPollUpdated       = "poll.updated"      // Message - опрос с новыми итогами в Message.Poll
+	EventPollClosed        = "poll.closed"       // Message - закрытый опрос с итогами
+	EventChatDeleted       = "chat.deleted"      // Последнее событие чата: подписки после него закрываются
+
+	// Эфемерные события: не сохраняются в истории чата
+	EventTypingStarted   = "typing.started"
+	EventTypingStopped   = "typing.stopped"
+	EventPresenceChanged = "presence.changed" // Presence - новое состояние UserID
+)
+
+// ChatEvent - событие, которое получает каждый подписчик чата.
+// Message заполняется только для событий, связанных с сообщениями,
+// Presence - только для presence.changed, Reaction - только для reaction.*;
+// все они должны рассматриваться получателями как неизменяемые.
+type ChatEvent struct {
+	Type      string    `json:"type"`
+	ChatID    string    `json:"chat_id"`
+	UserID    string    `json:"user_id"`
+	Message   *Message  `json:"message,omitempty"`
+	Presence  *Presence `json:"presence,omitempty"`
+	Reaction  *Reaction `json:"reaction,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Состояния присутствия пользователя
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// Presence - состояние присутствия пользователя. Хранится только в памяти
+// сервиса и вычисляется по активным подпискам и heartbeat'ам.
+type Presence struct {
+	UserID   string    `json:"user_id"`
+	Status   string    `json:"status"`
+	LastSeen time.Time `json:"last_seen"`        // Нулевое, если пользователь не появлялся с запуска сервиса
+	Typing   bool      `json:"typing,omitempty"` // Печатает ли пользователь в запрошенном чате
+}
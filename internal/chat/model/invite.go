@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Invite - приглашение в чат по токену. Приглашение действует, пока
+// не отозвано, не истекло и не исчерпан лимит использований.
+type Invite struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid"`
+	ChatID    string     `json:"chat_id" gorm:"type:uuid;index"`
+	Token     string     `json:"token" gorm:"uniqueIndex;size:64;not null"`
+	CreatedBy string     `json:"created_by" gorm:"type:uuid"`
+	MaxUses   int        `json:"max_uses" gorm:"not null;default:0"` // 0 - без ограничения
+	Uses      int        `json:"uses" gorm:"not null;default:0"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil - бессрочное
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Invite) TableName() string {
+	return "chat_invites"
+}
+
+// IsRevoked сообщает, отозвано ли приглашение
+func (i *Invite) IsRevoked() bool {
+	return i.RevokedAt != nil
+}
+
+// IsExpired сообщает, истек ли срок действия приглашения на момент now
+func (i *Invite) IsExpired(now time.Time) bool {
+	return i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)
+}
+
+// IsExhausted сообщает, исчерпан ли лимит использований
+func (i *Invite) IsExhausted() bool {
+	return i.MaxUses > 0 && i.Uses >= i.MaxUses
+}
+
+// JoinRequest - заявка на вступление в закрытый чат
+type JoinRequest struct {
+	ChatID    string    `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (JoinRequest) TableName() string {
+	return "chat_join_requests"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// MentionAll - упоминание всех участников чата (@all)
+const MentionAll = "all"
+
+// Mention - упоминание участника в сообщении. @all разворачивается
+// в упоминания всех участников, кроме автора.
+type Mention struct {
+	MessageID string    `json:"message_id" gorm:"primaryKey;type:uuid"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid;index"`
+	ChatID    string    `json:"chat_id" gorm:"type:uuid;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Mention) TableName() string {
+	return "message_mentions"
+}
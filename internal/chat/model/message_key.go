@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// MessageKey - клиентский ключ идемпотентности отправленного сообщения.
+// Повтор SendMessage с тем же ключом в пределах окна хранения возвращает
+// уже созданное сообщение вместо нового.
+type MessageKey struct {
+	ChatID          string    `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	UserID          string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	ClientMessageID string    `json:"client_message_id" gorm:"primaryKey;size:64"`
+	MessageID       string    `json:"message_id" gorm:"type:uuid;not null"`
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (MessageKey) TableName() string {
+	return "message_keys"
+}
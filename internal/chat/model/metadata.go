@@ -0,0 +1,73 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Системные события, которые описывает Metadata.Event у сообщений типа system
+const (
+	SystemEventJoined      = "joined"
+	SystemEventLeft        = "left"
+	SystemEventKicked      = "kicked"
+	SystemEventBanned      = "banned"
+	SystemEventUnbanned    = "unbanned"
+	SystemEventRoleChanged = "role_changed"
+	SystemEventOwnership   = "ownership_transferred"
+	SystemEventRenamed     = "renamed"
+	SystemEventArchived    = "archived"
+	SystemEventUnarchived  = "unarchived"
+	SystemEventTopic       = "topic_changed"
+)
+
+// Metadata - структурированные данные сообщения (колонка messages.metadata).
+// Набор заполненных полей зависит от типа сообщения: файл и изображение
+// описывают вложение, системное сообщение - событие чата.
+type Metadata struct {
+	// image, file. Если указан AttachmentID, остальные поля файла
+	// заполняются сервисом из загруженного вложения.
+	AttachmentID string `json:"attachment_id,omitempty"`
+	FileName     string `json:"file_name,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"` // В байтах
+	MimeType     string `json:"mime_type,omitempty"`
+
+	// image
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+
+	// system
+	Event        string `json:"event,omitempty"`
+	TargetUserID string `json:"target_user_id,omitempty"`
+	Role         string `json:"role,omitempty"`     // Для role_changed
+	OldName      string `json:"old_name,omitempty"` // Для renamed
+	NewName      string `json:"new_name,omitempty"` // Для renamed
+	Topic        string `json:"topic,omitempty"`    // Для topic_changed; пусто - тема удалена
+
+	// command
+	Command *CommandCall `json:"command,omitempty"`
+}
+
+// Value сохраняет метаданные как JSON
+func (m Metadata) Value() (driver.Value, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает метаданные из JSON-колонки
+func (m *Metadata) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("unsupported metadata type %T", value)
+	}
+}
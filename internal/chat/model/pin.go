@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Pin - закрепленное сообщение чата
+type Pin struct {
+	ChatID    string    `json:"chat_id" gorm:"primaryKey;type:uuid"`
+	MessageID string    `json:"message_id" gorm:"primaryKey;type:uuid"`
+	PinnedBy  string    `json:"pinned_by" gorm:"type:uuid"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Message *Message `json:"message,omitempty" gorm:"-"` // Заполняется репозиторием в GetPins
+}
+
+// TableName указывает имя таблицы для GORM
+func (Pin) TableName() string {
+	return "chat_pins"
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Poll - опрос, прикрепленный к сообщению типа poll. Вопрос опроса -
+// текст сообщения, поэтому он участвует в поиске и упоминаниях.
+type Poll struct {
+	MessageID      string      `json:"message_id" gorm:"primaryKey;type:uuid"`
+	ChatID         string      `json:"chat_id" gorm:"type:uuid;index"`
+	Options        PollOptions `json:"options" gorm:"type:jsonb;not null"`
+	MultipleChoice bool        `json:"multiple_choice"`
+	Anonymous      bool        `json:"anonymous"` // Голосовавшие не раскрываются никому, включая автора
+	ClosesAt       *time.Time  `json:"closes_at,omitempty" gorm:"index"`
+	ClosedAt       *time.Time  `json:"closed_at,omitempty"`
+
+	// Итоги заполняет сервис; Voters пуст у анонимных опросов,
+	// MyVotes - варианты пользователя, запросившего опрос
+	Votes       []int      `json:"votes" gorm:"-"`
+	Voters      [][]string `json:"voters,omitempty" gorm:"-"`
+	TotalVoters int        `json:"total_voters" gorm:"-"`
+	MyVotes     []int      `json:"my_votes,omitempty" gorm:"-"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Poll) TableName() string {
+	return "message_polls"
+}
+
+// IsClosed сообщает, закрыт ли опрос к моменту now: вручную или по ClosesAt
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || p.ClosesAt != nil && !now.Before(*p.ClosesAt)
+}
+
+// PollOptions - варианты ответа (колонка message_polls.options)
+type PollOptions []string
+
+// Value сохраняет варианты как JSON
+func (o PollOptions) Value() (driver.Value, error) {
+	data, err := json.Marshal([]string(o))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает варианты из JSON-колонки
+func (o *PollOptions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("unsupported poll options type %T", value)
+	}
+}
+
+// PollVote - голос пользователя за вариант Option (индекс в Poll.Options).
+// В опросе с одним ответом у пользователя не больше одного голоса.
+type PollVote struct {
+	MessageID string    `json:"message_id" gorm:"primaryKey;type:uuid"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Option    int       `json:"option" gorm:"primaryKey"`
+	ChatID    string    `json:"chat_id" gorm:"type:uuid;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (PollVote) TableName() string {
+	return "poll_votes"
+}
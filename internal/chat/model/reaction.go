@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Reaction - реакция пользователя на сообщение. Пара (пользователь, emoji)
+// уникальна в пределах сообщения.
+type Reaction struct {
+	MessageID string    `json:"message_id" gorm:"primaryKey;type:uuid"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid;index"`
+	Emoji     string    `json:"emoji" gorm:"primaryKey;size:64"`
+	ChatID    string    `json:"chat_id" gorm:"type:uuid;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Reaction) TableName() string {
+	return "message_reactions"
+}
+
+// ReactionCount - сводка реакций одним emoji на сообщение
+type ReactionCount struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"` // Относительно пользователя, запросившего сообщения
+}
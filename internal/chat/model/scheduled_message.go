@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ScheduledMessage - сообщение, отложенное до SendAt. При отправке запись
+// удаляется, а сообщение создается с тем же ID, получая Seq и CreatedAt
+// в момент отправки.
+type ScheduledMessage struct {
+	ID       string    `json:"id" gorm:"primaryKey;type:uuid"`
+	ChatID   string    `json:"chat_id" gorm:"type:uuid;index;uniqueIndex:idx_scheduled_messages_chat_key,priority:1"`
+	UserID   string    `json:"user_id" gorm:"type:uuid;index;uniqueIndex:idx_scheduled_messages_chat_key,priority:2"`
+	Type     string    `json:"type" gorm:"column:message_type;size:20;default:'text'"`
+	Content  string    `json:"content" gorm:"not null"`
+	Metadata *Metadata `json:"metadata,omitempty" gorm:"type:jsonb"`
+	ReplyTo  *string   `json:"reply_to,omitempty" gorm:"column:reply_to;type:uuid"` // Корень треда на момент планирования
+
+	// Ключ идемпотентности автора в чате, как у message_keys: NULL
+	// не участвует в уникальном индексе
+	ClientMessageID *string `json:"client_message_id,omitempty" gorm:"size:64;uniqueIndex:idx_scheduled_messages_chat_key,priority:3"`
+
+	ExpiresAfter time.Duration `json:"expires_after,omitempty"` // Время жизни после отправки, 0 - бессрочно
+	SendAt       time.Time     `json:"send_at" gorm:"index"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
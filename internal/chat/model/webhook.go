@@ -0,0 +1,106 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WebhookEventTypes - события, которые можно получать через исходящие
+// webhook'и. Эфемерные события и события, раскрывающие голосовавших
+// в анонимных опросах, не отправляются.
+var WebhookEventTypes = []string{
+	EventMessageCreated,
+	EventMessageEdited,
+	EventMessageDeleted,
+	EventMessagePinned,
+	EventMessageUnpinned,
+	EventParticipantJoined,
+	EventParticipantLeft,
+	EventParticipantRole,
+	EventReactionAdded,
+	EventReactionRemoved,
+	EventPollClosed,
+}
+
+// Webhook - регистрация исходящего webhook'а чата. События отправляются
+// POST-запросом с JSON телом, подписанным HMAC-SHA256 по Secret.
+type Webhook struct {
+	ID        string        `json:"id" gorm:"primaryKey;type:uuid"`
+	ChatID    string        `json:"chat_id" gorm:"type:uuid;index"`
+	URL       string        `json:"url" gorm:"size:2048;not null"`
+	Secret    string        `json:"-" gorm:"size:128;not null"`
+	Events    WebhookEvents `json:"events" gorm:"type:jsonb"` // Пусто - все WebhookEventTypes
+	CreatedBy string        `json:"created_by" gorm:"type:uuid"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Webhook) TableName() string {
+	return "chat_webhooks"
+}
+
+// Accepts сообщает, нужно ли отправлять webhook'у событие eventType
+func (w *Webhook) Accepts(eventType string) bool {
+	if !slices.Contains(WebhookEventTypes, eventType) {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, eventType)
+}
+
+// WebhookEvents - фильтр событий webhook'а (колонка chat_webhooks.events)
+type WebhookEvents []string
+
+// Value сохраняет фильтр как JSON
+func (e WebhookEvents) Value() (driver.Value, error) {
+	data, err := json.Marshal([]string(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает фильтр из JSON-колонки
+func (e *WebhookEvents) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("unsupported webhook events type %T", value)
+	}
+}
+
+// Состояния доставки webhook'а
+const (
+	WebhookDeliveryPending   = "pending"   // Ждет первой или повторной попытки
+	WebhookDeliveryDelivered = "delivered" // Получатель ответил 2xx
+	WebhookDeliveryDead      = "dead"      // Попытки исчерпаны: доставка в списке недоставленных
+)
+
+// WebhookDelivery - доставка одного события одному webhook'у и журнал ее попыток
+type WebhookDelivery struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:uuid"`
+	WebhookID      string     `json:"webhook_id" gorm:"type:uuid;index"`
+	ChatID         string     `json:"chat_id" gorm:"type:uuid;index"`
+	EventType      string     `json:"event_type" gorm:"size:64"`
+	Payload        string     `json:"payload" gorm:"not null"` // Тело запроса, одинаковое во всех попытках
+	Status         string     `json:"status" gorm:"size:20;index"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index"` // Только у pending
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP статус последней попытки, 0 - ответа не было
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
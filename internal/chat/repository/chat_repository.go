@@ -0,0 +1,1160 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ошибки репозитория, по которым сервис принимает решения
+var (
+	ErrChatNotFound        = errors.New("chat not found")
+	ErrMessageNotFound     = errors.New("message not found")
+	ErrParticipantNotFound = errors.New("participant not found")
+	ErrBanNotFound         = errors.New("ban not found")
+	ErrChatFull            = errors.New("chat has reached its participant limit")
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrInviteUnavailable   = errors.New("invite is revoked or has no uses left")
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrReactionExists      = errors.New("reaction already exists")
+	ErrReactionNotFound    = errors.New("reaction not found")
+	ErrAlreadyPinned       = errors.New("message is already pinned")
+	ErrPinLimit            = errors.New("chat has reached its pinned messages limit")
+	ErrPinNotFound         = errors.New("message is not pinned")
+
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	ErrPollNotFound             = errors.New("poll not found")
+	ErrPollClosed               = errors.New("poll is closed")
+	ErrWebhookNotFound          = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrCommandNotFound          = errors.New("command not found")
+	ErrCommandTaken             = errors.New("command is registered by another bot")
+
+	errNoChatFields = errors.New("no chat fields to update")
+)
+
+// ChatRepository интерфейс для работы с чатами, участниками и сообщениями
+type ChatRepository interface {
+	CreateChat(chat *model.Chat) error
+	GetOrCreateDirectChat(chat *model.Chat) (*model.Chat, bool, error)
+	GetChatByID(id string) (*model.Chat, error)
+	UpdateChat(chat *model.Chat, fields ...string) error
+	DeleteChat(id string) error
+	AddParticipant(participant *model.Participant) error
+	RemoveParticipant(chatID, userID string) error
+	UpdateParticipantRole(chatID, userID, role string) error
+	TransferOwnership(chatID, newOwnerID string) error
+	IsParticipant(chatID, userID string) (bool, error)
+	GetParticipant(chatID, userID string) (*model.Participant, error)
+	GetParticipants(chatID string) ([]*model.Participant, error)
+	BanParticipant(ban *model.Ban) error
+	RemoveBan(chatID, userID string) error
+	IsBanned(chatID, userID string) (bool, error)
+	CreateMessage(message *model.Message) error
+	GetOrCreateMessage(message *model.Message, clientMessageID string, since time.Time) (*model.Message, bool, error)
+	GetMessageByKey(chatID, userID, clientMessageID string, since time.Time) (*model.Message, error)
+	DeleteMessageKeys(before time.Time) (int64, error)
+	GetMessageByID(id string) (*model.Message, error)
+	GetMessages(query MessageQuery) ([]*model.Message, error)
+	GetReplies(rootID string) ([]*model.Message, error)
+	CountReplies(rootIDs []string) (map[string]int, error)
+	UpdateMessage(message *model.Message, revision *model.MessageRevision) error
+	GetMessageRevisions(messageID string) ([]*model.MessageRevision, error)
+	GetExpiredMessages(now time.Time, limit int) ([]*model.Message, error)
+	GetMentions(messageIDs []string) (map[string][]string, error)
+	GetUserMentions(query MentionQuery) ([]*UserMention, error)
+	GetPolls(messageIDs []string) (map[string]*model.Poll, error)
+	GetPollVotes(messageIDs []string) (map[string][]*model.PollVote, error)
+	SetPollVotes(messageID, userID string, votes []*model.PollVote, now time.Time) error
+	ClosePoll(messageID string, closedAt time.Time) error
+	GetDuePolls(now time.Time, limit int) ([]*model.Poll, error)
+	CreateScheduledMessage(scheduled *model.ScheduledMessage) (*model.ScheduledMessage, bool, error)
+	GetScheduledMessage(id string) (*model.ScheduledMessage, error)
+	GetScheduledMessageByKey(chatID, userID, clientMessageID string) (*model.ScheduledMessage, error)
+	GetScheduledMessages(userID, chatID string) ([]*model.ScheduledMessage, error)
+	GetDueScheduledMessages(now time.Time, limit int) ([]*model.ScheduledMessage, error)
+	DeleteScheduledMessage(id string) error
+	DeliverScheduledMessage(message *model.Message, clientMessageID string, since time.Time) (bool, error)
+	CreateWebhook(webhook *model.Webhook) error
+	GetWebhook(id string) (*model.Webhook, error)
+	GetWebhooks(chatID string) ([]*model.Webhook, error)
+	DeleteWebhook(id string) error
+	CreateWebhookDeliveries(deliveries []*model.WebhookDelivery) error
+	GetWebhookDelivery(id string) (*model.WebhookDelivery, error)
+	GetWebhookDeliveries(query WebhookDeliveryQuery) ([]*model.WebhookDelivery, error)
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error)
+	ClaimWebhookDelivery(id string, attempts int, leaseUntil time.Time) (bool, error)
+	UpdateWebhookDelivery(delivery *model.WebhookDelivery) error
+	SaveCommand(command *model.Command) error
+	GetCommand(chatID, name string) (*model.Command, error)
+	GetCommands(chatID string) ([]*model.Command, error)
+	DeleteCommand(chatID, name string) error
+	AddReaction(reaction *model.Reaction) error
+	RemoveReaction(messageID, userID, emoji string) error
+	GetReactionCounts(messageIDs []string, userID string) (map[string][]*model.ReactionCount, error)
+	PinMessage(pin *model.Pin, maxPins int) error
+	UnpinMessage(chatID, messageID string) error
+	GetPins(chatID string) ([]*model.Pin, error)
+	CreateInvite(invite *model.Invite) error
+	GetInviteByToken(token string) (*model.Invite, error)
+	RevokeInvite(id string, revokedAt time.Time) error
+	RedeemInvite(invite *model.Invite, participant *model.Participant) error
+	CreateJoinRequest(request *model.JoinRequest) error
+	GetJoinRequests(chatID string) ([]*model.JoinRequest, error)
+	DeleteJoinRequest(chatID, userID string) error
+	MarkRead(chatID, userID string, seq int64, readAt time.Time) error
+	GetUserChats(userID string) ([]*ChatSummary, error)
+	GetUserChatIDs(userID string) ([]string, error)
+	SearchMessages(query SearchQuery) ([]*SearchHit, error)
+	CreateAttachment(attachment *model.Attachment) error
+	GetAttachment(id string) (*model.Attachment, error)
+	ReleaseAttachment(id string) error
+}
+
+// MessageQuery - параметры выборки сообщений чата. Результат всегда
+// упорядочен по Seq по возрастанию.
+//
+// Если задан только BeforeSeq, возвращаются последние Limit сообщений перед
+// курсором (прокрутка истории назад). Иначе сообщения берутся от начала
+// диапазона (после AfterSeq, если он задан); Offset учитывается только
+// без курсоров.
+type MessageQuery struct {
+	ChatID    string
+	BeforeSeq int64 // 0 - без ограничения
+	AfterSeq  int64 // 0 - без ограничения
+	Limit     int
+	Offset    int
+}
+
+// Backwards сообщает, нужно ли брать сообщения с конца диапазона
+func (q MessageQuery) Backwards() bool {
+	return q.BeforeSeq > 0 && q.AfterSeq == 0
+}
+
+// ChatSummary - чат в списке чатов пользователя
+type ChatSummary struct {
+	Chat        *model.Chat    // Без списка участников
+	LastMessage *model.Message // nil, если в чате нет сообщений
+	UnreadCount int            // Непрочитанные сообщения других участников
+	LastReadSeq int64
+}
+
+// LastActivity возвращает время последнего сообщения или создания чата
+func (s *ChatSummary) LastActivity() time.Time {
+	if s.LastMessage != nil {
+		return s.LastMessage.CreatedAt
+	}
+	return s.Chat.CreatedAt
+}
+
+// sortByActivity упорядочивает чаты от недавно активных к давно неактивным
+func sortByActivity(summaries []*ChatSummary) {
+	slices.SortStableFunc(summaries, func(a, b *ChatSummary) int {
+		if c := b.LastActivity().Compare(a.LastActivity()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Chat.ID, b.Chat.ID)
+	})
+}
+
+// Models возвращает модели, таблицы которых нужны GormChatRepository
+// (AutoMigrate; индексы поиска создает Migrate)
+func Models() []interface{} {
+	return []interface{}{
+		&model.Chat{},
+		&model.Participant{},
+		&model.Message{},
+		&model.MessageRevision{},
+		&model.Ban{},
+		&model.Invite{},
+		&model.JoinRequest{},
+		&model.Attachment{},
+		&model.Reaction{},
+		&model.Pin{},
+		&model.MessageKey{},
+		&model.Mention{},
+		&model.ScheduledMessage{},
+		&model.Poll{},
+		&model.PollVote{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.Command{},
+	}
+}
+
+// GormChatRepository реализация репозитория с использованием GORM
+type GormChatRepository struct {
+	db *gorm.DB
+}
+
+// NewGormChatRepository создает новый репозиторий
+func NewGormChatRepository(db *gorm.DB) *GormChatRepository {
+	return &GormChatRepository{db: db}
+}
+
+// CreateChat создает чат и добавляет в него участников из chat.Participants
+func (r *GormChatRepository) CreateChat(chat *model.Chat) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(chat).Error; err != nil {
+			return err
+		}
+
+		for _, participant := range chat.Participants {
+			participant.ChatID = chat.ID
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(participant).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetOrCreateDirectChat возвращает личный чат с ключом chat.DirectKey или
+// атомарно создает chat вместе с участниками. Второй результат - был ли чат создан.
+func (r *GormChatRepository) GetOrCreateDirectChat(chat *model.Chat) (*model.Chat, bool, error) {
+	var existingID string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "direct_key"}},
+			DoNothing: true,
+		}).Create(chat)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			var existing model.Chat
+			if err := tx.Where("direct_key = ?", *chat.DirectKey).First(&existing).Error; err != nil {
+				return err
+			}
+			existingID = existing.ID
+			return nil
+		}
+
+		for _, participant := range chat.Participants {
+			participant.ChatID = chat.ID
+			if err := tx.Create(participant).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existingID != "" {
+		existing, err := r.GetChatByID(existingID)
+		return existing, false, err
+	}
+
+	created, err := r.GetChatByID(chat.ID)
+	return created, true, err
+}
+
+// GetChatByID получает чат по ID вместе со списком участников
+func (r *GormChatRepository) GetChatByID(id string) (*model.Chat, error) {
+	var chat model.Chat
+	err := r.db.Where("id = ?", id).First(&chat).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, err
+	}
+
+	participants, err := r.GetParticipants(id)
+	if err != nil {
+		return nil, err
+	}
+
+	chat.Participants = participants
+	return &chat, nil
+}
+
+// UpdateChat сохраняет перечисленные поля чата (имена полей model.Chat) и
+// updated_at. Остальные колонки не перезаписываются: иначе устаревшая копия
+// чата откатила бы параллельные изменения, например передачу владения.
+func (r *GormChatRepository) UpdateChat(chat *model.Chat, fields ...string) error {
+	if len(fields) == 0 {
+		return errNoChatFields
+	}
+
+	result := r.db.Model(chat).
+		Select(append(fields, "UpdatedAt")).
+		Updates(chat)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// DeleteChat удаляет чат вместе с участниками, сообщениями, их историей
+// правок, реакциями, закреплениями, опросами, банами, приглашениями,
+// заявками, отложенными сообщениями, webhook'ами с журналом доставок
+// и записями о вложениях. Блобы
+// вложений не удаляются: хранилище дедуплицирует их по содержимому,
+// и блоб может использоваться другим чатом.
+func (r *GormChatRepository) DeleteChat(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		messageIDs := tx.Model(&model.Message{}).Select("id").Where("chat_id = ?", id)
+		if err := tx.Where("message_id IN (?)", messageIDs).Delete(&model.MessageRevision{}).Error; err != nil {
+			return err
+		}
+
+		for _, child := range []interface{}{
+			&model.Reaction{},
+			&model.Pin{},
+			&model.Message{},
+			&model.Participant{},
+			&model.Ban{},
+			&model.Invite{},
+			&model.JoinRequest{},
+			&model.Attachment{},
+			&model.MessageKey{},
+			&model.Mention{},
+			&model.ScheduledMessage{},
+			&model.Poll{},
+			&model.PollVote{},
+			&model.Webhook{},
+			&model.WebhookDelivery{},
+			&model.Command{},
+		} {
+			if err := tx.Where("chat_id = ?", id).Delete(child).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("id = ?", id).Delete(&model.Chat{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+
+		return nil
+	})
+}
+
+// AddParticipant добавляет участника в чат (повторное добавление игнорируется).
+// Если в чате уже max_participants участников, возвращает ErrChatFull.
+func (r *GormChatRepository) AddParticipant(participant *model.Participant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return addParticipantTx(tx, participant)
+	})
+}
+
+// addParticipantTx добавляет участника с проверкой лимита. Строка чата
+// блокируется до конца транзакции, чтобы параллельные вступления
+// не превысили лимит.
+func addParticipantTx(tx *gorm.DB, participant *model.Participant) error {
+	var chat model.Chat
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "max_participants", "last_seq").
+		Where("id = ?", participant.ChatID).
+		First(&chat).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatNotFound
+		}
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&model.Participant{}).Where("chat_id = ?", participant.ChatID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	var exists int64
+	if err := tx.Model(&model.Participant{}).
+		Where("chat_id = ? AND user_id = ?", participant.ChatID, participant.UserID).
+		Count(&exists).Error; err != nil {
+		return err
+	}
+
+	if exists > 0 {
+		return nil
+	}
+
+	if chat.MaxParticipants > 0 && count >= int64(chat.MaxParticipants) {
+		return ErrChatFull
+	}
+
+	// История, отправленная до вступления, не считается непрочитанной
+	if participant.LastReadSeq == 0 {
+		participant.LastReadSeq = chat.LastSeq
+	}
+
+	return tx.Create(participant).Error
+}
+
+// RemoveParticipant удаляет участника из чата
+func (r *GormChatRepository) RemoveParticipant(chatID, userID string) error {
+	result := r.db.Delete(&model.Participant{}, "chat_id = ? AND user_id = ?", chatID, userID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrParticipantNotFound
+	}
+
+	return nil
+}
+
+// UpdateParticipantRole меняет роль участника
+func (r *GormChatRepository) UpdateParticipantRole(chatID, userID, role string) error {
+	result := r.db.Model(&model.Participant{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Update("role", role)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrParticipantNotFound
+	}
+
+	return nil
+}
+
+// TransferOwnership в одной транзакции назначает участника newOwnerID
+// администратором и владельцем чата
+func (r *GormChatRepository) TransferOwnership(chatID, newOwnerID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Participant{}).
+			Where("chat_id = ? AND user_id = ?", chatID, newOwnerID).
+			Update("role", model.RoleAdmin)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrParticipantNotFound
+		}
+
+		result = tx.Model(&model.Chat{}).Where("id = ?", chatID).Update("created_by", newOwnerID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+
+		return nil
+	})
+}
+
+// IsParticipant проверяет, является ли пользователь участником чата
+func (r *GormChatRepository) IsParticipant(chatID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Participant{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// GetParticipant получает участника чата
+func (r *GormChatRepository) GetParticipant(chatID, userID string) (*model.Participant, error) {
+	var participant model.Participant
+	err := r.db.Where("chat_id = ? AND user_id = ?", chatID, userID).First(&participant).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrParticipantNotFound
+		}
+		return nil, err
+	}
+
+	return &participant, nil
+}
+
+// GetParticipants получает участников чата в порядке присоединения
+func (r *GormChatRepository) GetParticipants(chatID string) ([]*model.Participant, error) {
+	var participants []*model.Participant
+	err := r.db.Where("chat_id = ?", chatID).Order("joined_at, user_id").Find(&participants).Error
+	return participants, err
+}
+
+// BanParticipant запрещает пользователю доступ к чату и удаляет его
+// из участников в одной транзакции
+func (r *GormChatRepository) BanParticipant(ban *model.Ban) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.Participant{}, "chat_id = ? AND user_id = ?", ban.ChatID, ban.UserID).Error; err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(ban).Error
+	})
+}
+
+// RemoveBan снимает запрет
+func (r *GormChatRepository) RemoveBan(chatID, userID string) error {
+	result := r.db.Delete(&model.Ban{}, "chat_id = ? AND user_id = ?", chatID, userID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrBanNotFound
+	}
+
+	return nil
+}
+
+// IsBanned проверяет, заблокирован ли пользователь в чате
+func (r *GormChatRepository) IsBanned(chatID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Ban{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// CreateMessage сохраняет сообщение вместе с упоминаниями из message.Mentions
+// и опросом из message.Poll, назначая ему следующий Seq чата.
+// Счетчик хранится в chats.last_seq: UPDATE блокирует строку чата до конца
+// транзакции, поэтому параллельные вставки получают разные номера.
+func (r *GormChatRepository) CreateMessage(message *model.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createMessageTx(tx, message)
+	})
+}
+
+func createMessageTx(tx *gorm.DB, message *model.Message) error {
+	result := tx.Model(&model.Chat{}).
+		Where("id = ?", message.ChatID).
+		UpdateColumn("last_seq", gorm.Expr("last_seq + 1"))
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrChatNotFound
+	}
+
+	if err := tx.Model(&model.Chat{}).
+		Select("last_seq").
+		Where("id = ?", message.ChatID).
+		Scan(&message.Seq).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(message).Error; err != nil {
+		return err
+	}
+
+	if err := createPollTx(tx, message); err != nil {
+		return err
+	}
+
+	return createMentionsTx(tx, message)
+}
+
+// errDuplicateMessage откатывает транзакцию GetOrCreateMessage,
+// если сообщение с таким ключом уже есть
+var errDuplicateMessage = errors.New("message with this client key already exists")
+
+// GetOrCreateMessage возвращает сообщение, отправленное автором message.UserID
+// в чат message.ChatID с ключом clientMessageID не раньше since, или создает
+// message и запоминает ключ. Второй результат - было ли сообщение создано.
+// Ключ проверяется после блокировки строки чата в createMessageTx, поэтому
+// параллельные повторы не создадут двух сообщений.
+func (r *GormChatRepository) GetOrCreateMessage(message *model.Message, clientMessageID string, since time.Time) (*model.Message, bool, error) {
+	var existingID string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := createMessageTx(tx, message); err != nil {
+			return err
+		}
+
+		var err error
+		existingID, err = saveMessageKeyTx(tx, message, clientMessageID, since)
+		return err
+	})
+
+	if errors.Is(err, errDuplicateMessage) {
+		existing, err := r.GetMessageByID(existingID)
+		return existing, false, err
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return message, true, nil
+}
+
+// saveMessageKeyTx запоминает ключ clientMessageID только что созданного
+// message. Если ключ уже занят сообщением не старше since, возвращает ID
+// этого сообщения и errDuplicateMessage; устаревший ключ заменяется.
+func saveMessageKeyTx(tx *gorm.DB, message *model.Message, clientMessageID string, since time.Time) (string, error) {
+	var key model.MessageKey
+	err := tx.Where("chat_id = ? AND user_id = ? AND client_message_id = ?", message.ChatID, message.UserID, clientMessageID).
+		First(&key).Error
+
+	switch {
+	case err == nil && !key.CreatedAt.Before(since):
+		return key.MessageID, errDuplicateMessage
+	case err == nil:
+		// Ключ устарел: повтор после окна хранения считается новым сообщением
+		if err := tx.Delete(&key).Error; err != nil {
+			return "", err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", err
+	}
+
+	return "", tx.Create(&model.MessageKey{
+		ChatID:          message.ChatID,
+		UserID:          message.UserID,
+		ClientMessageID: clientMessageID,
+		MessageID:       message.ID,
+		CreatedAt:       message.CreatedAt,
+	}).Error
+}
+
+// GetMessageByKey получает сообщение, отправленное userID в чат с ключом
+// clientMessageID не раньше since, или возвращает ErrMessageNotFound
+func (r *GormChatRepository) GetMessageByKey(chatID, userID, clientMessageID string, since time.Time) (*model.Message, error) {
+	var key model.MessageKey
+	err := r.db.Where("chat_id = ? AND user_id = ? AND client_message_id = ? AND created_at >= ?", chatID, userID, clientMessageID, since).
+		First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	return r.GetMessageByID(key.MessageID)
+}
+
+// DeleteMessageKeys удаляет ключи идемпотентности, созданные раньше before,
+// и возвращает их число
+func (r *GormChatRepository) DeleteMessageKeys(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&model.MessageKey{})
+	return result.RowsAffected, result.Error
+}
+
+// GetMessageByID получает сообщение по ID (в том числе удаленное)
+func (r *GormChatRepository) GetMessageByID(id string) (*model.Message, error) {
+	var message model.Message
+	err := r.db.Where("id = ?", id).First(&message).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// GetMessages получает сообщения чата согласно MessageQuery
+func (r *GormChatRepository) GetMessages(query MessageQuery) ([]*model.Message, error) {
+	var messages []*model.Message
+
+	db := r.db.Where("chat_id = ?", query.ChatID)
+	if query.AfterSeq > 0 {
+		db = db.Where("seq > ?", query.AfterSeq)
+	}
+	if query.BeforeSeq > 0 {
+		db = db.Where("seq < ?", query.BeforeSeq)
+	}
+
+	if query.Backwards() {
+		if err := db.Order("seq DESC").Limit(query.Limit).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+
+		slices.Reverse(messages)
+		return messages, nil
+	}
+
+	if query.AfterSeq == 0 && query.BeforeSeq == 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	err := db.Order("seq").Limit(query.Limit).Find(&messages).Error
+	return messages, err
+}
+
+// GetReplies получает ответы в треде по возрастанию Seq
+func (r *GormChatRepository) GetReplies(rootID string) ([]*model.Message, error) {
+	var messages []*model.Message
+	err := r.db.Where("reply_to = ?", rootID).Order("seq").Find(&messages).Error
+	return messages, err
+}
+
+// CountReplies считает ответы для каждого из корневых сообщений
+func (r *GormChatRepository) CountReplies(rootIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(rootIDs))
+	if len(rootIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ReplyTo string
+		Count   int
+	}
+	err := r.db.Model(&model.Message{}).
+		Select("reply_to, COUNT(*) AS count").
+		Where("reply_to IN ?", rootIDs).
+		Group("reply_to").
+		Scan(&rows).Error
+
+	for _, row := range rows {
+		counts[row.ReplyTo] = row.Count
+	}
+
+	return counts, err
+}
+
+// UpdateMessage сохраняет изменения текста и отметок сообщения.
+// Если revision не nil, предыдущая версия записывается в той же транзакции.
+func (r *GormChatRepository) UpdateMessage(message *model.Message, revision *model.MessageRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if revision != nil {
+			if err := tx.Create(revision).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(message).
+			Select("content", "metadata", "edited_at", "deleted_at", "updated_at").
+			Updates(message)
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrMessageNotFound
+		}
+
+		return nil
+	})
+}
+
+// GetMessageRevisions получает предыдущие версии сообщения, от старых к новым
+func (r *GormChatRepository) GetMessageRevisions(messageID string) ([]*model.MessageRevision, error) {
+	var revisions []*model.MessageRevision
+	err := r.db.Where("message_id = ?", messageID).Order("created_at, id").Find(&revisions).Error
+	return revisions, err
+}
+
+// AddReaction сохраняет реакцию; повторная реакция тем же emoji
+// возвращает ErrReactionExists
+func (r *GormChatRepository) AddReaction(reaction *model.Reaction) error {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrReactionExists
+	}
+
+	return nil
+}
+
+// RemoveReaction удаляет реакцию пользователя
+func (r *GormChatRepository) RemoveReaction(messageID, userID, emoji string) error {
+	result := r.db.Delete(&model.Reaction{}, "message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrReactionNotFound
+	}
+
+	return nil
+}
+
+// GetReactionCounts возвращает сводку реакций по сообщениям: для каждого
+// сообщения emoji в порядке первой реакции, ReactedByMe - относительно userID
+func (r *GormChatRepository) GetReactionCounts(messageIDs []string, userID string) (map[string][]*model.ReactionCount, error) {
+	counts := make(map[string][]*model.ReactionCount)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MessageID string
+		Emoji     string
+		Count     int
+		Mine      int
+	}
+
+	err := r.db.Model(&model.Reaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, SUM(CASE WHEN user_id = ? THEN 1 ELSE 0 END) AS mine", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("message_id, MIN(created_at), emoji").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.MessageID] = append(counts[row.MessageID], &model.ReactionCount{
+			Emoji:       row.Emoji,
+			Count:       row.Count,
+			ReactedByMe: row.Mine > 0,
+		})
+	}
+
+	return counts, nil
+}
+
+// PinMessage закрепляет сообщение. Строка чата блокируется, чтобы
+// параллельные закрепления не превысили maxPins.
+func (r *GormChatRepository) PinMessage(pin *model.Pin, maxPins int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var chat model.Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", pin.ChatID).
+			First(&chat).Error
+
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChatNotFound
+			}
+			return err
+		}
+
+		var pinned, count int64
+		if err := tx.Model(&model.Pin{}).Where("chat_id = ? AND message_id = ?", pin.ChatID, pin.MessageID).Count(&pinned).Error; err != nil {
+			return err
+		}
+
+		if pinned > 0 {
+			return ErrAlreadyPinned
+		}
+
+		if err := tx.Model(&model.Pin{}).Where("chat_id = ?", pin.ChatID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count >= int64(maxPins) {
+			return ErrPinLimit
+		}
+
+		return tx.Create(pin).Error
+	})
+}
+
+// UnpinMessage открепляет сообщение
+func (r *GormChatRepository) UnpinMessage(chatID, messageID string) error {
+	result := r.db.Delete(&model.Pin{}, "chat_id = ? AND message_id = ?", chatID, messageID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPinNotFound
+	}
+
+	return nil
+}
+
+// GetPins возвращает закрепленные сообщения чата, начиная с последнего закрепленного
+func (r *GormChatRepository) GetPins(chatID string) ([]*model.Pin, error) {
+	var pins []*model.Pin
+	if err := r.db.Where("chat_id = ?", chatID).Order("created_at DESC, message_id").Find(&pins).Error; err != nil {
+		return nil, err
+	}
+
+	if len(pins) == 0 {
+		return pins, nil
+	}
+
+	messageIDs := make([]string, len(pins))
+	for i, pin := range pins {
+		messageIDs[i] = pin.MessageID
+	}
+
+	var messages []*model.Message
+	if err := r.db.Where("id IN ?", messageIDs).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+
+	for _, pin := range pins {
+		pin.Message = byID[pin.MessageID]
+	}
+
+	return pins, nil
+}
+
+// CreateInvite сохраняет приглашение
+func (r *GormChatRepository) CreateInvite(invite *model.Invite) error {
+	return r.db.Create(invite).Error
+}
+
+// GetInviteByToken получает приглашение по токену
+func (r *GormChatRepository) GetInviteByToken(token string) (*model.Invite, error) {
+	var invite model.Invite
+	err := r.db.Where("token = ?", token).First(&invite).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// RevokeInvite отзывает приглашение (повторный отзыв не меняет revoked_at)
+func (r *GormChatRepository) RevokeInvite(id string, revokedAt time.Time) error {
+	result := r.db.Model(&model.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.Model(&model.Invite{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrInviteNotFound
+		}
+	}
+
+	return nil
+}
+
+// RedeemInvite списывает одно использование приглашения и добавляет участника
+// в одной транзакции. Если приглашение успели отозвать или исчерпать,
+// возвращает ErrInviteUnavailable; если чат заполнен - ErrChatFull,
+// и использование не списывается.
+func (r *GormChatRepository) RedeemInvite(invite *model.Invite, participant *model.Participant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Invite{}).
+			Where("id = ? AND revoked_at IS NULL AND (max_uses = 0 OR uses < max_uses)", invite.ID).
+			UpdateColumn("uses", gorm.Expr("uses + 1"))
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrInviteUnavailable
+		}
+
+		return addParticipantTx(tx, participant)
+	})
+}
+
+// CreateJoinRequest сохраняет заявку на вступление (повторная заявка игнорируется)
+func (r *GormChatRepository) CreateJoinRequest(request *model.JoinRequest) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(request).Error
+}
+
+// GetJoinRequests получает заявки на вступление в порядке подачи
+func (r *GormChatRepository) GetJoinRequests(chatID string) ([]*model.JoinRequest, error) {
+	var requests []*model.JoinRequest
+	err := r.db.Where("chat_id = ?", chatID).Order("created_at, user_id").Find(&requests).Error
+	return requests, err
+}
+
+// DeleteJoinRequest удаляет заявку на вступление
+func (r *GormChatRepository) DeleteJoinRequest(chatID, userID string) error {
+	result := r.db.Delete(&model.JoinRequest{}, "chat_id = ? AND user_id = ?", chatID, userID)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrJoinRequestNotFound
+	}
+
+	return nil
+}
+
+// MarkRead сдвигает отметку прочтения участника до seq.
+// Отметка только растет: более старый seq ничего не меняет.
+func (r *GormChatRepository) MarkRead(chatID, userID string, seq int64, readAt time.Time) error {
+	result := r.db.Model(&model.Participant{}).
+		Where("chat_id = ? AND user_id = ? AND last_read_seq < ?", chatID, userID, seq).
+		Updates(map[string]interface{}{"last_read_seq": seq, "last_read_at": readAt})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		isParticipant, err := r.IsParticipant(chatID, userID)
+		if err != nil {
+			return err
+		}
+		if !isParticipant {
+			return ErrParticipantNotFound
+		}
+	}
+
+	return nil
+}
+
+// GetUserChats возвращает чаты пользователя с последним сообщением и числом
+// непрочитанных, от недавно активных к давно неактивным
+func (r *GormChatRepository) GetUserChats(userID string) ([]*ChatSummary, error) {
+	var rows []struct {
+		model.Chat
+		ParticipantLastReadSeq int64
+	}
+
+	err := r.db.Model(&model.Chat{}).
+		Select("chats.*, chat_participants.last_read_seq AS participant_last_read_seq").
+		Joins("JOIN chat_participants ON chat_participants.chat_id = chats.id").
+		Where("chat_participants.user_id = ?", userID).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return []*ChatSummary{}, nil
+	}
+
+	chatIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		chatIDs = append(chatIDs, row.ID)
+	}
+
+	var lastMessages []*model.Message
+	err = r.db.Model(&model.Message{}).
+		Select("messages.*").
+		Joins("JOIN chats ON chats.id = messages.chat_id AND chats.last_seq = messages.seq").
+		Where("messages.chat_id IN ?", chatIDs).
+		Find(&lastMessages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var unread []struct {
+		ChatID string
+		Count  int
+	}
+	err = r.db.Model(&model.Message{}).
+		Select("messages.chat_id, COUNT(*) AS count").
+		Joins("JOIN chat_participants ON chat_participants.chat_id = messages.chat_id AND chat_participants.user_id = ?", userID).
+		Where("messages.seq > chat_participants.last_read_seq AND messages.user_id <> ? AND messages.deleted_at IS NULL", userID).
+		Group("messages.chat_id").
+		Scan(&unread).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lastByChat := make(map[string]*model.Message, len(lastMessages))
+	for _, message := range lastMessages {
+		lastByChat[message.ChatID] = message
+	}
+
+	unreadByChat := make(map[string]int, len(unread))
+	for _, row := range unread {
+		unreadByChat[row.ChatID] = row.Count
+	}
+
+	summaries := make([]*ChatSummary, 0, len(rows))
+	for _, row := range rows {
+		chat := row.Chat
+		summaries = append(summaries, &ChatSummary{
+			Chat:        &chat,
+			LastMessage: lastByChat[chat.ID],
+			UnreadCount: unreadByChat[chat.ID],
+			LastReadSeq: row.ParticipantLastReadSeq,
+		})
+	}
+
+	sortByActivity(summaries)
+	return summaries, nil
+}
+
+// GetUserChatIDs возвращает идентификаторы чатов, в которых состоит пользователь
+func (r *GormChatRepository) GetUserChatIDs(userID string) ([]string, error) {
+	var chatIDs []string
+	err := r.db.Model(&model.Participant{}).
+		Where("user_id = ?", userID).
+		Order("chat_id").
+		Pluck("chat_id", &chatIDs).Error
+	return chatIDs, err
+}
+
+// CreateAttachment сохраняет запись о вложении
+func (r *GormChatRepository) CreateAttachment(attachment *model.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+// GetAttachment получает вложение по ID
+func (r *GormChatRepository) GetAttachment(id string) (*model.Attachment, error) {
+	var attachment model.Attachment
+	err := r.db.Where("id = ?", id).First(&attachment).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// ReleaseAttachment удаляет запись о вложении, если на него не ссылается
+// ни одно неудаленное сообщение. Блоб остается в BlobStore: его могут
+// использовать вложения с тем же содержимым.
+func (r *GormChatRepository) ReleaseAttachment(id string) error {
+	attachmentID := "json_extract(metadata, '$.attachment_id')"
+	if r.db.Dialector.Name() == "postgres" {
+		attachmentID = "metadata->>'attachment_id'"
+	}
+
+	inUse := r.db.Model(&model.Message{}).Select("1").
+		Where("deleted_at IS NULL AND "+attachmentID+" = ?", id)
+
+	return r.db.Where("id = ? AND NOT EXISTS (?)", id, inUse).Delete(&model.Attachment{}).Error
+}
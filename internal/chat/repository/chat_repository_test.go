@@ -0,0 +1,1398 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB создает тестовую базу данных в памяти
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	// Автоматическая миграция для тестов
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return db
+}
+
+// forEachRepository запускает тест для GORM и in-memory реализаций
+func forEachRepository(t *testing.T, test func(t *testing.T, repo ChatRepository)) {
+	t.Run("gorm", func(t *testing.T) {
+		test(t, NewGormChatRepository(setupTestDB(t)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewInMemoryChatRepository())
+	})
+}
+
+func createTestChat(t *testing.T, repo ChatRepository, participants ...string) *model.Chat {
+	chat := &model.Chat{
+		ID:        "11111111-1111-1111-1111-111111111111",
+		Name:      "general",
+		CreatedBy: "owner",
+		CreatedAt: time.Now(),
+		Participants: []*model.Participant{
+			{UserID: "owner", Role: model.RoleAdmin},
+		},
+	}
+	for _, userID := range participants {
+		chat.Participants = append(chat.Participants, &model.Participant{UserID: userID, Role: model.RoleMember})
+	}
+
+	if err := repo.CreateChat(chat); err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+
+	return chat
+}
+
+// TestChatRepository_CreateAndGetChat тестирует создание чата с участниками
+func TestChatRepository_CreateAndGetChat(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		found, err := repo.GetChatByID(chat.ID)
+		if err != nil {
+			t.Fatalf("GetChatByID failed: %v", err)
+		}
+
+		if found.Name != "general" {
+			t.Errorf("Expected name 'general', got '%s'", found.Name)
+		}
+
+		if len(found.Participants) != 2 {
+			t.Fatalf("Expected 2 participants, got %v", found.Participants)
+		}
+
+		participants, err := repo.GetParticipants(chat.ID)
+		if err != nil {
+			t.Fatalf("GetParticipants failed: %v", err)
+		}
+
+		roles := map[string]string{}
+		for _, p := range participants {
+			roles[p.UserID] = p.Role
+		}
+
+		if roles["owner"] != model.RoleAdmin || roles["alice"] != model.RoleMember {
+			t.Errorf("Unexpected roles: %v", roles)
+		}
+
+		if _, err := repo.GetChatByID("22222222-2222-2222-2222-222222222222"); !errors.Is(err, ErrChatNotFound) {
+			t.Errorf("Expected ErrChatNotFound, got %v", err)
+		}
+	})
+}
+
+// TestChatRepository_AddParticipant тестирует идемпотентное добавление участника
+func TestChatRepository_AddParticipant(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		for i := 0; i < 2; i++ {
+			if err := repo.AddParticipant(&model.Participant{ChatID: chat.ID, UserID: "bob"}); err != nil {
+				t.Fatalf("AddParticipant failed: %v", err)
+			}
+		}
+
+		ok, err := repo.IsParticipant(chat.ID, "bob")
+		if err != nil || !ok {
+			t.Errorf("Expected bob to be a participant, got %v, %v", ok, err)
+		}
+
+		participants, _ := repo.GetParticipants(chat.ID)
+		if len(participants) != 2 {
+			t.Errorf("Expected 2 participants, got %d", len(participants))
+		}
+	})
+}
+
+// TestChatRepository_Messages тестирует сохранение и пагинацию сообщений
+func TestChatRepository_Messages(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		base := time.Now()
+		ids := []string{
+			"a0000000-0000-0000-0000-000000000001",
+			"a0000000-0000-0000-0000-000000000002",
+			"a0000000-0000-0000-0000-000000000003",
+		}
+		for i, id := range ids {
+			message := &model.Message{
+				ID:        id,
+				ChatID:    chat.ID,
+				UserID:    "owner",
+				Content:   "hello",
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		page, err := repo.GetMessages(MessageQuery{ChatID: chat.ID, Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("GetMessages failed: %v", err)
+		}
+
+		if len(page) != 2 || page[0].ID != ids[1] || page[1].ID != ids[2] {
+			t.Errorf("Unexpected page: %+v", page)
+		}
+
+		if page[0].Seq != 2 || page[1].Seq != 3 {
+			t.Errorf("Expected seq 2 and 3, got %d and %d", page[0].Seq, page[1].Seq)
+		}
+
+		older, err := repo.GetMessages(MessageQuery{ChatID: chat.ID, Limit: 1, BeforeSeq: 3})
+		if err != nil {
+			t.Fatalf("GetMessages failed: %v", err)
+		}
+
+		if len(older) != 1 || older[0].Seq != 2 {
+			t.Errorf("Expected message with seq 2 before cursor, got %+v", older)
+		}
+	})
+}
+
+// TestChatRepository_UpdateMessage тестирует редактирование с историей и удаление
+func TestChatRepository_UpdateMessage(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		message := &model.Message{
+			ID:        "a0000000-0000-0000-0000-000000000001",
+			ChatID:    chat.ID,
+			UserID:    "owner",
+			Type:      model.MessageTypeFile,
+			Content:   "helo",
+			Metadata:  &model.Metadata{FileName: "report.pdf", FileSize: 1024, MimeType: "application/pdf"},
+			CreatedAt: time.Now(),
+		}
+		if err := repo.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+
+		editedAt := time.Now()
+		revision := &model.MessageRevision{
+			ID:        "b0000000-0000-0000-0000-000000000001",
+			MessageID: message.ID,
+			Content:   message.Content,
+			EditedBy:  "owner",
+			CreatedAt: editedAt,
+		}
+		message.Content = "hello"
+		message.EditedAt = &editedAt
+
+		if err := repo.UpdateMessage(message, revision); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+
+		found, err := repo.GetMessageByID(message.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID failed: %v", err)
+		}
+		if found.Content != "hello" || found.EditedAt == nil || found.Metadata == nil || found.Metadata.FileName != "report.pdf" {
+			t.Errorf("Expected edited message with its file, got %+v", found)
+		}
+
+		revisions, err := repo.GetMessageRevisions(message.ID)
+		if err != nil {
+			t.Fatalf("GetMessageRevisions failed: %v", err)
+		}
+		if len(revisions) != 1 || revisions[0].Content != "helo" {
+			t.Errorf("Unexpected revisions: %+v", revisions)
+		}
+
+		deletedAt := time.Now()
+		message.Content = ""
+		message.Metadata = nil
+		message.DeletedAt = &deletedAt
+		if err := repo.UpdateMessage(message, nil); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+
+		page, _ := repo.GetMessages(MessageQuery{ChatID: chat.ID, Limit: 10})
+		if len(page) != 1 || !page[0].IsDeleted() || page[0].Content != "" || page[0].Metadata != nil {
+			t.Errorf("Expected tombstone in history, got %+v", page)
+		}
+
+		if _, err := repo.GetMessageByID("a0000000-0000-0000-0000-000000000009"); !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("Expected ErrMessageNotFound, got %v", err)
+		}
+	})
+}
+
+// TestChatRepository_Replies тестирует выборку и подсчет ответов в треде
+func TestChatRepository_Replies(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		rootID := "a0000000-0000-0000-0000-000000000001"
+		ids := []string{rootID, "a0000000-0000-0000-0000-000000000002", "a0000000-0000-0000-0000-000000000003"}
+		for i, id := range ids {
+			message := &model.Message{ID: id, ChatID: chat.ID, UserID: "owner", Content: "m", CreatedAt: time.Now()}
+			if i > 0 {
+				message.ReplyTo = &rootID
+			}
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		replies, err := repo.GetReplies(rootID)
+		if err != nil {
+			t.Fatalf("GetReplies failed: %v", err)
+		}
+		if len(replies) != 2 || replies[0].ID != ids[1] || replies[1].ID != ids[2] {
+			t.Errorf("Unexpected replies: %+v", replies)
+		}
+
+		counts, err := repo.CountReplies([]string{rootID, ids[1]})
+		if err != nil {
+			t.Fatalf("CountReplies failed: %v", err)
+		}
+		if counts[rootID] != 2 || counts[ids[1]] != 0 {
+			t.Errorf("Unexpected counts: %v", counts)
+		}
+	})
+}
+
+// TestChatRepository_Moderation тестирует роли, удаление участников и баны
+func TestChatRepository_Moderation(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice", "bob")
+
+		if err := repo.UpdateParticipantRole(chat.ID, "alice", model.RoleModerator); err != nil {
+			t.Fatalf("UpdateParticipantRole failed: %v", err)
+		}
+		alice, err := repo.GetParticipant(chat.ID, "alice")
+		if err != nil || alice.Role != model.RoleModerator {
+			t.Errorf("Expected alice to be moderator, got %+v, %v", alice, err)
+		}
+
+		if err := repo.RemoveParticipant(chat.ID, "alice"); err != nil {
+			t.Fatalf("RemoveParticipant failed: %v", err)
+		}
+		if err := repo.RemoveParticipant(chat.ID, "alice"); !errors.Is(err, ErrParticipantNotFound) {
+			t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+		}
+
+		if err := repo.BanParticipant(&model.Ban{ChatID: chat.ID, UserID: "bob", BannedBy: "owner", CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("BanParticipant failed: %v", err)
+		}
+		if ok, _ := repo.IsParticipant(chat.ID, "bob"); ok {
+			t.Error("Banned user should be removed from participants")
+		}
+		if banned, _ := repo.IsBanned(chat.ID, "bob"); !banned {
+			t.Error("Expected bob to be banned")
+		}
+
+		if err := repo.RemoveBan(chat.ID, "bob"); err != nil {
+			t.Fatalf("RemoveBan failed: %v", err)
+		}
+		if banned, _ := repo.IsBanned(chat.ID, "bob"); banned {
+			t.Error("Expected bob to be unbanned")
+		}
+
+		chat.Name = "renamed"
+		chat.Topic = "release"
+		if err := repo.UpdateChat(chat, "Name"); err != nil {
+			t.Fatalf("UpdateChat failed: %v", err)
+		}
+		found, _ := repo.GetChatByID(chat.ID)
+		if found.Name != "renamed" || found.Topic != "" {
+			t.Errorf("Expected only the name to change, got %+v", found)
+		}
+
+		chat.Topic = "release"
+		if err := repo.UpdateChat(chat, "Topic", "SlowModeSeconds"); err != nil {
+			t.Fatalf("UpdateChat failed: %v", err)
+		}
+		if found, _ := repo.GetChatByID(chat.ID); found.Topic != "release" {
+			t.Errorf("Expected the topic to be saved, got %q", found.Topic)
+		}
+	})
+}
+
+func TestChatRepository_TransferOwnership(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		if err := repo.TransferOwnership(chat.ID, "stranger"); !errors.Is(err, ErrParticipantNotFound) {
+			t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+		}
+		if found, _ := repo.GetChatByID(chat.ID); found.CreatedBy != "owner" {
+			t.Errorf("Expected the owner to stay after a failed transfer, got %s", found.CreatedBy)
+		}
+
+		if err := repo.TransferOwnership(chat.ID, "alice"); err != nil {
+			t.Fatalf("TransferOwnership failed: %v", err)
+		}
+
+		found, _ := repo.GetChatByID(chat.ID)
+		alice, _ := repo.GetParticipant(chat.ID, "alice")
+		if found.CreatedBy != "alice" || alice.Role != model.RoleAdmin {
+			t.Errorf("Expected alice to be the admin owner, got %s and %+v", found.CreatedBy, alice)
+		}
+
+		// Переименование по копии, прочитанной до передачи, не возвращает владельца
+		chat.Name = "renamed"
+		if err := repo.UpdateChat(chat, "Name"); err != nil {
+			t.Fatalf("UpdateChat failed: %v", err)
+		}
+		found, _ = repo.GetChatByID(chat.ID)
+		if found.CreatedBy != "alice" || found.Name != "renamed" {
+			t.Errorf("Expected the rename to keep alice as the owner, got %+v", found)
+		}
+	})
+}
+
+// TestChatRepository_ParticipantLimit тестирует соблюдение max_participants
+func TestChatRepository_ParticipantLimit(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+		chat.MaxParticipants = 2
+		if err := repo.UpdateChat(chat, "MaxParticipants"); err != nil {
+			t.Fatalf("UpdateChat failed: %v", err)
+		}
+
+		bob := &model.Participant{ChatID: chat.ID, UserID: "bob", Role: model.RoleMember}
+		if err := repo.AddParticipant(bob); !errors.Is(err, ErrChatFull) {
+			t.Errorf("Expected ErrChatFull, got %v", err)
+		}
+
+		// Повторное добавление существующего участника не упирается в лимит
+		alice := &model.Participant{ChatID: chat.ID, UserID: "alice", Role: model.RoleMember}
+		if err := repo.AddParticipant(alice); err != nil {
+			t.Errorf("Expected re-adding participant to succeed, got %v", err)
+		}
+	})
+}
+
+// TestChatRepository_Invites тестирует списание использований приглашения
+func TestChatRepository_Invites(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		invite := &model.Invite{
+			ID:        "33333333-3333-3333-3333-333333333333",
+			ChatID:    chat.ID,
+			Token:     "token",
+			CreatedBy: "owner",
+			MaxUses:   1,
+			CreatedAt: time.Now(),
+		}
+		if err := repo.CreateInvite(invite); err != nil {
+			t.Fatalf("CreateInvite failed: %v", err)
+		}
+
+		if _, err := repo.GetInviteByToken("missing"); !errors.Is(err, ErrInviteNotFound) {
+			t.Errorf("Expected ErrInviteNotFound, got %v", err)
+		}
+
+		alice := &model.Participant{ChatID: chat.ID, UserID: "alice", Role: model.RoleMember}
+		if err := repo.RedeemInvite(invite, alice); err != nil {
+			t.Fatalf("RedeemInvite failed: %v", err)
+		}
+
+		found, _ := repo.GetInviteByToken("token")
+		if found.Uses != 1 {
+			t.Errorf("Expected 1 use, got %d", found.Uses)
+		}
+		if ok, _ := repo.IsParticipant(chat.ID, "alice"); !ok {
+			t.Error("Expected alice to join by invite")
+		}
+
+		bob := &model.Participant{ChatID: chat.ID, UserID: "bob", Role: model.RoleMember}
+		if err := repo.RedeemInvite(invite, bob); !errors.Is(err, ErrInviteUnavailable) {
+			t.Errorf("Expected ErrInviteUnavailable, got %v", err)
+		}
+
+		if err := repo.RevokeInvite(invite.ID, time.Now()); err != nil {
+			t.Fatalf("RevokeInvite failed: %v", err)
+		}
+		found, _ = repo.GetInviteByToken("token")
+		if !found.IsRevoked() {
+			t.Error("Expected invite to be revoked")
+		}
+	})
+}
+
+// TestChatRepository_ReadState тестирует отметки прочтения и список чатов пользователя
+func TestChatRepository_ReadState(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		quiet := &model.Chat{
+			ID:           "22222222-2222-2222-2222-222222222222",
+			Name:         "quiet",
+			CreatedBy:    "alice",
+			CreatedAt:    time.Now().Add(-time.Hour),
+			Participants: []*model.Participant{{UserID: "alice", Role: model.RoleAdmin}},
+		}
+		if err := repo.CreateChat(quiet); err != nil {
+			t.Fatalf("CreateChat failed: %v", err)
+		}
+
+		base := time.Now()
+		messages := []struct{ id, author string }{
+			{"b0000000-0000-0000-0000-000000000001", "owner"},
+			{"b0000000-0000-0000-0000-000000000002", "owner"},
+			{"b0000000-0000-0000-0000-000000000003", "alice"},
+		}
+		for i, m := range messages {
+			message := &model.Message{
+				ID:        m.id,
+				ChatID:    chat.ID,
+				UserID:    m.author,
+				Type:      model.MessageTypeText,
+				Content:   "message",
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		if err := repo.MarkRead(chat.ID, "alice", 1, base); err != nil {
+			t.Fatalf("MarkRead failed: %v", err)
+		}
+		// Отметка не сдвигается назад
+		if err := repo.MarkRead(chat.ID, "alice", 0, base); err != nil {
+			t.Fatalf("MarkRead failed: %v", err)
+		}
+		if err := repo.MarkRead(chat.ID, "stranger", 1, base); !errors.Is(err, ErrParticipantNotFound) {
+			t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+		}
+
+		summaries, err := repo.GetUserChats("alice")
+		if err != nil {
+			t.Fatalf("GetUserChats failed: %v", err)
+		}
+
+		if len(summaries) != 2 || summaries[0].Chat.ID != chat.ID || summaries[1].Chat.ID != quiet.ID {
+			t.Fatalf("Expected active chat first, got %v", summaries)
+		}
+
+		active := summaries[0]
+		if active.LastReadSeq != 1 {
+			t.Errorf("Expected last_read_seq 1, got %d", active.LastReadSeq)
+		}
+		// Непрочитано только второе сообщение: третье написала сама alice
+		if active.UnreadCount != 1 {
+			t.Errorf("Expected 1 unread message, got %d", active.UnreadCount)
+		}
+		if active.LastMessage == nil || active.LastMessage.Seq != 3 {
+			t.Errorf("Expected last message seq 3, got %+v", active.LastMessage)
+		}
+
+		if summaries[1].LastMessage != nil || summaries[1].UnreadCount != 0 {
+			t.Errorf("Expected empty summary for quiet chat, got %+v", summaries[1])
+		}
+
+		// Новый участник не видит старую историю непрочитанной
+		bob := &model.Participant{ChatID: chat.ID, UserID: "bob", Role: model.RoleMember}
+		if err := repo.AddParticipant(bob); err != nil {
+			t.Fatalf("AddParticipant failed: %v", err)
+		}
+		summaries, _ = repo.GetUserChats("bob")
+		if len(summaries) != 1 || summaries[0].UnreadCount != 0 {
+			t.Errorf("Expected no unread messages for new participant, got %+v", summaries)
+		}
+	})
+}
+
+// TestChatRepository_MessageMetadata тестирует сохранение метаданных сообщения
+func TestChatRepository_MessageMetadata(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		withMetadata := &model.Message{
+			ID:        "c0000000-0000-0000-0000-000000000001",
+			ChatID:    chat.ID,
+			UserID:    "owner",
+			Type:      model.MessageTypeFile,
+			Metadata:  &model.Metadata{FileName: "report.pdf", FileSize: 2048, MimeType: "application/pdf"},
+			CreatedAt: time.Now(),
+		}
+		plain := &model.Message{
+			ID:        "c0000000-0000-0000-0000-000000000002",
+			ChatID:    chat.ID,
+			UserID:    "owner",
+			Type:      model.MessageTypeText,
+			Content:   "hello",
+			CreatedAt: time.Now(),
+		}
+
+		for _, message := range []*model.Message{withMetadata, plain} {
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		found, err := repo.GetMessageByID(withMetadata.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID failed: %v", err)
+		}
+		if found.Type != model.MessageTypeFile || found.Metadata == nil || *found.Metadata != *withMetadata.Metadata {
+			t.Errorf("Unexpected message %+v with metadata %+v", found, found.Metadata)
+		}
+
+		found, _ = repo.GetMessageByID(plain.ID)
+		if found.Metadata != nil {
+			t.Errorf("Expected no metadata, got %+v", found.Metadata)
+		}
+	})
+}
+
+func TestChatRepository_Attachments(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		attachment := &model.Attachment{
+			ID:         "44444444-4444-4444-4444-444444444444",
+			ChatID:     chat.ID,
+			UploaderID: "owner",
+			SHA256:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Size:       4,
+			MimeType:   "text/plain; charset=utf-8",
+			FileName:   "test.txt",
+			CreatedAt:  time.Now(),
+		}
+		if err := repo.CreateAttachment(attachment); err != nil {
+			t.Fatalf("CreateAttachment failed: %v", err)
+		}
+
+		found, err := repo.GetAttachment(attachment.ID)
+		if err != nil {
+			t.Fatalf("GetAttachment failed: %v", err)
+		}
+		if found.SHA256 != attachment.SHA256 || found.FileName != "test.txt" || found.Size != 4 {
+			t.Errorf("Unexpected attachment: %+v", found)
+		}
+
+		if _, err := repo.GetAttachment("missing"); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+		}
+
+		message := &model.Message{
+			ID:        "44444444-4444-4444-4444-444444444445",
+			ChatID:    chat.ID,
+			UserID:    "owner",
+			Type:      model.MessageTypeFile,
+			Metadata:  &model.Metadata{AttachmentID: attachment.ID, FileName: "test.txt"},
+			CreatedAt: time.Now(),
+		}
+		if err := repo.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+
+		// Вложение живого сообщения не освобождается
+		if err := repo.ReleaseAttachment(attachment.ID); err != nil {
+			t.Fatalf("ReleaseAttachment failed: %v", err)
+		}
+		if _, err := repo.GetAttachment(attachment.ID); err != nil {
+			t.Errorf("Expected the attachment in use to be kept, got %v", err)
+		}
+
+		deletedAt := time.Now()
+		message.Metadata = nil
+		message.DeletedAt = &deletedAt
+		if err := repo.UpdateMessage(message, nil); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+		if err := repo.ReleaseAttachment(attachment.ID); err != nil {
+			t.Fatalf("ReleaseAttachment failed: %v", err)
+		}
+		if _, err := repo.GetAttachment(attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("Expected the released attachment to be deleted, got %v", err)
+		}
+	})
+}
+
+func TestChatRepository_GetOrCreateDirectChat(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		newDirectChat := func(id, userID, peerID string) *model.Chat {
+			key := model.DirectChatKey(userID, peerID)
+			return &model.Chat{
+				ID:              id,
+				CreatedBy:       userID,
+				MaxParticipants: 2,
+				DirectKey:       &key,
+				Participants: []*model.Participant{
+					{UserID: userID, Role: model.RoleMember},
+					{UserID: peerID, Role: model.RoleMember},
+				},
+			}
+		}
+
+		chat, created, err := repo.GetOrCreateDirectChat(newDirectChat("44444444-4444-4444-4444-444444444444", "alice", "bob"))
+		if err != nil {
+			t.Fatalf("GetOrCreateDirectChat failed: %v", err)
+		}
+		if !created || len(chat.Participants) != 2 || chat.DirectPeer("alice") != "bob" {
+			t.Errorf("Unexpected chat: created=%v %+v", created, chat)
+		}
+
+		existing, created, err := repo.GetOrCreateDirectChat(newDirectChat("55555555-5555-5555-5555-555555555555", "bob", "alice"))
+		if err != nil {
+			t.Fatalf("GetOrCreateDirectChat failed: %v", err)
+		}
+		if created || existing.ID != chat.ID || len(existing.Participants) != 2 {
+			t.Errorf("Expected existing chat %s, got created=%v %+v", chat.ID, created, existing)
+		}
+
+		if _, err := repo.GetChatByID("55555555-5555-5555-5555-555555555555"); !errors.Is(err, ErrChatNotFound) {
+			t.Errorf("Duplicate direct chat must not be stored, got %v", err)
+		}
+
+		// Групповые чаты без ключа не конфликтуют между собой
+		createTestChat(t, repo)
+		group := &model.Chat{ID: "33333333-3333-3333-3333-333333333333", Name: "second", CreatedBy: "owner", MaxParticipants: 10}
+		if err := repo.CreateChat(group); err != nil {
+			t.Errorf("CreateChat failed: %v", err)
+		}
+	})
+}
+
+func TestChatRepository_Reactions(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		message := &model.Message{ID: "f0000000-0000-0000-0000-000000000001", ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeText, Content: "hi", CreatedAt: time.Now()}
+		if err := repo.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+
+		base := time.Now()
+		reactions := []*model.Reaction{
+			{UserID: "alice", Emoji: "👍"},
+			{UserID: "owner", Emoji: "🎉"},
+			{UserID: "owner", Emoji: "👍"},
+		}
+		for i, reaction := range reactions {
+			reaction.MessageID, reaction.ChatID = message.ID, chat.ID
+			reaction.CreatedAt = base.Add(time.Duration(i) * time.Second)
+			if err := repo.AddReaction(reaction); err != nil {
+				t.Fatalf("AddReaction failed: %v", err)
+			}
+		}
+
+		duplicate := *reactions[0]
+		if err := repo.AddReaction(&duplicate); !errors.Is(err, ErrReactionExists) {
+			t.Errorf("Expected ErrReactionExists, got %v", err)
+		}
+
+		counts, err := repo.GetReactionCounts([]string{message.ID, "missing"}, "alice")
+		if err != nil {
+			t.Fatalf("GetReactionCounts failed: %v", err)
+		}
+
+		got := counts[message.ID]
+		if len(got) != 2 || len(counts) != 1 {
+			t.Fatalf("Unexpected counts: %+v", counts)
+		}
+		if *got[0] != (model.ReactionCount{Emoji: "👍", Count: 2, ReactedByMe: true}) ||
+			*got[1] != (model.ReactionCount{Emoji: "🎉", Count: 1, ReactedByMe: false}) {
+			t.Errorf("Unexpected counts: %+v %+v", got[0], got[1])
+		}
+
+		if err := repo.RemoveReaction(message.ID, "alice", "👍"); err != nil {
+			t.Fatalf("RemoveReaction failed: %v", err)
+		}
+		if err := repo.RemoveReaction(message.ID, "alice", "👍"); !errors.Is(err, ErrReactionNotFound) {
+			t.Errorf("Expected ErrReactionNotFound, got %v", err)
+		}
+
+		counts, _ = repo.GetReactionCounts([]string{message.ID}, "alice")
+		if got := counts[message.ID]; got[0].Count != 1 || got[0].ReactedByMe {
+			t.Errorf("Unexpected counts after removal: %+v", got[0])
+		}
+	})
+}
+
+func TestChatRepository_Pins(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		base := time.Now()
+		for i := range 3 {
+			message := &model.Message{ID: fmt.Sprintf("a1000000-0000-0000-0000-%012d", i), ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeText, Content: fmt.Sprintf("message %d", i), CreatedAt: base}
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		pin := func(i int) error {
+			return repo.PinMessage(&model.Pin{
+				ChatID:    chat.ID,
+				MessageID: fmt.Sprintf("a1000000-0000-0000-0000-%012d", i),
+				PinnedBy:  "owner",
+				CreatedAt: base.Add(time.Duration(i) * time.Second),
+			}, 2)
+		}
+
+		if err := pin(0); err != nil {
+			t.Fatalf("PinMessage failed: %v", err)
+		}
+		if err := pin(0); !errors.Is(err, ErrAlreadyPinned) {
+			t.Errorf("Expected ErrAlreadyPinned, got %v", err)
+		}
+		if err := pin(1); err != nil {
+			t.Fatalf("PinMessage failed: %v", err)
+		}
+		if err := pin(2); !errors.Is(err, ErrPinLimit) {
+			t.Errorf("Expected ErrPinLimit, got %v", err)
+		}
+
+		pins, err := repo.GetPins(chat.ID)
+		if err != nil {
+			t.Fatalf("GetPins failed: %v", err)
+		}
+		if len(pins) != 2 || pins[0].Message == nil || pins[0].Message.Content != "message 1" || pins[1].Message.Content != "message 0" {
+			t.Errorf("Unexpected pins: %+v", pins)
+		}
+
+		if err := repo.UnpinMessage(chat.ID, pins[0].MessageID); err != nil {
+			t.Fatalf("UnpinMessage failed: %v", err)
+		}
+		if err := repo.UnpinMessage(chat.ID, pins[0].MessageID); !errors.Is(err, ErrPinNotFound) {
+			t.Errorf("Expected ErrPinNotFound, got %v", err)
+		}
+		if err := pin(2); err != nil {
+			t.Errorf("PinMessage after unpin failed: %v", err)
+		}
+	})
+}
+
+// TestChatRepository_DeleteChat тестирует каскадное удаление чата
+func TestChatRepository_DeleteChat(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+		other := &model.Chat{
+			ID:           "22222222-2222-2222-2222-222222222222",
+			Name:         "other",
+			CreatedBy:    "owner",
+			Participants: []*model.Participant{{UserID: "owner", Role: model.RoleAdmin}},
+		}
+		if err := repo.CreateChat(other); err != nil {
+			t.Fatalf("CreateChat failed: %v", err)
+		}
+
+		now := time.Now()
+		message := &model.Message{ID: "a2000000-0000-0000-0000-000000000001", ChatID: chat.ID, UserID: "alice", Type: model.MessageTypeText, Content: "hello", CreatedAt: now}
+		kept := &model.Message{ID: "a2000000-0000-0000-0000-000000000002", ChatID: other.ID, UserID: "owner", Type: model.MessageTypeText, Content: "stays", CreatedAt: now}
+		for _, m := range []*model.Message{message, kept} {
+			if err := repo.CreateMessage(m); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		edited := *message
+		edited.Content = "hello again"
+		revision := &model.MessageRevision{ID: "a2000000-0000-0000-0000-000000000003", MessageID: message.ID, Content: message.Content, EditedBy: "alice", CreatedAt: now}
+		if err := repo.UpdateMessage(&edited, revision); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+		if err := repo.AddReaction(&model.Reaction{MessageID: message.ID, UserID: "owner", ChatID: chat.ID, Emoji: "👍", CreatedAt: now}); err != nil {
+			t.Fatalf("AddReaction failed: %v", err)
+		}
+		if err := repo.PinMessage(&model.Pin{ChatID: chat.ID, MessageID: message.ID, PinnedBy: "owner", CreatedAt: now}, 10); err != nil {
+			t.Fatalf("PinMessage failed: %v", err)
+		}
+		if err := repo.BanParticipant(&model.Ban{ChatID: chat.ID, UserID: "bob", BannedBy: "owner", CreatedAt: now}); err != nil {
+			t.Fatalf("BanParticipant failed: %v", err)
+		}
+		if err := repo.CreateInvite(&model.Invite{ID: "a2000000-0000-0000-0000-000000000004", ChatID: chat.ID, Token: "delete-me", CreatedBy: "owner", CreatedAt: now}); err != nil {
+			t.Fatalf("CreateInvite failed: %v", err)
+		}
+		if err := repo.CreateAttachment(&model.Attachment{ID: "a2000000-0000-0000-0000-000000000005", ChatID: chat.ID, UploaderID: "alice", SHA256: "abc", Size: 1, MimeType: "text/plain", FileName: "a.txt", CreatedAt: now}); err != nil {
+			t.Fatalf("CreateAttachment failed: %v", err)
+		}
+
+		if err := repo.DeleteChat(chat.ID); err != nil {
+			t.Fatalf("DeleteChat failed: %v", err)
+		}
+
+		if _, err := repo.GetChatByID(chat.ID); !errors.Is(err, ErrChatNotFound) {
+			t.Errorf("Expected ErrChatNotFound, got %v", err)
+		}
+		if _, err := repo.GetMessageByID(message.ID); !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("Expected ErrMessageNotFound, got %v", err)
+		}
+		if revisions, _ := repo.GetMessageRevisions(message.ID); len(revisions) != 0 {
+			t.Errorf("Expected revisions to be deleted, got %d", len(revisions))
+		}
+		if counts, _ := repo.GetReactionCounts([]string{message.ID}, "owner"); len(counts[message.ID]) != 0 {
+			t.Errorf("Expected reactions to be deleted, got %+v", counts)
+		}
+		if pins, _ := repo.GetPins(chat.ID); len(pins) != 0 {
+			t.Errorf("Expected pins to be deleted, got %d", len(pins))
+		}
+		if isBanned, _ := repo.IsBanned(chat.ID, "bob"); isBanned {
+			t.Error("Expected ban to be deleted")
+		}
+		if _, err := repo.GetInviteByToken("delete-me"); !errors.Is(err, ErrInviteNotFound) {
+			t.Errorf("Expected ErrInviteNotFound, got %v", err)
+		}
+		if _, err := repo.GetAttachment("a2000000-0000-0000-0000-000000000005"); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+		}
+		if chatIDs, _ := repo.GetUserChatIDs("alice"); len(chatIDs) != 0 {
+			t.Errorf("Expected alice to have no chats, got %v", chatIDs)
+		}
+
+		if _, err := repo.GetMessageByID(kept.ID); err != nil {
+			t.Errorf("Expected other chat's message to survive, got %v", err)
+		}
+		if err := repo.DeleteChat(chat.ID); !errors.Is(err, ErrChatNotFound) {
+			t.Errorf("Expected ErrChatNotFound on repeated delete, got %v", err)
+		}
+	})
+}
+
+// TestChatRepository_GetOrCreateMessage тестирует дедупликацию по клиентскому ключу
+func TestChatRepository_GetOrCreateMessage(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		base := time.Now()
+		newMessage := func(id, userID string, createdAt time.Time) *model.Message {
+			return &model.Message{ID: id, ChatID: chat.ID, UserID: userID, Type: model.MessageTypeText, Content: "hello", CreatedAt: createdAt}
+		}
+
+		first, created, err := repo.GetOrCreateMessage(newMessage("a3000000-0000-0000-0000-000000000001", "alice", base), "key", base.Add(-time.Hour))
+		if err != nil || !created {
+			t.Fatalf("GetOrCreateMessage failed: created=%v, err=%v", created, err)
+		}
+
+		retry, created, err := repo.GetOrCreateMessage(newMessage("a3000000-0000-0000-0000-000000000002", "alice", base), "key", base.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("GetOrCreateMessage retry failed: %v", err)
+		}
+		if created || retry.ID != first.ID || retry.Seq != first.Seq {
+			t.Errorf("Expected retry to return the first message, got %+v (created=%v)", retry, created)
+		}
+
+		if found, err := repo.GetMessageByKey(chat.ID, "alice", "key", base.Add(-time.Hour)); err != nil || found.ID != first.ID {
+			t.Errorf("Expected GetMessageByKey to find the first message, got %+v, %v", found, err)
+		}
+		if _, err := repo.GetMessageByKey(chat.ID, "alice", "key", base.Add(time.Second)); !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("Expected ErrMessageNotFound for an expired key, got %v", err)
+		}
+		if _, err := repo.GetMessageByKey(chat.ID, "owner", "key", base.Add(-time.Hour)); !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("Expected ErrMessageNotFound for another user, got %v", err)
+		}
+
+		// Ключ другого автора не пересекается с ключом alice
+		if _, created, _ := repo.GetOrCreateMessage(newMessage("a3000000-0000-0000-0000-000000000003", "owner", base), "key", base.Add(-time.Hour)); !created {
+			t.Error("Expected the same key from another user to create a message")
+		}
+
+		// Ключ старше since устарел: создается новое сообщение
+		later := base.Add(2 * time.Hour)
+		renewed, created, err := repo.GetOrCreateMessage(newMessage("a3000000-0000-0000-0000-000000000004", "alice", later), "key", later.Add(-time.Hour))
+		if err != nil || !created || renewed.ID == first.ID {
+			t.Errorf("Expected a new message after the key expired, got %+v (created=%v, err=%v)", renewed, created, err)
+		}
+
+		messages, _ := repo.GetMessages(MessageQuery{ChatID: chat.ID, Limit: 10})
+		if len(messages) != 3 || messages[2].Seq != 3 {
+			t.Errorf("Expected 3 messages without gaps in seq, got %d", len(messages))
+		}
+
+		// Остались ключи owner (base) и обновленный ключ alice (later)
+		deleted, err := repo.DeleteMessageKeys(base.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteMessageKeys failed: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("Expected 1 expired key, got %d", deleted)
+		}
+	})
+}
+
+func TestChatRepository_Mentions(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice", "bob")
+
+		base := time.Now()
+		messages := []*model.Message{
+			{ID: "a4000000-0000-0000-0000-000000000001", Mentions: []string{"bob", "alice"}},
+			{ID: "a4000000-0000-0000-0000-000000000002"},
+			{ID: "a4000000-0000-0000-0000-000000000003", Mentions: []string{"alice"}},
+		}
+		for i, message := range messages {
+			message.ChatID, message.UserID, message.Type, message.Content = chat.ID, "owner", model.MessageTypeText, "hi"
+			message.CreatedAt = base.Add(time.Duration(i) * time.Second)
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		mentions, err := repo.GetMentions([]string{messages[0].ID, messages[1].ID, messages[2].ID})
+		if err != nil {
+			t.Fatalf("GetMentions failed: %v", err)
+		}
+		if len(mentions) != 2 || !slices.Equal(mentions[messages[0].ID], []string{"alice", "bob"}) {
+			t.Errorf("Unexpected mentions: %v", mentions)
+		}
+
+		if err := repo.MarkRead(chat.ID, "alice", messages[0].Seq, base); err != nil {
+			t.Fatalf("MarkRead failed: %v", err)
+		}
+
+		got, err := repo.GetUserMentions(MentionQuery{UserID: "alice", Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserMentions failed: %v", err)
+		}
+		if len(got) != 2 || got[0].Message.ID != messages[2].ID || !got[0].Unread || got[1].Unread {
+			t.Fatalf("Unexpected user mentions: %+v %+v", got[0], got[1])
+		}
+
+		unread, _ := repo.GetUserMentions(MentionQuery{UserID: "alice", UnreadOnly: true, Limit: 10})
+		if len(unread) != 1 || unread[0].Message.ID != messages[2].ID {
+			t.Errorf("Expected 1 unread mention, got %d", len(unread))
+		}
+
+		page, _ := repo.GetUserMentions(MentionQuery{UserID: "alice", Limit: 1, Offset: 1})
+		if len(page) != 1 || page[0].Message.ID != messages[0].ID {
+			t.Errorf("Expected the second page to hold the first message, got %d", len(page))
+		}
+
+		// Бывший участник своих упоминаний в чате не видит
+		if err := repo.RemoveParticipant(chat.ID, "bob"); err != nil {
+			t.Fatalf("RemoveParticipant failed: %v", err)
+		}
+		if left, _ := repo.GetUserMentions(MentionQuery{UserID: "bob", Limit: 10}); len(left) != 0 {
+			t.Errorf("Expected no mentions after leaving, got %d", len(left))
+		}
+	})
+}
+
+func TestChatRepository_ScheduledMessages(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		base := time.Now().Truncate(time.Second)
+		key := "key"
+		newScheduled := func(id, userID string, sendAt time.Time) *model.ScheduledMessage {
+			return &model.ScheduledMessage{ID: id, ChatID: chat.ID, UserID: userID, Type: model.MessageTypeText, Content: "later", SendAt: sendAt, CreatedAt: base}
+		}
+
+		later := newScheduled("a5000000-0000-0000-0000-000000000001", "alice", base.Add(2*time.Minute))
+		later.ClientMessageID = &key
+		if _, created, err := repo.CreateScheduledMessage(later); err != nil || !created {
+			t.Fatalf("CreateScheduledMessage failed: created=%v, err=%v", created, err)
+		}
+
+		retry := newScheduled("a5000000-0000-0000-0000-000000000002", "alice", base.Add(time.Hour))
+		retry.ClientMessageID = &key
+		existing, created, err := repo.CreateScheduledMessage(retry)
+		if err != nil || created || existing.ID != later.ID {
+			t.Errorf("Expected the retry to return the pending message, got %+v (created=%v, err=%v)", existing, created, err)
+		}
+
+		// Тот же ключ в другом чате - другое сообщение
+		elsewhere := newScheduled("a5000000-0000-0000-0000-000000000005", "alice", base.Add(time.Hour))
+		elsewhere.ChatID = "22222222-2222-2222-2222-222222222222"
+		elsewhere.ClientMessageID = &key
+		if _, created, err := repo.CreateScheduledMessage(elsewhere); err != nil || !created {
+			t.Errorf("Expected the key to be scoped to the chat, got created=%v, err=%v", created, err)
+		}
+		if err := repo.DeleteScheduledMessage(elsewhere.ID); err != nil {
+			t.Fatalf("DeleteScheduledMessage failed: %v", err)
+		}
+
+		sooner := newScheduled("a5000000-0000-0000-0000-000000000003", "alice", base.Add(time.Minute))
+		ownerMessage := newScheduled("a5000000-0000-0000-0000-000000000004", "owner", base.Add(time.Hour))
+		for _, scheduled := range []*model.ScheduledMessage{sooner, ownerMessage} {
+			if _, _, err := repo.CreateScheduledMessage(scheduled); err != nil {
+				t.Fatalf("CreateScheduledMessage failed: %v", err)
+			}
+		}
+
+		pending, err := repo.GetScheduledMessages("alice", chat.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessages failed: %v", err)
+		}
+		if len(pending) != 2 || pending[0].ID != sooner.ID || pending[1].ID != later.ID {
+			t.Fatalf("Expected alice's messages in send_at order, got %d", len(pending))
+		}
+		if *pending[1].ClientMessageID != key {
+			t.Errorf("Expected client_message_id to be stored, got %v", pending[1].ClientMessageID)
+		}
+
+		due, err := repo.GetDueScheduledMessages(base.Add(5*time.Minute), 10)
+		if err != nil {
+			t.Fatalf("GetDueScheduledMessages failed: %v", err)
+		}
+		if len(due) != 2 || due[0].ID != sooner.ID {
+			t.Fatalf("Expected 2 due messages, got %d", len(due))
+		}
+
+		expiresAt := base.Add(10 * time.Second)
+		message := &model.Message{ID: sooner.ID, ChatID: chat.ID, UserID: "alice", Type: model.MessageTypeText, Content: "later", CreatedAt: base, ExpiresAt: &expiresAt}
+		if created, err := repo.DeliverScheduledMessage(message, "", base); err != nil || !created {
+			t.Fatalf("DeliverScheduledMessage failed: created=%v, err=%v", created, err)
+		}
+		if message.Seq != 1 {
+			t.Errorf("Expected the delivered message to get seq 1, got %d", message.Seq)
+		}
+
+		duplicate := *message
+		if _, err := repo.DeliverScheduledMessage(&duplicate, "", base); !errors.Is(err, ErrScheduledMessageNotFound) {
+			t.Errorf("Expected ErrScheduledMessageNotFound on repeated delivery, got %v", err)
+		}
+		if _, err := repo.GetScheduledMessage(sooner.ID); !errors.Is(err, ErrScheduledMessageNotFound) {
+			t.Errorf("Expected the delivered message to leave the queue, got %v", err)
+		}
+
+		if found, err := repo.GetScheduledMessageByKey(chat.ID, "alice", key); err != nil || found.ID != later.ID {
+			t.Errorf("Expected to find the pending message by key, got %+v (err=%v)", found, err)
+		}
+		if _, err := repo.GetScheduledMessageByKey(chat.ID, "owner", key); !errors.Is(err, ErrScheduledMessageNotFound) {
+			t.Errorf("Expected ErrScheduledMessageNotFound for another user, got %v", err)
+		}
+
+		if err := repo.DeleteScheduledMessage(later.ID); err != nil {
+			t.Fatalf("DeleteScheduledMessage failed: %v", err)
+		}
+		if err := repo.DeleteScheduledMessage(later.ID); !errors.Is(err, ErrScheduledMessageNotFound) {
+			t.Errorf("Expected ErrScheduledMessageNotFound, got %v", err)
+		}
+
+		// Доставка запоминает ключ, и повторно отложенное с ним сообщение отбрасывается
+		since := base.Add(-time.Hour)
+		keyed := newScheduled("a5000000-0000-0000-0000-000000000006", "alice", base)
+		keyed.ClientMessageID = &key
+		again := newScheduled("a5000000-0000-0000-0000-000000000007", "alice", base)
+		again.ClientMessageID = &key
+		for i, scheduled := range []*model.ScheduledMessage{keyed, again} {
+			if _, created, err := repo.CreateScheduledMessage(scheduled); err != nil || !created {
+				t.Fatalf("CreateScheduledMessage failed: created=%v, err=%v", created, err)
+			}
+
+			delivered := &model.Message{ID: scheduled.ID, ChatID: chat.ID, UserID: "alice", Type: model.MessageTypeText, Content: "later", CreatedAt: base}
+			created, err := repo.DeliverScheduledMessage(delivered, key, since)
+			if err != nil || created != (i == 0) {
+				t.Errorf("Expected delivery %d to return created=%v, got created=%v, err=%v", i, i == 0, created, err)
+			}
+			if _, err := repo.GetScheduledMessage(scheduled.ID); !errors.Is(err, ErrScheduledMessageNotFound) {
+				t.Errorf("Expected delivery %d to leave the queue, got %v", i, err)
+			}
+		}
+		if stored, err := repo.GetMessageByKey(chat.ID, "alice", key, since); err != nil || stored.ID != keyed.ID {
+			t.Errorf("Expected the key to point at the first delivery, got %+v (err=%v)", stored, err)
+		}
+		if _, err := repo.GetMessageByID(again.ID); err == nil {
+			t.Error("Expected the repeated delivery not to create a message")
+		}
+
+		if expired, _ := repo.GetExpiredMessages(base.Add(5*time.Second), 10); len(expired) != 0 {
+			t.Errorf("Expected no expired messages yet, got %d", len(expired))
+		}
+		expired, err := repo.GetExpiredMessages(expiresAt, 10)
+		if err != nil {
+			t.Fatalf("GetExpiredMessages failed: %v", err)
+		}
+		if len(expired) != 1 || expired[0].ID != message.ID {
+			t.Fatalf("Expected the message to expire, got %d", len(expired))
+		}
+
+		deletedAt := expiresAt
+		expired[0].Content, expired[0].DeletedAt = "", &deletedAt
+		if err := repo.UpdateMessage(expired[0], nil); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+		if expired, _ := repo.GetExpiredMessages(expiresAt, 10); len(expired) != 0 {
+			t.Errorf("Expected tombstoned messages to be skipped, got %d", len(expired))
+		}
+
+		if err := repo.DeleteChat(chat.ID); err != nil {
+			t.Fatalf("DeleteChat failed: %v", err)
+		}
+		if _, err := repo.GetScheduledMessage(ownerMessage.ID); !errors.Is(err, ErrScheduledMessageNotFound) {
+			t.Errorf("Expected DeleteChat to drop scheduled messages, got %v", err)
+		}
+	})
+}
+
+func TestChatRepository_Polls(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice", "bob")
+
+		base := time.Now().Truncate(time.Second)
+		closesAt := base.Add(time.Hour)
+		message := &model.Message{
+			ID:        "a6000000-0000-0000-0000-000000000001",
+			ChatID:    chat.ID,
+			UserID:    "owner",
+			Type:      model.MessageTypePoll,
+			Content:   "Lunch?",
+			CreatedAt: base,
+			Poll:      &model.Poll{Options: model.PollOptions{"pizza", "sushi"}, MultipleChoice: true, ClosesAt: &closesAt},
+		}
+		if err := repo.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+
+		polls, err := repo.GetPolls([]string{message.ID, "missing"})
+		if err != nil {
+			t.Fatalf("GetPolls failed: %v", err)
+		}
+		poll := polls[message.ID]
+		if len(polls) != 1 || poll.ChatID != chat.ID || !slices.Equal(poll.Options, model.PollOptions{"pizza", "sushi"}) || !poll.MultipleChoice {
+			t.Fatalf("Unexpected polls: %+v", polls)
+		}
+
+		vote := func(userID string, at time.Time, options ...int) []*model.PollVote {
+			var votes []*model.PollVote
+			for _, option := range options {
+				votes = append(votes, &model.PollVote{MessageID: message.ID, UserID: userID, Option: option, ChatID: chat.ID, CreatedAt: at})
+			}
+			return votes
+		}
+
+		if err := repo.SetPollVotes(message.ID, "alice", vote("alice", base, 0, 1), base); err != nil {
+			t.Fatalf("SetPollVotes failed: %v", err)
+		}
+		if err := repo.SetPollVotes(message.ID, "bob", vote("bob", base.Add(time.Second), 1), base); err != nil {
+			t.Fatalf("SetPollVotes failed: %v", err)
+		}
+		// Новый голос alice заменяет оба предыдущих
+		if err := repo.SetPollVotes(message.ID, "alice", vote("alice", base.Add(2*time.Second), 0), base); err != nil {
+			t.Fatalf("SetPollVotes failed: %v", err)
+		}
+
+		votes, err := repo.GetPollVotes([]string{message.ID})
+		if err != nil {
+			t.Fatalf("GetPollVotes failed: %v", err)
+		}
+		got := votes[message.ID]
+		if len(got) != 2 || got[0].UserID != "bob" || got[1].UserID != "alice" || got[1].Option != 0 {
+			t.Fatalf("Unexpected votes: %+v", got)
+		}
+
+		if err := repo.SetPollVotes(message.ID, "bob", nil, base); err != nil {
+			t.Fatalf("SetPollVotes retract failed: %v", err)
+		}
+		if votes, _ := repo.GetPollVotes([]string{message.ID}); len(votes[message.ID]) != 1 {
+			t.Errorf("Expected 1 vote after retracting, got %d", len(votes[message.ID]))
+		}
+
+		if err := repo.SetPollVotes(message.ID, "bob", vote("bob", closesAt, 0), closesAt); !errors.Is(err, ErrPollClosed) {
+			t.Errorf("Expected ErrPollClosed after closes_at, got %v", err)
+		}
+		if err := repo.SetPollVotes("missing", "bob", nil, base); !errors.Is(err, ErrPollNotFound) {
+			t.Errorf("Expected ErrPollNotFound, got %v", err)
+		}
+
+		if due, _ := repo.GetDuePolls(base, 10); len(due) != 0 {
+			t.Errorf("Expected no due polls yet, got %d", len(due))
+		}
+		due, err := repo.GetDuePolls(closesAt, 10)
+		if err != nil || len(due) != 1 || due[0].MessageID != message.ID {
+			t.Fatalf("Expected the poll to be due, got %d (err=%v)", len(due), err)
+		}
+
+		if err := repo.ClosePoll(message.ID, closesAt); err != nil {
+			t.Fatalf("ClosePoll failed: %v", err)
+		}
+		if err := repo.ClosePoll(message.ID, closesAt); !errors.Is(err, ErrPollClosed) {
+			t.Errorf("Expected ErrPollClosed on a repeated close, got %v", err)
+		}
+		if err := repo.ClosePoll("a6000000-0000-0000-0000-000000000002", closesAt); !errors.Is(err, ErrPollNotFound) {
+			t.Errorf("Expected ErrPollNotFound, got %v", err)
+		}
+		if due, _ := repo.GetDuePolls(closesAt, 10); len(due) != 0 {
+			t.Errorf("Expected closed polls not to be due, got %d", len(due))
+		}
+
+		polls, _ = repo.GetPolls([]string{message.ID})
+		if closedAt := polls[message.ID].ClosedAt; closedAt == nil || !closedAt.Equal(closesAt) {
+			t.Errorf("Expected closed_at to be stored, got %v", closedAt)
+		}
+	})
+}
+
+func TestChatRepository_Webhooks(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo)
+
+		base := time.Now().Truncate(time.Second)
+		webhook := &model.Webhook{
+			ID:        "a7000000-0000-0000-0000-000000000001",
+			ChatID:    chat.ID,
+			URL:       "https://example.com/hook",
+			Secret:    "0123456789abcdef",
+			Events:    model.WebhookEvents{model.EventMessageCreated},
+			CreatedBy: "owner",
+			CreatedAt: base,
+		}
+		if err := repo.CreateWebhook(webhook); err != nil {
+			t.Fatalf("CreateWebhook failed: %v", err)
+		}
+
+		webhooks, err := repo.GetWebhooks(chat.ID)
+		if err != nil {
+			t.Fatalf("GetWebhooks failed: %v", err)
+		}
+		if len(webhooks) != 1 || webhooks[0].Secret != webhook.Secret || !webhooks[0].Accepts(model.EventMessageCreated) || webhooks[0].Accepts(model.EventMessageEdited) {
+			t.Fatalf("Expected the webhook with its event filter, got %+v", webhooks)
+		}
+
+		newDelivery := func(id string, createdAt time.Time) *model.WebhookDelivery {
+			return &model.WebhookDelivery{
+				ID:            id,
+				WebhookID:     webhook.ID,
+				ChatID:        chat.ID,
+				EventType:     model.EventMessageCreated,
+				Payload:       `{"type":"message.created"}`,
+				Status:        model.WebhookDeliveryPending,
+				NextAttemptAt: &createdAt,
+				CreatedAt:     createdAt,
+			}
+		}
+
+		first := newDelivery("a7000000-0000-0000-0000-000000000002", base)
+		second := newDelivery("a7000000-0000-0000-0000-000000000003", base.Add(time.Minute))
+		if err := repo.CreateWebhookDeliveries([]*model.WebhookDelivery{first, second}); err != nil {
+			t.Fatalf("CreateWebhookDeliveries failed: %v", err)
+		}
+
+		due, err := repo.GetDueWebhookDeliveries(base.Add(time.Second), 10)
+		if err != nil {
+			t.Fatalf("GetDueWebhookDeliveries failed: %v", err)
+		}
+		if len(due) != 1 || due[0].ID != first.ID {
+			t.Fatalf("Expected only the first delivery to be due, got %d", len(due))
+		}
+
+		leaseUntil := base.Add(time.Hour)
+		if claimed, err := repo.ClaimWebhookDelivery(first.ID, 0, leaseUntil); err != nil || !claimed {
+			t.Fatalf("ClaimWebhookDelivery failed: claimed=%v, err=%v", claimed, err)
+		}
+		if claimed, _ := repo.ClaimWebhookDelivery(first.ID, 0, leaseUntil); claimed {
+			t.Error("Expected the attempt to be claimed once")
+		}
+		if due, _ := repo.GetDueWebhookDeliveries(base.Add(2*time.Minute), 10); len(due) != 1 || due[0].ID != second.ID {
+			t.Errorf("Expected the claimed delivery to wait for the lease, got %d due", len(due))
+		}
+
+		attemptAt := base.Add(time.Second)
+		first.Attempts = 1
+		first.Status = model.WebhookDeliveryDead
+		first.NextAttemptAt = nil
+		first.LastAttemptAt = &attemptAt
+		first.ResponseStatus = 500
+		first.LastError = "receiver responded with 500 Internal Server Error"
+		if err := repo.UpdateWebhookDelivery(first); err != nil {
+			t.Fatalf("UpdateWebhookDelivery failed: %v", err)
+		}
+
+		stored, err := repo.GetWebhookDelivery(first.ID)
+		if err != nil {
+			t.Fatalf("GetWebhookDelivery failed: %v", err)
+		}
+		if stored.Status != model.WebhookDeliveryDead || stored.Attempts != 1 || stored.NextAttemptAt != nil || stored.ResponseStatus != 500 {
+			t.Errorf("Unexpected stored delivery: %+v", stored)
+		}
+
+		log, err := repo.GetWebhookDeliveries(WebhookDeliveryQuery{WebhookID: webhook.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetWebhookDeliveries failed: %v", err)
+		}
+		if len(log) != 2 || log[0].ID != second.ID || log[1].ID != first.ID {
+			t.Fatalf("Expected the delivery log from newest to oldest, got %d", len(log))
+		}
+
+		dead, _ := repo.GetWebhookDeliveries(WebhookDeliveryQuery{WebhookID: webhook.ID, Status: model.WebhookDeliveryDead})
+		if len(dead) != 1 || dead[0].ID != first.ID {
+			t.Errorf("Expected 1 dead delivery, got %d", len(dead))
+		}
+
+		if err := repo.DeleteWebhook(webhook.ID); err != nil {
+			t.Fatalf("DeleteWebhook failed: %v", err)
+		}
+		if _, err := repo.GetWebhookDelivery(second.ID); !errors.Is(err, ErrWebhookDeliveryNotFound) {
+			t.Errorf("Expected deliveries to be deleted with the webhook, got %v", err)
+		}
+		if err := repo.DeleteWebhook(webhook.ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+	})
+}
+
+func TestChatRepository_Commands(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "bot")
+
+		deploy := &model.Command{
+			ChatID:      chat.ID,
+			Name:        "deploy",
+			BotID:       "bot",
+			Description: "Deploy a service",
+			Args:        model.CommandArgs{{Name: "service", Type: model.CommandArgString, Required: true}},
+			CreatedAt:   time.Now(),
+		}
+		if err := repo.SaveCommand(deploy); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+		repo.SaveCommand(&model.Command{ChatID: chat.ID, Name: "build", BotID: "bot", CreatedAt: time.Now()})
+
+		// Повторная регистрация тем же ботом обновляет команду
+		deploy.Description = "Deploy a service to production"
+		if err := repo.SaveCommand(deploy); err != nil {
+			t.Fatalf("SaveCommand update failed: %v", err)
+		}
+
+		if err := repo.SaveCommand(&model.Command{ChatID: chat.ID, Name: "deploy", BotID: "other-bot"}); !errors.Is(err, ErrCommandTaken) {
+			t.Errorf("Expected ErrCommandTaken, got %v", err)
+		}
+
+		command, err := repo.GetCommand(chat.ID, "deploy")
+		if err != nil {
+			t.Fatalf("GetCommand failed: %v", err)
+		}
+		if command.BotID != "bot" || command.Description != deploy.Description || len(command.Args) != 1 || command.Args[0].Name != "service" || !command.Args[0].Required {
+			t.Errorf("Unexpected command: %+v", command)
+		}
+
+		commands, err := repo.GetCommands(chat.ID)
+		if err != nil {
+			t.Fatalf("GetCommands failed: %v", err)
+		}
+		if len(commands) != 2 || commands[0].Name != "build" || commands[1].Name != "deploy" {
+			t.Errorf("Expected commands ordered by name, got %d", len(commands))
+		}
+
+		if err := repo.DeleteCommand(chat.ID, "build"); err != nil {
+			t.Fatalf("DeleteCommand failed: %v", err)
+		}
+		if err := repo.DeleteCommand(chat.ID, "build"); !errors.Is(err, ErrCommandNotFound) {
+			t.Errorf("Expected ErrCommandNotFound, got %v", err)
+		}
+
+		if err := repo.DeleteChat(chat.ID); err != nil {
+			t.Fatalf("DeleteChat failed: %v", err)
+		}
+		if _, err := repo.GetCommand(chat.ID, "deploy"); !errors.Is(err, ErrCommandNotFound) {
+			t.Errorf("Expected commands to be deleted with the chat, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+)
+
+// SaveCommand регистрирует команду бота в чате или обновляет ее описание
+// и аргументы. Если имя занято другим ботом, возвращает ErrCommandTaken.
+func (r *GormChatRepository) SaveCommand(command *model.Command) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.Command
+		err := tx.Where("chat_id = ? AND name = ?", command.ChatID, command.Name).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(command).Error
+		case err != nil:
+			return err
+		case existing.BotID != command.BotID:
+			return ErrCommandTaken
+		}
+
+		command.CreatedAt = existing.CreatedAt
+		return tx.Save(command).Error
+	})
+}
+
+// GetCommand получает команду чата по имени
+func (r *GormChatRepository) GetCommand(chatID, name string) (*model.Command, error) {
+	var command model.Command
+	if err := r.db.Where("chat_id = ? AND name = ?", chatID, name).First(&command).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommandNotFound
+		}
+		return nil, err
+	}
+
+	return &command, nil
+}
+
+// GetCommands возвращает команды ботов в чате, упорядоченные по имени
+func (r *GormChatRepository) GetCommands(chatID string) ([]*model.Command, error) {
+	var commands []*model.Command
+	err := r.db.Where("chat_id = ?", chatID).Order("name").Find(&commands).Error
+	return commands, err
+}
+
+// DeleteCommand удаляет команду из чата
+func (r *GormChatRepository) DeleteCommand(chatID, name string) error {
+	result := r.db.Where("chat_id = ? AND name = ?", chatID, name).Delete(&model.Command{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrCommandNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,1710 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+// InMemoryChatRepository хранит чаты в памяти процесса (используется в тестах)
+type InMemoryChatRepository struct {
+	mu           sync.RWMutex
+	chats        map[string]*model.Chat
+	participants map[string][]*model.Participant // chat_id -> участники в порядке присоединения
+	messages     map[string][]*model.Message     // chat_id -> сообщения в порядке создания
+	messageByID  map[string]*model.Message       // id -> то же сообщение, что и в messages
+	revisions    map[string][]*model.MessageRevision
+	bans         map[string]map[string]*model.Ban // chat_id -> user_id -> бан
+	invites      map[string]*model.Invite         // token -> приглашение
+	joinRequests map[string][]*model.JoinRequest  // chat_id -> заявки в порядке подачи
+	attachments  map[string]*model.Attachment
+	reactions    map[string][]*model.Reaction // message_id -> реакции в порядке добавления
+	pins         map[string][]*model.Pin      // chat_id -> закрепления в порядке добавления
+	messageKeys  map[messageKey]*model.MessageKey
+	mentions     map[string][]string // message_id -> упомянутые пользователи по user_id
+	scheduled    map[string]*model.ScheduledMessage
+	polls        map[string]*model.Poll       // message_id -> опрос без итогов
+	pollVotes    map[string][]*model.PollVote // message_id -> голоса в порядке подачи
+	webhooks     map[string]*model.Webhook
+	deliveries   map[string]*model.WebhookDelivery
+	commands     map[string]map[string]*model.Command // chat_id -> имя -> команда бота
+}
+
+// messageKey - первичный ключ model.MessageKey
+type messageKey struct {
+	chatID, userID, clientMessageID string
+}
+
+// NewInMemoryChatRepository создает новый репозиторий в памяти
+func NewInMemoryChatRepository() *InMemoryChatRepository {
+	return &InMemoryChatRepository{
+		chats:        make(map[string]*model.Chat),
+		participants: make(map[string][]*model.Participant),
+		messages:     make(map[string][]*model.Message),
+		messageByID:  make(map[string]*model.Message),
+		revisions:    make(map[string][]*model.MessageRevision),
+		bans:         make(map[string]map[string]*model.Ban),
+		invites:      make(map[string]*model.Invite),
+		joinRequests: make(map[string][]*model.JoinRequest),
+		attachments:  make(map[string]*model.Attachment),
+		reactions:    make(map[string][]*model.Reaction),
+		pins:         make(map[string][]*model.Pin),
+		messageKeys:  make(map[messageKey]*model.MessageKey),
+		mentions:     make(map[string][]string),
+		scheduled:    make(map[string]*model.ScheduledMessage),
+		polls:        make(map[string]*model.Poll),
+		pollVotes:    make(map[string][]*model.PollVote),
+		webhooks:     make(map[string]*model.Webhook),
+		deliveries:   make(map[string]*model.WebhookDelivery),
+		commands:     make(map[string]map[string]*model.Command),
+	}
+}
+
+// CreateChat сохраняет чат и его участников
+func (r *InMemoryChatRepository) CreateChat(chat *model.Chat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.createChatLocked(chat)
+	return nil
+}
+
+func (r *InMemoryChatRepository) createChatLocked(chat *model.Chat) {
+	if chat.CreatedAt.IsZero() {
+		chat.CreatedAt = time.Now()
+	}
+	chat.UpdatedAt = chat.CreatedAt
+
+	stored := *chat
+	stored.Participants = nil
+	r.chats[chat.ID] = &stored
+
+	for _, participant := range chat.Participants {
+		participant.ChatID = chat.ID
+		r.addParticipantLocked(participant)
+	}
+}
+
+// GetOrCreateDirectChat возвращает личный чат с ключом chat.DirectKey или
+// создает chat вместе с участниками. Второй результат - был ли чат создан.
+func (r *InMemoryChatRepository) GetOrCreateDirectChat(chat *model.Chat) (*model.Chat, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.chats {
+		if stored.DirectKey != nil && *stored.DirectKey == *chat.DirectKey {
+			existing := *stored
+			existing.Participants = r.participantsLocked(stored.ID)
+			return &existing, false, nil
+		}
+	}
+
+	r.createChatLocked(chat)
+
+	created := *r.chats[chat.ID]
+	created.Participants = r.participantsLocked(chat.ID)
+	return &created, true, nil
+}
+
+// GetChatByID получает копию чата вместе со списком участников
+func (r *InMemoryChatRepository) GetChatByID(id string) (*model.Chat, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.chats[id]
+	if !exists {
+		return nil, ErrChatNotFound
+	}
+
+	chat := *stored
+	chat.Participants = r.participantsLocked(id)
+	return &chat, nil
+}
+
+// UpdateChat сохраняет перечисленные поля чата и updated_at
+func (r *InMemoryChatRepository) UpdateChat(chat *model.Chat, fields ...string) error {
+	if len(fields) == 0 {
+		return errNoChatFields
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.chats[chat.ID]
+	if !exists {
+		return ErrChatNotFound
+	}
+
+	updated := *stored
+	for _, field := range fields {
+		switch field {
+		case "Name":
+			updated.Name = chat.Name
+		case "IsPrivate":
+			updated.IsPrivate = chat.IsPrivate
+		case "MaxParticipants":
+			updated.MaxParticipants = chat.MaxParticipants
+		case "IsAnnouncement":
+			updated.IsAnnouncement = chat.IsAnnouncement
+		case "Topic":
+			updated.Topic = chat.Topic
+		case "SlowModeSeconds":
+			updated.SlowModeSeconds = chat.SlowModeSeconds
+		case "ArchivedAt":
+			updated.ArchivedAt = chat.ArchivedAt
+		default:
+			return fmt.Errorf("unsupported chat field %q", field)
+		}
+	}
+
+	chat.UpdatedAt = time.Now()
+	updated.UpdatedAt = chat.UpdatedAt
+	r.chats[chat.ID] = &updated
+	return nil
+}
+
+// DeleteChat удаляет чат и все связанные с ним записи
+func (r *InMemoryChatRepository) DeleteChat(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[id]; !exists {
+		return ErrChatNotFound
+	}
+
+	for _, message := range r.messages[id] {
+		delete(r.messageByID, message.ID)
+		delete(r.revisions, message.ID)
+		delete(r.reactions, message.ID)
+		delete(r.mentions, message.ID)
+		delete(r.polls, message.ID)
+		delete(r.pollVotes, message.ID)
+	}
+
+	for token, invite := range r.invites {
+		if invite.ChatID == id {
+			delete(r.invites, token)
+		}
+	}
+
+	for attachmentID, attachment := range r.attachments {
+		if attachment.ChatID == id {
+			delete(r.attachments, attachmentID)
+		}
+	}
+
+	for key := range r.messageKeys {
+		if key.chatID == id {
+			delete(r.messageKeys, key)
+		}
+	}
+
+	for scheduledID, scheduled := range r.scheduled {
+		if scheduled.ChatID == id {
+			delete(r.scheduled, scheduledID)
+		}
+	}
+
+	for webhookID, webhook := range r.webhooks {
+		if webhook.ChatID == id {
+			delete(r.webhooks, webhookID)
+		}
+	}
+
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.ChatID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+
+	delete(r.chats, id)
+	delete(r.participants, id)
+	delete(r.messages, id)
+	delete(r.bans, id)
+	delete(r.joinRequests, id)
+	delete(r.pins, id)
+	delete(r.commands, id)
+	return nil
+}
+
+// AddParticipant добавляет участника в чат (повторное добавление игнорируется).
+// Если в чате уже MaxParticipants участников, возвращает ErrChatFull.
+func (r *InMemoryChatRepository) AddParticipant(participant *model.Participant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.joinLocked(participant)
+}
+
+// joinLocked добавляет участника с проверкой лимита чата
+func (r *InMemoryChatRepository) joinLocked(participant *model.Participant) error {
+	chat, exists := r.chats[participant.ChatID]
+	if !exists {
+		return ErrChatNotFound
+	}
+
+	participants := r.participants[participant.ChatID]
+	for _, existing := range participants {
+		if existing.UserID == participant.UserID {
+			return nil
+		}
+	}
+
+	if chat.MaxParticipants > 0 && len(participants) >= chat.MaxParticipants {
+		return ErrChatFull
+	}
+
+	// История, отправленная до вступления, не считается непрочитанной
+	if participant.LastReadSeq == 0 {
+		participant.LastReadSeq = chat.LastSeq
+	}
+
+	r.addParticipantLocked(participant)
+	return nil
+}
+
+func (r *InMemoryChatRepository) addParticipantLocked(participant *model.Participant) {
+	for _, existing := range r.participants[participant.ChatID] {
+		if existing.UserID == participant.UserID {
+			return
+		}
+	}
+
+	stored := *participant
+	if stored.Role == "" {
+		stored.Role = model.RoleMember
+	}
+	if stored.JoinedAt.IsZero() {
+		stored.JoinedAt = time.Now()
+	}
+	r.participants[participant.ChatID] = append(r.participants[participant.ChatID], &stored)
+}
+
+// RemoveParticipant удаляет участника из чата
+func (r *InMemoryChatRepository) RemoveParticipant(chatID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.removeParticipantLocked(chatID, userID) {
+		return ErrParticipantNotFound
+	}
+
+	return nil
+}
+
+func (r *InMemoryChatRepository) removeParticipantLocked(chatID, userID string) bool {
+	participants := r.participants[chatID]
+	for i, participant := range participants {
+		if participant.UserID == userID {
+			r.participants[chatID] = append(participants[:i:i], participants[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateParticipantRole меняет роль участника
+func (r *InMemoryChatRepository) UpdateParticipantRole(chatID, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, participant := range r.participants[chatID] {
+		if participant.UserID == userID {
+			participant.Role = role
+			return nil
+		}
+	}
+
+	return ErrParticipantNotFound
+}
+
+// TransferOwnership назначает участника newOwnerID администратором и владельцем чата
+func (r *InMemoryChatRepository) TransferOwnership(chatID, newOwnerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chat, exists := r.chats[chatID]
+	if !exists {
+		return ErrChatNotFound
+	}
+
+	for _, participant := range r.participants[chatID] {
+		if participant.UserID == newOwnerID {
+			participant.Role = model.RoleAdmin
+			chat.CreatedBy = newOwnerID
+			return nil
+		}
+	}
+
+	return ErrParticipantNotFound
+}
+
+// IsParticipant проверяет, является ли пользователь участником чата
+func (r *InMemoryChatRepository) IsParticipant(chatID, userID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, participant := range r.participants[chatID] {
+		if participant.UserID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetParticipant получает копию участника чата
+func (r *InMemoryChatRepository) GetParticipant(chatID, userID string) (*model.Participant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, participant := range r.participants[chatID] {
+		if participant.UserID == userID {
+			p := *participant
+			return &p, nil
+		}
+	}
+
+	return nil, ErrParticipantNotFound
+}
+
+// GetParticipants получает копии участников чата в порядке присоединения
+func (r *InMemoryChatRepository) GetParticipants(chatID string) ([]*model.Participant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.participantsLocked(chatID), nil
+}
+
+func (r *InMemoryChatRepository) participantsLocked(chatID string) []*model.Participant {
+	participants := make([]*model.Participant, 0, len(r.participants[chatID]))
+	for _, participant := range r.participants[chatID] {
+		p := *participant
+		participants = append(participants, &p)
+	}
+
+	return participants
+}
+
+// BanParticipant запрещает пользователю доступ к чату и удаляет его из участников
+func (r *InMemoryChatRepository) BanParticipant(ban *model.Ban) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[ban.ChatID]; !exists {
+		return ErrChatNotFound
+	}
+
+	r.removeParticipantLocked(ban.ChatID, ban.UserID)
+
+	if r.bans[ban.ChatID] == nil {
+		r.bans[ban.ChatID] = make(map[string]*model.Ban)
+	}
+	stored := *ban
+	r.bans[ban.ChatID][ban.UserID] = &stored
+	return nil
+}
+
+// RemoveBan снимает запрет
+func (r *InMemoryChatRepository) RemoveBan(chatID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.bans[chatID][userID]; !exists {
+		return ErrBanNotFound
+	}
+
+	delete(r.bans[chatID], userID)
+	return nil
+}
+
+// IsBanned проверяет, заблокирован ли пользователь в чате
+func (r *InMemoryChatRepository) IsBanned(chatID, userID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.bans[chatID][userID]
+	return exists, nil
+}
+
+// CreateMessage сохраняет сообщение, назначая ему следующий Seq чата
+func (r *InMemoryChatRepository) CreateMessage(message *model.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.createMessageLocked(message)
+}
+
+func (r *InMemoryChatRepository) createMessageLocked(message *model.Message) error {
+	chat, exists := r.chats[message.ChatID]
+	if !exists {
+		return ErrChatNotFound
+	}
+
+	chat.LastSeq++
+	message.Seq = chat.LastSeq
+	if message.UpdatedAt.IsZero() {
+		message.UpdatedAt = message.CreatedAt
+	}
+
+	stored := *message
+	stored.Mentions = nil // Как и в БД, хранятся отдельно от сообщения
+	stored.Poll = nil
+	if message.Metadata != nil {
+		metadata := *message.Metadata
+		stored.Metadata = &metadata
+	}
+	r.messages[message.ChatID] = append(r.messages[message.ChatID], &stored)
+	r.messageByID[message.ID] = &stored
+
+	if message.Poll != nil {
+		message.Poll.MessageID = message.ID
+		message.Poll.ChatID = message.ChatID
+		r.polls[message.ID] = storedPoll(message.Poll)
+	}
+
+	if len(message.Mentions) > 0 {
+		mentioned := slices.Clone(message.Mentions)
+		slices.Sort(mentioned)
+		r.mentions[message.ID] = slices.Compact(mentioned)
+	}
+
+	return nil
+}
+
+// GetOrCreateMessage возвращает сообщение, отправленное с ключом
+// clientMessageID не раньше since, или создает message и запоминает ключ.
+// Второй результат - было ли сообщение создано.
+func (r *InMemoryChatRepository) GetOrCreateMessage(message *model.Message, clientMessageID string, since time.Time) (*model.Message, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := messageKey{message.ChatID, message.UserID, clientMessageID}
+	if stored, exists := r.messageKeys[key]; exists && !stored.CreatedAt.Before(since) {
+		if existing, exists := r.messageByID[stored.MessageID]; exists {
+			message := *existing
+			return &message, false, nil
+		}
+	}
+
+	if err := r.createMessageLocked(message); err != nil {
+		return nil, false, err
+	}
+
+	r.messageKeys[key] = &model.MessageKey{
+		ChatID:          message.ChatID,
+		UserID:          message.UserID,
+		ClientMessageID: clientMessageID,
+		MessageID:       message.ID,
+		CreatedAt:       message.CreatedAt,
+	}
+
+	return message, true, nil
+}
+
+// GetMessageByKey получает копию сообщения, отправленного userID в чат
+// с ключом clientMessageID не раньше since, или возвращает ErrMessageNotFound
+func (r *InMemoryChatRepository) GetMessageByKey(chatID, userID, clientMessageID string, since time.Time) (*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.messageKeys[messageKey{chatID, userID, clientMessageID}]
+	if !exists || stored.CreatedAt.Before(since) {
+		return nil, ErrMessageNotFound
+	}
+
+	existing, exists := r.messageByID[stored.MessageID]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	message := *existing
+	return &message, nil
+}
+
+// DeleteMessageKeys удаляет ключи идемпотентности, созданные раньше before
+func (r *InMemoryChatRepository) DeleteMessageKeys(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, stored := range r.messageKeys {
+		if stored.CreatedAt.Before(before) {
+			delete(r.messageKeys, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// GetMessageByID получает копию сообщения по ID (в том числе удаленного)
+func (r *InMemoryChatRepository) GetMessageByID(id string) (*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.messageByID[id]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	message := *stored
+	return &message, nil
+}
+
+// GetMessages получает копии сообщений чата согласно MessageQuery
+func (r *InMemoryChatRepository) GetMessages(query MessageQuery) ([]*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Сообщения хранятся в порядке Seq, поэтому достаточно отфильтровать диапазон
+	var matched []*model.Message
+	for _, message := range r.messages[query.ChatID] {
+		if query.AfterSeq > 0 && message.Seq <= query.AfterSeq {
+			continue
+		}
+		if query.BeforeSeq > 0 && message.Seq >= query.BeforeSeq {
+			continue
+		}
+		matched = append(matched, message)
+	}
+
+	start, end := 0, len(matched)
+	if query.Backwards() {
+		start = max(0, end-query.Limit)
+	} else {
+		if query.AfterSeq == 0 && query.BeforeSeq == 0 {
+			start = min(query.Offset, end)
+		}
+		end = min(start+query.Limit, end)
+	}
+
+	messages := make([]*model.Message, 0, end-start)
+	for _, message := range matched[start:end] {
+		m := *message
+		messages = append(messages, &m)
+	}
+
+	return messages, nil
+}
+
+// GetReplies получает копии ответов в треде по возрастанию Seq
+func (r *InMemoryChatRepository) GetReplies(rootID string) ([]*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	root, exists := r.messageByID[rootID]
+	if !exists {
+		return []*model.Message{}, nil
+	}
+
+	replies := []*model.Message{}
+	for _, message := range r.messages[root.ChatID] {
+		if message.ReplyTo != nil && *message.ReplyTo == rootID {
+			m := *message
+			replies = append(replies, &m)
+		}
+	}
+
+	return replies, nil
+}
+
+// CountReplies считает ответы для каждого из корневых сообщений
+func (r *InMemoryChatRepository) CountReplies(rootIDs []string) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int, len(rootIDs))
+	for _, rootID := range rootIDs {
+		root, exists := r.messageByID[rootID]
+		if !exists {
+			continue
+		}
+
+		for _, message := range r.messages[root.ChatID] {
+			if message.ReplyTo != nil && *message.ReplyTo == rootID {
+				counts[rootID]++
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// UpdateMessage сохраняет изменения текста и отметок сообщения
+func (r *InMemoryChatRepository) UpdateMessage(message *model.Message, revision *model.MessageRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.messageByID[message.ID]
+	if !exists {
+		return ErrMessageNotFound
+	}
+
+	if revision != nil {
+		rev := *revision
+		r.revisions[message.ID] = append(r.revisions[message.ID], &rev)
+	}
+
+	message.UpdatedAt = time.Now()
+	stored.Content = message.Content
+	stored.Metadata = message.Metadata
+	stored.EditedAt = message.EditedAt
+	stored.DeletedAt = message.DeletedAt
+	stored.UpdatedAt = message.UpdatedAt
+	return nil
+}
+
+// GetMessageRevisions получает копии предыдущих версий сообщения
+func (r *InMemoryChatRepository) GetMessageRevisions(messageID string) ([]*model.MessageRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revisions := make([]*model.MessageRevision, 0, len(r.revisions[messageID]))
+	for _, revision := range r.revisions[messageID] {
+		rev := *revision
+		revisions = append(revisions, &rev)
+	}
+
+	return revisions, nil
+}
+
+// AddReaction сохраняет реакцию; повторная реакция тем же emoji
+// возвращает ErrReactionExists
+func (r *InMemoryChatRepository) AddReaction(reaction *model.Reaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.reactions[reaction.MessageID] {
+		if existing.UserID == reaction.UserID && existing.Emoji == reaction.Emoji {
+			return ErrReactionExists
+		}
+	}
+
+	stored := *reaction
+	r.reactions[reaction.MessageID] = append(r.reactions[reaction.MessageID], &stored)
+	return nil
+}
+
+// RemoveReaction удаляет реакцию пользователя
+func (r *InMemoryChatRepository) RemoveReaction(messageID, userID, emoji string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reactions := r.reactions[messageID]
+	for i, existing := range reactions {
+		if existing.UserID == userID && existing.Emoji == emoji {
+			r.reactions[messageID] = slices.Delete(reactions, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrReactionNotFound
+}
+
+// GetReactionCounts возвращает сводку реакций по сообщениям: для каждого
+// сообщения emoji в порядке первой реакции, ReactedByMe - относительно userID
+func (r *InMemoryChatRepository) GetReactionCounts(messageIDs []string, userID string) (map[string][]*model.ReactionCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string][]*model.ReactionCount)
+	for _, messageID := range messageIDs {
+		byEmoji := map[string]*model.ReactionCount{}
+		for _, reaction := range r.reactions[messageID] {
+			count, exists := byEmoji[reaction.Emoji]
+			if !exists {
+				count = &model.ReactionCount{Emoji: reaction.Emoji}
+				byEmoji[reaction.Emoji] = count
+				counts[messageID] = append(counts[messageID], count)
+			}
+
+			count.Count++
+			if reaction.UserID == userID {
+				count.ReactedByMe = true
+			}
+		}
+	}
+
+	return counts, nil
+}
+
+// GetMentions возвращает упомянутых пользователей для каждого сообщения
+func (r *InMemoryChatRepository) GetMentions(messageIDs []string) (map[string][]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mentions := make(map[string][]string)
+	for _, messageID := range messageIDs {
+		if mentioned, exists := r.mentions[messageID]; exists {
+			mentions[messageID] = slices.Clone(mentioned)
+		}
+	}
+
+	return mentions, nil
+}
+
+// GetUserMentions возвращает сообщения, где упомянут пользователь (см. MentionQuery)
+func (r *InMemoryChatRepository) GetUserMentions(query MentionQuery) ([]*UserMention, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var mentions []*UserMention
+	for chatID, participants := range r.participants {
+		if query.ChatID != "" && chatID != query.ChatID {
+			continue
+		}
+
+		index := slices.IndexFunc(participants, func(p *model.Participant) bool { return p.UserID == query.UserID })
+		if index < 0 {
+			continue
+		}
+		lastReadSeq := participants[index].LastReadSeq
+
+		for _, message := range r.messages[chatID] {
+			if message.IsDeleted() || !slices.Contains(r.mentions[message.ID], query.UserID) {
+				continue
+			}
+
+			unread := message.Seq > lastReadSeq
+			if query.UnreadOnly && !unread {
+				continue
+			}
+
+			m := *message
+			mentions = append(mentions, &UserMention{Message: &m, Unread: unread})
+		}
+	}
+
+	slices.SortFunc(mentions, func(a, b *UserMention) int {
+		if c := b.Message.CreatedAt.Compare(a.Message.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.Message.ID, a.Message.ID)
+	})
+
+	if query.Offset >= len(mentions) {
+		return []*UserMention{}, nil
+	}
+
+	mentions = mentions[query.Offset:]
+	if query.Limit > 0 && len(mentions) > query.Limit {
+		mentions = mentions[:query.Limit]
+	}
+
+	return mentions, nil
+}
+
+// storedPoll возвращает копию сохраняемых полей опроса
+func storedPoll(poll *model.Poll) *model.Poll {
+	return &model.Poll{
+		MessageID:      poll.MessageID,
+		ChatID:         poll.ChatID,
+		Options:        slices.Clone(poll.Options),
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		ClosesAt:       poll.ClosesAt,
+		ClosedAt:       poll.ClosedAt,
+	}
+}
+
+// GetPolls возвращает копии опросов сообщений без итогов
+func (r *InMemoryChatRepository) GetPolls(messageIDs []string) (map[string]*model.Poll, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	polls := make(map[string]*model.Poll)
+	for _, messageID := range messageIDs {
+		if poll, exists := r.polls[messageID]; exists {
+			polls[messageID] = storedPoll(poll)
+		}
+	}
+
+	return polls, nil
+}
+
+// GetPollVotes возвращает копии голосов в опросах сообщений в порядке подачи
+func (r *InMemoryChatRepository) GetPollVotes(messageIDs []string) (map[string][]*model.PollVote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	votes := make(map[string][]*model.PollVote)
+	for _, messageID := range messageIDs {
+		for _, vote := range r.pollVotes[messageID] {
+			v := *vote
+			votes[messageID] = append(votes[messageID], &v)
+		}
+	}
+
+	return votes, nil
+}
+
+// SetPollVotes заменяет голоса пользователя в открытом опросе на votes
+func (r *InMemoryChatRepository) SetPollVotes(messageID, userID string, votes []*model.PollVote, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	poll, exists := r.polls[messageID]
+	if !exists {
+		return ErrPollNotFound
+	}
+
+	if poll.IsClosed(now) {
+		return ErrPollClosed
+	}
+
+	remaining := slices.DeleteFunc(r.pollVotes[messageID], func(vote *model.PollVote) bool {
+		return vote.UserID == userID
+	})
+	for _, vote := range votes {
+		v := *vote
+		remaining = append(remaining, &v)
+	}
+
+	if len(remaining) == 0 {
+		delete(r.pollVotes, messageID)
+	} else {
+		r.pollVotes[messageID] = remaining
+	}
+
+	return nil
+}
+
+// ClosePoll закрывает открытый опрос
+func (r *InMemoryChatRepository) ClosePoll(messageID string, closedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	poll, exists := r.polls[messageID]
+	if !exists {
+		return ErrPollNotFound
+	}
+
+	if poll.ClosedAt != nil {
+		return ErrPollClosed
+	}
+
+	poll.ClosedAt = &closedAt
+	return nil
+}
+
+// GetDuePolls возвращает до limit копий незакрытых опросов с наступившим ClosesAt
+func (r *InMemoryChatRepository) GetDuePolls(now time.Time, limit int) ([]*model.Poll, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []*model.Poll
+	for _, poll := range r.polls {
+		if poll.ClosedAt == nil && poll.ClosesAt != nil && !poll.ClosesAt.After(now) {
+			due = append(due, storedPoll(poll))
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *model.Poll) int {
+		if c := a.ClosesAt.Compare(*b.ClosesAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.MessageID, b.MessageID)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+// CreateScheduledMessage сохраняет отложенное сообщение или возвращает
+// ожидающее сообщение автора в том же чате с тем же ClientMessageID
+func (r *InMemoryChatRepository) CreateScheduledMessage(scheduled *model.ScheduledMessage) (*model.ScheduledMessage, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if scheduled.ClientMessageID != nil {
+		for _, existing := range r.scheduled {
+			if existing.ChatID == scheduled.ChatID && existing.UserID == scheduled.UserID && existing.ClientMessageID != nil &&
+				*existing.ClientMessageID == *scheduled.ClientMessageID {
+				found := *existing
+				return &found, false, nil
+			}
+		}
+	}
+
+	stored := *scheduled
+	r.scheduled[scheduled.ID] = &stored
+	return scheduled, true, nil
+}
+
+// GetScheduledMessage получает копию ожидающего отправки сообщения
+func (r *InMemoryChatRepository) GetScheduledMessage(id string) (*model.ScheduledMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.scheduled[id]
+	if !exists {
+		return nil, ErrScheduledMessageNotFound
+	}
+
+	scheduled := *stored
+	return &scheduled, nil
+}
+
+// GetScheduledMessageByKey получает копию ожидающего сообщения, отложенного
+// userID в чат с ключом clientMessageID
+func (r *InMemoryChatRepository) GetScheduledMessageByKey(chatID, userID, clientMessageID string) (*model.ScheduledMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, stored := range r.scheduled {
+		if stored.ChatID == chatID && stored.UserID == userID && stored.ClientMessageID != nil &&
+			*stored.ClientMessageID == clientMessageID {
+			scheduled := *stored
+			return &scheduled, nil
+		}
+	}
+
+	return nil, ErrScheduledMessageNotFound
+}
+
+// GetScheduledMessages возвращает копии ожидающих сообщений автора в порядке отправки
+func (r *InMemoryChatRepository) GetScheduledMessages(userID, chatID string) ([]*model.ScheduledMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.scheduledLocked(func(scheduled *model.ScheduledMessage) bool {
+		return scheduled.UserID == userID && (chatID == "" || scheduled.ChatID == chatID)
+	}, 0), nil
+}
+
+// GetDueScheduledMessages возвращает до limit копий сообщений с SendAt не позже now
+func (r *InMemoryChatRepository) GetDueScheduledMessages(now time.Time, limit int) ([]*model.ScheduledMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.scheduledLocked(func(scheduled *model.ScheduledMessage) bool {
+		return !scheduled.SendAt.After(now)
+	}, limit), nil
+}
+
+// scheduledLocked отбирает копии отложенных сообщений в порядке
+// send_at, created_at, id; limit 0 - без ограничения
+func (r *InMemoryChatRepository) scheduledLocked(match func(*model.ScheduledMessage) bool, limit int) []*model.ScheduledMessage {
+	matched := []*model.ScheduledMessage{}
+	for _, stored := range r.scheduled {
+		if match(stored) {
+			scheduled := *stored
+			matched = append(matched, &scheduled)
+		}
+	}
+
+	slices.SortFunc(matched, func(a, b *model.ScheduledMessage) int {
+		if c := a.SendAt.Compare(b.SendAt); c != 0 {
+			return c
+		}
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	return matched
+}
+
+// DeleteScheduledMessage отменяет отложенное сообщение
+func (r *InMemoryChatRepository) DeleteScheduledMessage(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.scheduled[id]; !exists {
+		return ErrScheduledMessageNotFound
+	}
+
+	delete(r.scheduled, id)
+	return nil
+}
+
+// DeliverScheduledMessage удаляет отложенное сообщение с ID message.ID
+// и создает message с ключом clientMessageID. Если ключ с since уже занят,
+// отложенное сообщение удаляется как повтор.
+func (r *InMemoryChatRepository) DeliverScheduledMessage(message *model.Message, clientMessageID string, since time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.scheduled[message.ID]; !exists {
+		return false, ErrScheduledMessageNotFound
+	}
+
+	key := messageKey{message.ChatID, message.UserID, clientMessageID}
+	if clientMessageID != "" {
+		if stored, exists := r.messageKeys[key]; exists && !stored.CreatedAt.Before(since) {
+			delete(r.scheduled, message.ID)
+			return false, nil
+		}
+	}
+
+	if err := r.createMessageLocked(message); err != nil {
+		return false, err
+	}
+
+	if clientMessageID != "" {
+		r.messageKeys[key] = &model.MessageKey{
+			ChatID:          message.ChatID,
+			UserID:          message.UserID,
+			ClientMessageID: clientMessageID,
+			MessageID:       message.ID,
+			CreatedAt:       message.CreatedAt,
+		}
+	}
+
+	delete(r.scheduled, message.ID)
+	return true, nil
+}
+
+// GetExpiredMessages возвращает до limit копий неудаленных сообщений
+// с ExpiresAt не позже now, начиная с истекших раньше
+func (r *InMemoryChatRepository) GetExpiredMessages(now time.Time, limit int) ([]*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var expired []*model.Message
+	for _, stored := range r.messageByID {
+		if stored.ExpiresAt != nil && !stored.ExpiresAt.After(now) && !stored.IsDeleted() {
+			message := *stored
+			expired = append(expired, &message)
+		}
+	}
+
+	slices.SortFunc(expired, func(a, b *model.Message) int {
+		if c := a.ExpiresAt.Compare(*b.ExpiresAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	return expired, nil
+}
+
+// PinMessage закрепляет сообщение, если в чате меньше maxPins закреплений
+func (r *InMemoryChatRepository) PinMessage(pin *model.Pin, maxPins int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[pin.ChatID]; !exists {
+		return ErrChatNotFound
+	}
+
+	pins := r.pins[pin.ChatID]
+	if slices.ContainsFunc(pins, func(p *model.Pin) bool { return p.MessageID == pin.MessageID }) {
+		return ErrAlreadyPinned
+	}
+
+	if len(pins) >= maxPins {
+		return ErrPinLimit
+	}
+
+	stored := *pin
+	stored.Message = nil
+	r.pins[pin.ChatID] = append(pins, &stored)
+	return nil
+}
+
+// UnpinMessage открепляет сообщение
+func (r *InMemoryChatRepository) UnpinMessage(chatID, messageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pins := r.pins[chatID]
+	for i, pin := range pins {
+		if pin.MessageID == messageID {
+			r.pins[chatID] = slices.Delete(pins, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrPinNotFound
+}
+
+// GetPins возвращает копии закреплений чата, начиная с последнего закрепленного
+func (r *InMemoryChatRepository) GetPins(chatID string) ([]*model.Pin, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pins := make([]*model.Pin, 0, len(r.pins[chatID]))
+	for i := len(r.pins[chatID]) - 1; i >= 0; i-- {
+		pin := *r.pins[chatID][i]
+		if message, exists := r.messageByID[pin.MessageID]; exists {
+			m := *message
+			pin.Message = &m
+		}
+		pins = append(pins, &pin)
+	}
+
+	return pins, nil
+}
+
+// CreateInvite сохраняет приглашение
+func (r *InMemoryChatRepository) CreateInvite(invite *model.Invite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[invite.ChatID]; !exists {
+		return ErrChatNotFound
+	}
+
+	stored := *invite
+	r.invites[invite.Token] = &stored
+	return nil
+}
+
+// GetInviteByToken получает копию приглашения по токену
+func (r *InMemoryChatRepository) GetInviteByToken(token string) (*model.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.invites[token]
+	if !exists {
+		return nil, ErrInviteNotFound
+	}
+
+	invite := *stored
+	return &invite, nil
+}
+
+// RevokeInvite отзывает приглашение (повторный отзыв не меняет RevokedAt)
+func (r *InMemoryChatRepository) RevokeInvite(id string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invite := range r.invites {
+		if invite.ID != id {
+			continue
+		}
+
+		if invite.RevokedAt == nil {
+			invite.RevokedAt = &revokedAt
+		}
+		return nil
+	}
+
+	return ErrInviteNotFound
+}
+
+// RedeemInvite списывает одно использование приглашения и добавляет участника
+func (r *InMemoryChatRepository) RedeemInvite(invite *model.Invite, participant *model.Participant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.invites[invite.Token]
+	if !exists || stored.ID != invite.ID {
+		return ErrInviteNotFound
+	}
+
+	if stored.IsRevoked() || stored.IsExhausted() {
+		return ErrInviteUnavailable
+	}
+
+	if err := r.joinLocked(participant); err != nil {
+		return err
+	}
+
+	stored.Uses++
+	return nil
+}
+
+// CreateJoinRequest сохраняет заявку на вступление (повторная заявка игнорируется)
+func (r *InMemoryChatRepository) CreateJoinRequest(request *model.JoinRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[request.ChatID]; !exists {
+		return ErrChatNotFound
+	}
+
+	for _, existing := range r.joinRequests[request.ChatID] {
+		if existing.UserID == request.UserID {
+			return nil
+		}
+	}
+
+	stored := *request
+	r.joinRequests[request.ChatID] = append(r.joinRequests[request.ChatID], &stored)
+	return nil
+}
+
+// GetJoinRequests получает копии заявок на вступление в порядке подачи
+func (r *InMemoryChatRepository) GetJoinRequests(chatID string) ([]*model.JoinRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	requests := make([]*model.JoinRequest, 0, len(r.joinRequests[chatID]))
+	for _, request := range r.joinRequests[chatID] {
+		req := *request
+		requests = append(requests, &req)
+	}
+
+	return requests, nil
+}
+
+// DeleteJoinRequest удаляет заявку на вступление
+func (r *InMemoryChatRepository) DeleteJoinRequest(chatID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	requests := r.joinRequests[chatID]
+	for i, request := range requests {
+		if request.UserID == userID {
+			r.joinRequests[chatID] = append(requests[:i:i], requests[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrJoinRequestNotFound
+}
+
+// MarkRead сдвигает отметку прочтения участника до seq (только вперед)
+func (r *InMemoryChatRepository) MarkRead(chatID, userID string, seq int64, readAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, participant := range r.participants[chatID] {
+		if participant.UserID != userID {
+			continue
+		}
+
+		if participant.LastReadSeq < seq {
+			participant.LastReadSeq = seq
+			participant.LastReadAt = &readAt
+		}
+		return nil
+	}
+
+	return ErrParticipantNotFound
+}
+
+// GetUserChats возвращает чаты пользователя с последним сообщением и числом
+// непрочитанных, от недавно активных к давно неактивным
+func (r *InMemoryChatRepository) GetUserChats(userID string) ([]*ChatSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := []*ChatSummary{}
+	for chatID, participants := range r.participants {
+		for _, participant := range participants {
+			if participant.UserID != userID {
+				continue
+			}
+
+			chat := *r.chats[chatID]
+			summary := &ChatSummary{Chat: &chat, LastReadSeq: participant.LastReadSeq}
+
+			messages := r.messages[chatID]
+			if len(messages) > 0 {
+				last := *messages[len(messages)-1]
+				summary.LastMessage = &last
+			}
+
+			for _, message := range messages {
+				if message.Seq > participant.LastReadSeq && message.UserID != userID && !message.IsDeleted() {
+					summary.UnreadCount++
+				}
+			}
+
+			summaries = append(summaries, summary)
+			break
+		}
+	}
+
+	sortByActivity(summaries)
+	return summaries, nil
+}
+
+// GetUserChatIDs возвращает идентификаторы чатов, в которых состоит пользователь
+func (r *InMemoryChatRepository) GetUserChatIDs(userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chatIDs := []string{}
+	for chatID, participants := range r.participants {
+		for _, participant := range participants {
+			if participant.UserID == userID {
+				chatIDs = append(chatIDs, chatID)
+				break
+			}
+		}
+	}
+
+	slices.Sort(chatIDs)
+	return chatIDs, nil
+}
+
+// SearchMessages перебирает сообщения чатов пользователя (см. SearchQuery)
+func (r *InMemoryChatRepository) SearchMessages(query SearchQuery) ([]*SearchHit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return []*SearchHit{}, nil
+	}
+
+	var candidates []*model.Message
+	for chatID, participants := range r.participants {
+		if query.ChatID != "" && chatID != query.ChatID {
+			continue
+		}
+		if !slices.ContainsFunc(participants, func(p *model.Participant) bool { return p.UserID == query.UserID }) {
+			continue
+		}
+
+		for _, message := range r.messages[chatID] {
+			if message.IsDeleted() || message.Type == model.MessageTypeSystem {
+				continue
+			}
+			if query.FromUserID != "" && message.UserID != query.FromUserID {
+				continue
+			}
+			if query.Before != nil && !message.CreatedAt.Before(*query.Before) {
+				continue
+			}
+			if query.After != nil && !message.CreatedAt.After(*query.After) {
+				continue
+			}
+
+			m := *message
+			candidates = append(candidates, &m)
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b *model.Message) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+
+	return paginateHits(scanHits(candidates, terms), query), nil
+}
+
+// CreateAttachment сохраняет запись о вложении
+func (r *InMemoryChatRepository) CreateAttachment(attachment *model.Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.chats[attachment.ChatID]; !exists {
+		return ErrChatNotFound
+	}
+
+	stored := *attachment
+	r.attachments[attachment.ID] = &stored
+	return nil
+}
+
+// GetAttachment получает копию вложения по ID
+func (r *InMemoryChatRepository) GetAttachment(id string) (*model.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.attachments[id]
+	if !exists {
+		return nil, ErrAttachmentNotFound
+	}
+
+	attachment := *stored
+	return &attachment, nil
+}
+
+// ReleaseAttachment удаляет вложение, если на него не ссылается ни одно
+// неудаленное сообщение
+func (r *InMemoryChatRepository) ReleaseAttachment(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, message := range r.messageByID {
+		if !message.IsDeleted() && message.Metadata != nil && message.Metadata.AttachmentID == id {
+			return nil
+		}
+	}
+
+	delete(r.attachments, id)
+	return nil
+}
+
+// storedWebhook возвращает копию webhook'а с собственным фильтром событий
+func storedWebhook(webhook *model.Webhook) *model.Webhook {
+	stored := *webhook
+	stored.Events = slices.Clone(webhook.Events)
+	return &stored
+}
+
+// CreateWebhook сохраняет регистрацию webhook'а
+func (r *InMemoryChatRepository) CreateWebhook(webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks[webhook.ID] = storedWebhook(webhook)
+	return nil
+}
+
+// GetWebhook получает копию webhook'а
+func (r *InMemoryChatRepository) GetWebhook(id string) (*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, exists := r.webhooks[id]
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	return storedWebhook(webhook), nil
+}
+
+// GetWebhooks возвращает копии webhook'ов чата в порядке регистрации
+func (r *InMemoryChatRepository) GetWebhooks(chatID string) ([]*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var webhooks []*model.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.ChatID == chatID {
+			webhooks = append(webhooks, storedWebhook(webhook))
+		}
+	}
+
+	slices.SortFunc(webhooks, func(a, b *model.Webhook) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет webhook вместе с журналом его доставок
+func (r *InMemoryChatRepository) DeleteWebhook(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+
+	delete(r.webhooks, id)
+	return nil
+}
+
+// CreateWebhookDeliveries ставит копии доставок в очередь
+func (r *InMemoryChatRepository) CreateWebhookDeliveries(deliveries []*model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		stored := *delivery
+		r.deliveries[delivery.ID] = &stored
+	}
+
+	return nil
+}
+
+// GetWebhookDelivery получает копию доставки
+func (r *InMemoryChatRepository) GetWebhookDelivery(id string) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, exists := r.deliveries[id]
+	if !exists {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	delivery := *stored
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries возвращает копии доставок webhook'а от новых к старым
+func (r *InMemoryChatRepository) GetWebhookDeliveries(query WebhookDeliveryQuery) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []*model.WebhookDelivery{}
+	for _, stored := range r.deliveries {
+		if stored.WebhookID == query.WebhookID && (query.Status == "" || stored.Status == query.Status) {
+			delivery := *stored
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+
+	slices.SortFunc(deliveries, func(a, b *model.WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+
+	deliveries = deliveries[min(query.Offset, len(deliveries)):]
+	if query.Limit > 0 && len(deliveries) > query.Limit {
+		deliveries = deliveries[:query.Limit]
+	}
+
+	return deliveries, nil
+}
+
+// GetDueWebhookDeliveries возвращает до limit копий ожидающих доставок
+// с NextAttemptAt не позже now, начиная с самых давних
+func (r *InMemoryChatRepository) GetDueWebhookDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []*model.WebhookDelivery
+	for _, stored := range r.deliveries {
+		if stored.Status == model.WebhookDeliveryPending && stored.NextAttemptAt != nil && !stored.NextAttemptAt.After(now) {
+			delivery := *stored
+			due = append(due, &delivery)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *model.WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+// ClaimWebhookDelivery начинает попытку доставки, если ее еще никто не начал
+func (r *InMemoryChatRepository) ClaimWebhookDelivery(id string, attempts int, leaseUntil time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists || delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != attempts {
+		return false, nil
+	}
+
+	delivery.Attempts = attempts + 1
+	delivery.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+// UpdateWebhookDelivery сохраняет состояние доставки и результат последней попытки
+func (r *InMemoryChatRepository) UpdateWebhookDelivery(delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.deliveries[delivery.ID]
+	if !exists {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastAttemptAt = delivery.LastAttemptAt
+	stored.ResponseStatus = delivery.ResponseStatus
+	stored.LastError = delivery.LastError
+	return nil
+}
+
+// storedCommand возвращает копию команды с собственной схемой аргументов
+func storedCommand(command *model.Command) *model.Command {
+	stored := *command
+	stored.Args = make(model.CommandArgs, len(command.Args))
+	for i, arg := range command.Args {
+		a := *arg
+		stored.Args[i] = &a
+	}
+	return &stored
+}
+
+// SaveCommand регистрирует команду бота в чате или обновляет ее описание
+// и аргументы. Если имя занято другим ботом, возвращает ErrCommandTaken.
+func (r *InMemoryChatRepository) SaveCommand(command *model.Command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commands := r.commands[command.ChatID]
+	if commands == nil {
+		commands = make(map[string]*model.Command)
+		r.commands[command.ChatID] = commands
+	}
+
+	if existing, exists := commands[command.Name]; exists {
+		if existing.BotID != command.BotID {
+			return ErrCommandTaken
+		}
+		command.CreatedAt = existing.CreatedAt
+	}
+
+	commands[command.Name] = storedCommand(command)
+	return nil
+}
+
+// GetCommand получает копию команды чата по имени
+func (r *InMemoryChatRepository) GetCommand(chatID, name string) (*model.Command, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	command, exists := r.commands[chatID][name]
+	if !exists {
+		return nil, ErrCommandNotFound
+	}
+
+	return storedCommand(command), nil
+}
+
+// GetCommands возвращает копии команд ботов в чате, упорядоченные по имени
+func (r *InMemoryChatRepository) GetCommands(chatID string) ([]*model.Command, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var commands []*model.Command
+	for _, command := range r.commands[chatID] {
+		commands = append(commands, storedCommand(command))
+	}
+
+	slices.SortFunc(commands, func(a, b *model.Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return commands, nil
+}
+
+// DeleteCommand удаляет команду из чата
+func (r *InMemoryChatRepository) DeleteCommand(chatID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.commands[chatID][name]; !exists {
+		return ErrCommandNotFound
+	}
+
+	delete(r.commands[chatID], name)
+	return nil
+}
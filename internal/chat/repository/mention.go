@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+)
+
+// MentionQuery - параметры выборки упоминаний пользователя. Возвращаются
+// только неудаленные сообщения чатов, в которых UserID состоит сейчас,
+// от новых к старым.
+type MentionQuery struct {
+	UserID     string
+	ChatID     string // Необязательно: только этот чат
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// UserMention - сообщение, в котором упомянут пользователь. Упоминание
+// непрочитано, пока отметка прочтения пользователя в чате не дошла до сообщения.
+type UserMention struct {
+	Message *model.Message
+	Unread  bool
+}
+
+// mentionRows строит записи message_mentions для message.Mentions
+func mentionRows(message *model.Message) []*model.Mention {
+	rows := make([]*model.Mention, 0, len(message.Mentions))
+	for _, userID := range message.Mentions {
+		rows = append(rows, &model.Mention{
+			MessageID: message.ID,
+			UserID:    userID,
+			ChatID:    message.ChatID,
+			CreatedAt: message.CreatedAt,
+		})
+	}
+	return rows
+}
+
+// createMentionsTx сохраняет упоминания сообщения в транзакции createMessageTx
+func createMentionsTx(tx *gorm.DB, message *model.Message) error {
+	if len(message.Mentions) == 0 {
+		return nil
+	}
+	return tx.Create(mentionRows(message)).Error
+}
+
+// GetMentions возвращает упомянутых пользователей для каждого сообщения
+// (в порядке user_id); сообщения без упоминаний в результат не попадают
+func (r *GormChatRepository) GetMentions(messageIDs []string) (map[string][]string, error) {
+	mentions := make(map[string][]string)
+	if len(messageIDs) == 0 {
+		return mentions, nil
+	}
+
+	var rows []*model.Mention
+	if err := r.db.Where("message_id IN ?", messageIDs).Order("message_id, user_id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		mentions[row.MessageID] = append(mentions[row.MessageID], row.UserID)
+	}
+
+	return mentions, nil
+}
+
+// GetUserMentions возвращает сообщения, где упомянут пользователь (см. MentionQuery)
+func (r *GormChatRepository) GetUserMentions(query MentionQuery) ([]*UserMention, error) {
+	var rows []struct {
+		model.Message
+		Unread bool
+	}
+
+	db := r.db.Table("messages").
+		Select("messages.*, messages.seq > cp.last_read_seq AS unread").
+		Joins("JOIN message_mentions mm ON mm.message_id = messages.id AND mm.user_id = ?", query.UserID).
+		Joins("JOIN chat_participants cp ON cp.chat_id = messages.chat_id AND cp.user_id = ?", query.UserID).
+		Where("messages.deleted_at IS NULL")
+
+	if query.ChatID != "" {
+		db = db.Where("messages.chat_id = ?", query.ChatID)
+	}
+	if query.UnreadOnly {
+		db = db.Where("messages.seq > cp.last_read_seq")
+	}
+
+	err := db.Order("messages.created_at DESC, messages.id DESC").
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := make([]*UserMention, 0, len(rows))
+	for _, row := range rows {
+		message := row.Message
+		mentions = append(mentions, &UserMention{Message: &message, Unread: row.Unread})
+	}
+
+	return mentions, nil
+}
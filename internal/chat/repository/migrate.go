@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strings"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+)
+
+// messageTypesConstraint - CHECK на messages.message_type из scripts/init.sql.
+// Postgres называет ограничение столбца <таблица>_<столбец>_check.
+const messageTypesConstraint = "messages_message_type_check"
+
+// messageTypes - допустимые значения messages.message_type
+var messageTypes = []string{
+	model.MessageTypeText,
+	model.MessageTypeImage,
+	model.MessageTypeFile,
+	model.MessageTypePoll,
+	model.MessageTypeAction,
+	model.MessageTypeCommand,
+	model.MessageTypeSystem,
+}
+
+// migratePostgresMessageTypes пересоздает CHECK на messages.message_type:
+// CREATE TABLE IF NOT EXISTS в init.sql не меняет уже созданную таблицу,
+// и без этого новые типы сообщений в ней не сохранялись бы.
+// Повторный запуск безопасен.
+func migratePostgresMessageTypes(db *gorm.DB) error {
+	values := make([]string, len(messageTypes))
+	for i, messageType := range messageTypes {
+		values[i] = "'" + messageType + "'"
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE messages DROP CONSTRAINT IF EXISTS ` + messageTypesConstraint).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`ALTER TABLE messages ADD CONSTRAINT ` + messageTypesConstraint +
+			` CHECK (message_type IN (` + strings.Join(values, ", ") + `))`).Error
+	})
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createPollTx сохраняет опрос сообщения в транзакции createMessageTx
+func createPollTx(tx *gorm.DB, message *model.Message) error {
+	if message.Poll == nil {
+		return nil
+	}
+
+	message.Poll.MessageID = message.ID
+	message.Poll.ChatID = message.ChatID
+	return tx.Create(message.Poll).Error
+}
+
+// GetPolls возвращает опросы сообщений без итогов; сообщения
+// без опроса в результат не попадают
+func (r *GormChatRepository) GetPolls(messageIDs []string) (map[string]*model.Poll, error) {
+	polls := make(map[string]*model.Poll)
+	if len(messageIDs) == 0 {
+		return polls, nil
+	}
+
+	var rows []*model.Poll
+	if err := r.db.Where("message_id IN ?", messageIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, poll := range rows {
+		polls[poll.MessageID] = poll
+	}
+
+	return polls, nil
+}
+
+// GetPollVotes возвращает голоса в опросах сообщений в порядке подачи
+func (r *GormChatRepository) GetPollVotes(messageIDs []string) (map[string][]*model.PollVote, error) {
+	votes := make(map[string][]*model.PollVote)
+	if len(messageIDs) == 0 {
+		return votes, nil
+	}
+
+	var rows []*model.PollVote
+	err := r.db.Where("message_id IN ?", messageIDs).
+		Order("created_at, user_id, option").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vote := range rows {
+		votes[vote.MessageID] = append(votes[vote.MessageID], vote)
+	}
+
+	return votes, nil
+}
+
+// SetPollVotes заменяет голоса пользователя в опросе на votes (пустой
+// список отзывает голос). Строка опроса блокируется до конца транзакции,
+// поэтому голос не пройдет после ClosePoll; если опрос закрыт к моменту
+// now, возвращается ErrPollClosed.
+func (r *GormChatRepository) SetPollVotes(messageID, userID string, votes []*model.PollVote, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var poll model.Poll
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("message_id = ?", messageID).
+			First(&poll).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPollNotFound
+			}
+			return err
+		}
+
+		if poll.IsClosed(now) {
+			return ErrPollClosed
+		}
+
+		if err := tx.Where("message_id = ? AND user_id = ?", messageID, userID).Delete(&model.PollVote{}).Error; err != nil {
+			return err
+		}
+
+		if len(votes) == 0 {
+			return nil
+		}
+
+		return tx.Create(votes).Error
+	})
+}
+
+// ClosePoll закрывает открытый опрос. Если опрос уже закрыт вручную,
+// возвращает ErrPollClosed.
+func (r *GormChatRepository) ClosePoll(messageID string, closedAt time.Time) error {
+	result := r.db.Model(&model.Poll{}).
+		Where("message_id = ? AND closed_at IS NULL", messageID).
+		Update("closed_at", closedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := r.db.Model(&model.Poll{}).Where("message_id = ?", messageID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrPollNotFound
+	}
+
+	return ErrPollClosed
+}
+
+// GetDuePolls возвращает до limit опросов, у которых наступил ClosesAt,
+// но которые еще не закрыты
+func (r *GormChatRepository) GetDuePolls(now time.Time, limit int) ([]*model.Poll, error) {
+	var polls []*model.Poll
+	err := r.db.Where("closes_at <= ? AND closed_at IS NULL", now).
+		Order("closes_at, message_id").
+		Limit(limit).
+		Find(&polls).Error
+	return polls, err
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateScheduledMessage сохраняет отложенное сообщение. Если у автора уже
+// есть ожидающее сообщение в том же чате с тем же ClientMessageID, возвращает его;
+// второй результат - было ли сообщение создано.
+func (r *GormChatRepository) CreateScheduledMessage(scheduled *model.ScheduledMessage) (*model.ScheduledMessage, bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(scheduled)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return scheduled, true, nil
+	}
+
+	var existing model.ScheduledMessage
+	err := r.db.Where("chat_id = ? AND user_id = ? AND client_message_id = ?",
+		scheduled.ChatID, scheduled.UserID, scheduled.ClientMessageID).
+		First(&existing).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &existing, false, nil
+}
+
+// GetScheduledMessage получает ожидающее отправки сообщение по ID
+func (r *GormChatRepository) GetScheduledMessage(id string) (*model.ScheduledMessage, error) {
+	var scheduled model.ScheduledMessage
+	if err := r.db.Where("id = ?", id).First(&scheduled).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledMessageNotFound
+		}
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// GetScheduledMessageByKey получает ожидающее сообщение, отложенное userID
+// в чат с ключом clientMessageID
+func (r *GormChatRepository) GetScheduledMessageByKey(chatID, userID, clientMessageID string) (*model.ScheduledMessage, error) {
+	var scheduled model.ScheduledMessage
+	err := r.db.Where("chat_id = ? AND user_id = ? AND client_message_id = ?", chatID, userID, clientMessageID).
+		First(&scheduled).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledMessageNotFound
+		}
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// GetScheduledMessages возвращает ожидающие сообщения автора в порядке
+// отправки; chatID (необязательно) ограничивает выборку одним чатом
+func (r *GormChatRepository) GetScheduledMessages(userID, chatID string) ([]*model.ScheduledMessage, error) {
+	db := r.db.Where("user_id = ?", userID)
+	if chatID != "" {
+		db = db.Where("chat_id = ?", chatID)
+	}
+
+	var scheduled []*model.ScheduledMessage
+	err := db.Order("send_at, created_at, id").Find(&scheduled).Error
+	return scheduled, err
+}
+
+// GetDueScheduledMessages возвращает до limit сообщений с SendAt не позже now
+// в порядке отправки
+func (r *GormChatRepository) GetDueScheduledMessages(now time.Time, limit int) ([]*model.ScheduledMessage, error) {
+	var scheduled []*model.ScheduledMessage
+	err := r.db.Where("send_at <= ?", now).
+		Order("send_at, created_at, id").
+		Limit(limit).
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+// DeleteScheduledMessage отменяет отложенное сообщение
+func (r *GormChatRepository) DeleteScheduledMessage(id string) error {
+	result := r.db.Where("id = ?", id).Delete(&model.ScheduledMessage{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrScheduledMessageNotFound
+	}
+
+	return nil
+}
+
+// DeliverScheduledMessage в одной транзакции удаляет отложенное сообщение
+// с ID message.ID и создает message, как CreateMessage, запоминая его ключ
+// clientMessageID (если он задан), как GetOrCreateMessage. Если запись уже
+// удалена (сообщение отменено или отправлено другим экземпляром сервиса),
+// возвращает ErrScheduledMessageNotFound и ничего не создает. Если ключ
+// с since уже занят отправленным сообщением, отложенное удаляется как
+// повтор, и первый результат - false.
+func (r *GormChatRepository) DeliverScheduledMessage(message *model.Message, clientMessageID string, since time.Time) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", message.ID).Delete(&model.ScheduledMessage{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrScheduledMessageNotFound
+		}
+
+		if err := createMessageTx(tx, message); err != nil {
+			return err
+		}
+
+		if clientMessageID == "" {
+			return nil
+		}
+
+		_, err := saveMessageKeyTx(tx, message, clientMessageID, since)
+		return err
+	})
+
+	if errors.Is(err, errDuplicateMessage) {
+		err := r.DeleteScheduledMessage(message.ID)
+		if errors.Is(err, ErrScheduledMessageNotFound) {
+			err = nil
+		}
+		return false, err
+	}
+
+	return err == nil, err
+}
+
+// GetExpiredMessages возвращает до limit неудаленных сообщений с ExpiresAt
+// не позже now, начиная с истекших раньше
+func (r *GormChatRepository) GetExpiredMessages(now time.Time, limit int) ([]*model.Message, error) {
+	var messages []*model.Message
+	err := r.db.Where("expires_at <= ? AND deleted_at IS NULL", now).
+		Order("expires_at, id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
@@ -0,0 +1,394 @@
+package repository
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+)
+
+// Маркеры совпадений в сниппетах результатов поиска
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+
+	snippetEllipsis = "…"
+	snippetWords    = 16 // Примерная длина сниппета в словах
+
+	// Маркеры, которыми совпадения обрамляет СУБД: текст сниппета
+	// экранируется после нее, и только затем они заменяются на HTML.
+	// Такие символы из области частного использования в сообщениях
+	// не ждем; если они там есть, сниппет может выделить лишнее, но
+	// остается экранированным.
+	dbHighlightStart = "\uE000"
+	dbHighlightEnd   = "\uE001"
+)
+
+// SearchQuery - параметры полнотекстового поиска по сообщениям.
+// Ищутся только сообщения чатов, в которых состоит UserID; удаленные
+// и системные сообщения не ищутся. Все слова Text должны встречаться
+// в сообщении. Результат упорядочен от новых сообщений к старым.
+type SearchQuery struct {
+	UserID     string
+	Text       string
+	ChatID     string     // Необязательно: искать только в этом чате
+	FromUserID string     // Необязательно: только сообщения этого автора
+	Before     *time.Time // Необязательно: созданные раньше
+	After      *time.Time // Необязательно: созданные позже
+	Limit      int
+	Offset     int
+}
+
+// SearchHit - найденное сообщение со сниппетом, где совпавшие слова
+// обрамлены HighlightStart/HighlightEnd. Остальной текст сниппета
+// экранирован для HTML, поэтому его можно выводить как есть.
+type SearchHit struct {
+	Message *model.Message
+	Snippet string
+}
+
+// SearchTerms разбивает текст на слова для поиска: последовательности букв
+// и цифр в нижнем регистре, без повторов
+func SearchTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, span := range wordSpans(text) {
+		term := strings.ToLower(text[span.start:span.end])
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+type wordSpan struct {
+	start, end int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordSpans возвращает границы слов текста в байтах
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			spans = append(spans, wordSpan{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{start, len(text)})
+	}
+	return spans
+}
+
+// scanMatch проверяет, что content содержит все terms, и строит сниппет.
+// Используется in-memory репозиторием и GORM без полнотекстового индекса.
+func scanMatch(content string, terms []string) (string, bool) {
+	if len(terms) == 0 {
+		return "", false
+	}
+
+	spans := wordSpans(content)
+	matched := make([]bool, len(spans))
+	found := map[string]bool{}
+	first := -1
+
+	for i, span := range spans {
+		word := strings.ToLower(content[span.start:span.end])
+		for _, term := range terms {
+			if word == term {
+				matched[i] = true
+				found[term] = true
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+
+	if len(found) < len(terms) {
+		return "", false
+	}
+
+	// Окно слов вокруг первого совпадения
+	lo := max(0, first-snippetWords/4)
+	hi := min(len(spans), lo+snippetWords)
+
+	var snippet strings.Builder
+	pos := 0
+	if lo > 0 {
+		snippet.WriteString(snippetEllipsis)
+		pos = spans[lo].start
+	}
+
+	for i := lo; i < hi; i++ {
+		snippet.WriteString(html.EscapeString(content[pos:spans[i].start]))
+		word := content[spans[i].start:spans[i].end]
+		if matched[i] {
+			snippet.WriteString(HighlightStart + word + HighlightEnd)
+		} else {
+			snippet.WriteString(word)
+		}
+		pos = spans[i].end
+	}
+
+	if hi < len(spans) {
+		snippet.WriteString(snippetEllipsis)
+	} else {
+		snippet.WriteString(html.EscapeString(content[pos:]))
+	}
+
+	return snippet.String(), true
+}
+
+// highlightSnippet экранирует сниппет, построенный СУБД, и заменяет
+// в нем dbHighlightStart/dbHighlightEnd на HighlightStart/HighlightEnd
+func highlightSnippet(snippet string) string {
+	var highlighted strings.Builder
+	open := false
+	for snippet != "" {
+		i := strings.IndexAny(snippet, dbHighlightStart+dbHighlightEnd)
+		if i < 0 {
+			highlighted.WriteString(html.EscapeString(snippet))
+			break
+		}
+
+		highlighted.WriteString(html.EscapeString(snippet[:i]))
+		marker := snippet[i : i+len(dbHighlightStart)]
+		snippet = snippet[i+len(marker):]
+
+		// Лишние маркеры - это символы из самого сообщения
+		switch {
+		case marker == dbHighlightStart && !open:
+			highlighted.WriteString(HighlightStart)
+			open = true
+		case marker == dbHighlightEnd && open:
+			highlighted.WriteString(HighlightEnd)
+			open = false
+		}
+	}
+
+	if open {
+		highlighted.WriteString(HighlightEnd)
+	}
+	return highlighted.String()
+}
+
+// Migrate создает таблицы чатов и индекс полнотекстового поиска по сообщениям:
+// tsvector с GIN индексом на Postgres и FTS5 на SQLite. На Postgres также
+// обновляется список допустимых типов сообщений.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(Models()...); err != nil {
+		return err
+	}
+
+	// Прежний ключ отложенных сообщений не включал chat_id
+	if db.Migrator().HasIndex(&model.ScheduledMessage{}, "idx_scheduled_messages_key") {
+		if err := db.Migrator().DropIndex(&model.ScheduledMessage{}, "idx_scheduled_messages_key"); err != nil {
+			return err
+		}
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		if err := migratePostgresMessageTypes(db); err != nil {
+			return err
+		}
+		return db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_content_fts
+			ON messages USING GIN (to_tsvector('simple', content))`).Error
+	case "sqlite":
+		return migrateSQLiteSearch(db)
+	default:
+		return nil
+	}
+}
+
+// migrateSQLiteSearch создает FTS5 таблицу, которую триггеры держат
+// в актуальном состоянии. Если SQLite собран без FTS5 (mattn/go-sqlite3
+// без тега sqlite_fts5), поиск работает перебором.
+func migrateSQLiteSearch(db *gorm.DB) error {
+	if db.Migrator().HasTable(sqliteSearchTable) {
+		return nil
+	}
+
+	err := db.Exec(`CREATE VIRTUAL TABLE ` + sqliteSearchTable + ` USING fts5(content, message_id UNINDEXED)`).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return nil
+		}
+		return err
+	}
+
+	statements := []string{
+		`CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(content, message_id) VALUES (new.content, new.id);
+		END`,
+		`CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+			UPDATE messages_fts SET content = new.content WHERE message_id = new.id;
+		END`,
+		`CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+			DELETE FROM messages_fts WHERE message_id = old.id;
+		END`,
+		`INSERT INTO messages_fts(content, message_id) SELECT content, id FROM messages`,
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const sqliteSearchTable = "messages_fts"
+
+// searchRow - строка результата поиска в GORM
+type searchRow struct {
+	model.Message
+	Snippet string
+}
+
+// SearchMessages ищет сообщения в чатах пользователя (см. SearchQuery)
+func (r *GormChatRepository) SearchMessages(query SearchQuery) ([]*SearchHit, error) {
+	terms := SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return []*SearchHit{}, nil
+	}
+
+	switch {
+	case r.db.Dialector.Name() == "postgres":
+		return r.searchPostgres(query, terms)
+	case r.db.Dialector.Name() == "sqlite" && r.db.Migrator().HasTable(sqliteSearchTable):
+		return r.searchSQLite(query, terms)
+	default:
+		return r.searchScan(query, terms)
+	}
+}
+
+// searchScope ограничивает выборку чатами пользователя и фильтрами запроса
+func searchScope(query SearchQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Joins("JOIN chat_participants cp ON cp.chat_id = messages.chat_id AND cp.user_id = ?", query.UserID).
+			Where("messages.deleted_at IS NULL AND messages.message_type <> ?", model.MessageTypeSystem)
+
+		if query.ChatID != "" {
+			db = db.Where("messages.chat_id = ?", query.ChatID)
+		}
+		if query.FromUserID != "" {
+			db = db.Where("messages.user_id = ?", query.FromUserID)
+		}
+		if query.Before != nil {
+			db = db.Where("messages.created_at < ?", *query.Before)
+		}
+		if query.After != nil {
+			db = db.Where("messages.created_at > ?", *query.After)
+		}
+
+		return db.Order("messages.created_at DESC, messages.id DESC")
+	}
+}
+
+func (r *GormChatRepository) searchPostgres(query SearchQuery, terms []string) ([]*SearchHit, error) {
+	tsQuery := strings.Join(terms, " ")
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=%d, MinWords=%d", dbHighlightStart, dbHighlightEnd, snippetWords, snippetWords/2)
+
+	var rows []*searchRow
+	err := r.db.Table("messages").
+		Select("messages.*, ts_headline('simple', messages.content, plainto_tsquery('simple', ?), ?) AS snippet", tsQuery, headline).
+		Scopes(searchScope(query)).
+		Where("to_tsvector('simple', messages.content) @@ plainto_tsquery('simple', ?)", tsQuery).
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return searchHits(rows), nil
+}
+
+func (r *GormChatRepository) searchSQLite(query SearchQuery, terms []string) ([]*SearchHit, error) {
+	// Слова состоят только из букв и цифр, поэтому кавычки их полностью экранируют
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+
+	var rows []*searchRow
+	err := r.db.Table(sqliteSearchTable).
+		Select("messages.*, snippet(messages_fts, 0, ?, ?, ?, ?) AS snippet", dbHighlightStart, dbHighlightEnd, snippetEllipsis, snippetWords).
+		Joins("JOIN messages ON messages.id = messages_fts.message_id").
+		Scopes(searchScope(query)).
+		Where("messages_fts MATCH ?", strings.Join(quoted, " ")).
+		Limit(query.Limit).
+		Offset(query.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return searchHits(rows), nil
+}
+
+// searchScan перебирает сообщения, подходящие под фильтры. Используется
+// только для SQLite без FTS5 (тесты), поэтому индекс не нужен.
+func (r *GormChatRepository) searchScan(query SearchQuery, terms []string) ([]*SearchHit, error) {
+	var messages []*model.Message
+	err := r.db.Table("messages").
+		Select("messages.*").
+		Scopes(searchScope(query)).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return paginateHits(scanHits(messages, terms), query), nil
+}
+
+func searchHits(rows []*searchRow) []*SearchHit {
+	hits := make([]*SearchHit, 0, len(rows))
+	for _, row := range rows {
+		message := row.Message
+		hits = append(hits, &SearchHit{Message: &message, Snippet: highlightSnippet(row.Snippet)})
+	}
+	return hits
+}
+
+// scanHits отбирает сообщения, содержащие все слова, сохраняя порядок
+func scanHits(messages []*model.Message, terms []string) []*SearchHit {
+	hits := []*SearchHit{}
+	for _, message := range messages {
+		if snippet, ok := scanMatch(message.Content, terms); ok {
+			hits = append(hits, &SearchHit{Message: message, Snippet: snippet})
+		}
+	}
+	return hits
+}
+
+func paginateHits(hits []*SearchHit, query SearchQuery) []*SearchHit {
+	if query.Offset >= len(hits) {
+		return []*SearchHit{}
+	}
+
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits
+}
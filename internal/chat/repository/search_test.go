@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+func TestSearchTerms(t *testing.T) {
+	terms := SearchTerms("  Deploy, deploy! v2 -- Привет ")
+	if !slices.Equal(terms, []string{"deploy", "v2", "привет"}) {
+		t.Errorf("Unexpected terms: %v", terms)
+	}
+
+	if terms := SearchTerms(" ?! "); len(terms) != 0 {
+		t.Errorf("Expected no terms, got %v", terms)
+	}
+}
+
+func TestScanMatch(t *testing.T) {
+	snippet, ok := scanMatch("The Deploy failed, rolling back", []string{"deploy", "failed"})
+	if !ok || snippet != "The <mark>Deploy</mark> <mark>failed</mark>, rolling back" {
+		t.Errorf("Unexpected snippet %q (match=%v)", snippet, ok)
+	}
+
+	if _, ok := scanMatch("deployment failed", []string{"deploy"}); ok {
+		t.Error("Only whole words must match")
+	}
+
+	snippet, _ = scanMatch(`<b>deploy</b> & "rollback"`, []string{"deploy"})
+	if snippet != "&lt;b&gt;<mark>deploy</mark>&lt;/b&gt; &amp; &#34;rollback&#34;" {
+		t.Errorf("Expected the snippet text to be escaped, got %q", snippet)
+	}
+
+	long := strings.Repeat("word ", 40) + "needle " + strings.Repeat("word ", 40)
+	snippet, _ = scanMatch(long, []string{"needle"})
+	if !strings.HasPrefix(snippet, snippetEllipsis) || !strings.HasSuffix(snippet, snippetEllipsis) ||
+		!strings.Contains(snippet, "<mark>needle</mark>") {
+		t.Errorf("Unexpected snippet of a long message: %q", snippet)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	snippet := highlightSnippet("a<b " + dbHighlightStart + "deploy" + dbHighlightEnd + " & " + dbHighlightEnd + "x")
+	if snippet != "a&lt;b <mark>deploy</mark> &amp; x" {
+		t.Errorf("Unexpected snippet %q", snippet)
+	}
+}
+
+func TestChatRepository_SearchEscapesSnippets(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+
+		message := &model.Message{
+			ID:        "d1000000-0000-0000-0000-000000000001",
+			ChatID:    chat.ID,
+			UserID:    "alice",
+			Type:      model.MessageTypeText,
+			Content:   "<script>alert(1)</script> deploy <b>now</b>",
+			CreatedAt: time.Now(),
+		}
+		if err := repo.CreateMessage(message); err != nil {
+			t.Fatalf("CreateMessage failed: %v", err)
+		}
+
+		hits, err := repo.SearchMessages(SearchQuery{UserID: "alice", Text: "deploy", Limit: 10})
+		if err != nil || len(hits) != 1 {
+			t.Fatalf("SearchMessages failed: %d hits, err=%v", len(hits), err)
+		}
+
+		want := "&lt;script&gt;alert(1)&lt;/script&gt; <mark>deploy</mark> &lt;b&gt;now&lt;/b&gt;"
+		if hits[0].Snippet != want {
+			t.Errorf("Expected %q, got %q", want, hits[0].Snippet)
+		}
+	})
+}
+
+func TestChatRepository_SearchMessages(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo ChatRepository) {
+		chat := createTestChat(t, repo, "alice")
+		other := &model.Chat{
+			ID:           "22222222-2222-2222-2222-222222222222",
+			Name:         "other",
+			CreatedBy:    "bob",
+			Participants: []*model.Participant{{UserID: "bob", Role: model.RoleAdmin}},
+		}
+		if err := repo.CreateChat(other); err != nil {
+			t.Fatalf("CreateChat failed: %v", err)
+		}
+
+		base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		deletedAt := base
+		messages := []*model.Message{
+			{ChatID: chat.ID, UserID: "alice", Content: "Deploy is scheduled for Friday"},
+			{ChatID: chat.ID, UserID: "owner", Content: "The deploy failed, rolling back"},
+			{ChatID: chat.ID, UserID: "owner", Content: "lunch?"},
+			{ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeSystem, Content: "alice joined the deploy"},
+			{ChatID: chat.ID, UserID: "owner", Content: "deploy", DeletedAt: &deletedAt},
+			{ChatID: other.ID, UserID: "bob", Content: "deploy tonight"},
+		}
+		for i, message := range messages {
+			message.ID = fmt.Sprintf("d0000000-0000-0000-0000-%012d", i+1)
+			message.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			if message.Type == "" {
+				message.Type = model.MessageTypeText
+			}
+			if err := repo.CreateMessage(message); err != nil {
+				t.Fatalf("CreateMessage failed: %v", err)
+			}
+		}
+
+		// Правка текста должна попасть в индекс
+		edited := messages[2]
+		edited.Content = "lunch after the deploy"
+		revision := &model.MessageRevision{ID: "e0000000-0000-0000-0000-000000000001", MessageID: edited.ID, Content: "lunch?", EditedBy: "owner", CreatedAt: base}
+		if err := repo.UpdateMessage(edited, revision); err != nil {
+			t.Fatalf("UpdateMessage failed: %v", err)
+		}
+
+		after := base.Add(30 * time.Second)
+		tests := []struct {
+			name  string
+			query SearchQuery
+			want  []string // Индексы сообщений + 1 в ожидаемом порядке
+		}{
+			{"newest first", SearchQuery{UserID: "alice", Text: "deploy"}, []string{"3", "2", "1"}},
+			{"all words", SearchQuery{UserID: "alice", Text: "DEPLOY failed"}, []string{"2"}},
+			{"from user", SearchQuery{UserID: "alice", Text: "deploy", FromUserID: "alice"}, []string{"1"}},
+			{"after", SearchQuery{UserID: "alice", Text: "deploy", After: &after}, []string{"3", "2"}},
+			{"before", SearchQuery{UserID: "alice", Text: "deploy", Before: &after}, []string{"1"}},
+			{"chat filter", SearchQuery{UserID: "alice", Text: "deploy", ChatID: other.ID}, nil},
+			{"page", SearchQuery{UserID: "alice", Text: "deploy", Limit: 1, Offset: 1}, []string{"2"}},
+			{"other user", SearchQuery{UserID: "bob", Text: "deploy"}, []string{"6"}},
+			{"no match", SearchQuery{UserID: "alice", Text: "friday deploy failed"}, nil},
+			{"punctuation only", SearchQuery{UserID: "alice", Text: "?!"}, nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.query.Limit == 0 {
+					tt.query.Limit = 10
+				}
+
+				hits, err := repo.SearchMessages(tt.query)
+				if err != nil {
+					t.Fatalf("SearchMessages failed: %v", err)
+				}
+
+				var got []string
+				for _, hit := range hits {
+					got = append(got, strings.TrimLeft(hit.Message.ID[len("d0000000-0000-0000-0000-"):], "0"))
+					if !strings.Contains(strings.ToLower(hit.Snippet), HighlightStart+"deploy"+HighlightEnd) {
+						t.Errorf("Snippet %q does not highlight the match", hit.Snippet)
+					}
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"golang-chat/internal/chat/model"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryQuery - параметры выборки журнала доставок webhook'а.
+// Доставки возвращаются от новых к старым.
+type WebhookDeliveryQuery struct {
+	WebhookID string
+	Status    string // Необязательно: только доставки в этом состоянии
+	Limit     int
+	Offset    int
+}
+
+// CreateWebhook сохраняет регистрацию webhook'а
+func (r *GormChatRepository) CreateWebhook(webhook *model.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// GetWebhook получает webhook по ID
+func (r *GormChatRepository) GetWebhook(id string) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := r.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetWebhooks возвращает webhook'и чата в порядке регистрации
+func (r *GormChatRepository) GetWebhooks(chatID string) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	err := r.db.Where("chat_id = ?", chatID).Order("created_at, id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// DeleteWebhook удаляет webhook вместе с журналом его доставок
+func (r *GormChatRepository) DeleteWebhook(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", id).Delete(&model.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+
+		return nil
+	})
+}
+
+// CreateWebhookDeliveries ставит доставки в очередь
+func (r *GormChatRepository) CreateWebhookDeliveries(deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return r.db.Create(deliveries).Error
+}
+
+// GetWebhookDelivery получает доставку по ID
+func (r *GormChatRepository) GetWebhookDelivery(id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.db.Where("id = ?", id).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries возвращает журнал доставок webhook'а (см. WebhookDeliveryQuery)
+func (r *GormChatRepository) GetWebhookDeliveries(query WebhookDeliveryQuery) ([]*model.WebhookDelivery, error) {
+	db := r.db.Where("webhook_id = ?", query.WebhookID)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	var deliveries []*model.WebhookDelivery
+	err := db.Order("created_at DESC, id DESC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDueWebhookDeliveries возвращает до limit ожидающих доставок
+// с NextAttemptAt не позже now, начиная с самых давних
+func (r *GormChatRepository) GetDueWebhookDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at, created_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimWebhookDelivery начинает попытку доставки: увеличивает Attempts
+// и откладывает NextAttemptAt до leaseUntil, если доставка все еще ожидает
+// и счетчик попыток равен attempts. Возвращает false, если попытку уже
+// начал другой экземпляр сервиса. Если попытка не завершится
+// UpdateWebhookDelivery (например, сервис упадет), доставка будет
+// повторена после leaseUntil.
+func (r *GormChatRepository) ClaimWebhookDelivery(id string, attempts int, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, model.WebhookDeliveryPending, attempts).
+		Updates(map[string]interface{}{
+			"attempts":        attempts + 1,
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateWebhookDelivery сохраняет состояние доставки и результат последней попытки
+func (r *GormChatRepository) UpdateWebhookDelivery(delivery *model.WebhookDelivery) error {
+	result := r.db.Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "last_error").
+		Updates(delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif" // Регистрация декодеров для определения размеров изображений
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+	"golang-chat/internal/chat/storage"
+
+	"github.com/google/uuid"
+)
+
+// sniffLength - сколько первых байт нужно http.DetectContentType
+const sniffLength = 512
+
+var (
+	ErrAttachmentTooLarge    = errors.New("attachment exceeds the maximum size")
+	ErrEmptyAttachment       = errors.New("attachment is empty")
+	ErrInvalidFileName       = errors.New("invalid file name")
+	ErrAttachmentOtherChat   = errors.New("attachment belongs to another chat")
+	ErrAttachmentNotAnImage  = errors.New("attachment is not a recognized image")
+	ErrAttachmentSizeChanged = errors.New("uploaded size does not match the declared size")
+)
+
+// AttachmentService принимает и отдает вложения чатов. Содержимое хранится
+// в BlobStore по SHA-256, записи о вложениях - в репозитории чатов.
+type AttachmentService struct {
+	chatRepository repository.ChatRepository
+	blobStore      storage.BlobStore
+	maxSize        int64
+	now            func() time.Time
+}
+
+// NewAttachmentService создает сервис вложений с ограничением размера maxSize байт
+func NewAttachmentService(chatRepository repository.ChatRepository, blobStore storage.BlobStore, maxSize int64) *AttachmentService {
+	return &AttachmentService{
+		chatRepository: chatRepository,
+		blobStore:      blobStore,
+		maxSize:        maxSize,
+		now:            time.Now,
+	}
+}
+
+// UploadAttachmentInput - параметры загрузки вложения
+type UploadAttachmentInput struct {
+	ChatID   string
+	UserID   string
+	FileName string
+	Size     int64 // Заявленный размер; 0 - неизвестен
+}
+
+// UploadAttachment читает содержимое до конца, определяет MIME-тип по первым
+// байтам и сохраняет блоб, если такого содержимого еще нет в хранилище.
+// Загружать могут только участники чата.
+func (s *AttachmentService) UploadAttachment(ctx context.Context, input UploadAttachmentInput, content io.Reader) (*model.Attachment, error) {
+	if !validFileName(input.FileName) {
+		return nil, ErrInvalidFileName
+	}
+
+	if input.Size > s.maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	chat, err := s.requireParticipant(input.ChatID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	// Буферизуем во временный файл: хеш известен только после чтения всего
+	// содержимого, а в хранилище блоб кладется уже под этим ключом
+	tmp, err := os.CreateTemp("", "chat-attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	head := &headBuffer{limit: sniffLength}
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash, head), io.LimitReader(content, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case size > s.maxSize:
+		return nil, ErrAttachmentTooLarge
+	case size == 0:
+		return nil, ErrEmptyAttachment
+	case input.Size > 0 && size != input.Size:
+		return nil, ErrAttachmentSizeChanged
+	}
+
+	attachment := &model.Attachment{
+		ID:         uuid.New().String(),
+		ChatID:     input.ChatID,
+		UploaderID: input.UserID,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		Size:       size,
+		MimeType:   http.DetectContentType(head.data),
+		FileName:   strings.TrimSpace(input.FileName),
+		CreatedAt:  s.now(),
+	}
+
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if config, _, err := image.DecodeConfig(tmp); err == nil {
+			attachment.Width, attachment.Height = config.Width, config.Height
+		}
+	}
+
+	exists, err := s.blobStore.Exists(ctx, attachment.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := s.blobStore.Put(ctx, attachment.SHA256, tmp, size); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.chatRepository.CreateAttachment(attachment); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// OpenAttachment возвращает вложение и его содержимое, если пользователь
+// состоит в чате, куда вложение было загружено. Вложение, все сообщения
+// с которым удалены или истекли, не найдется. Вызывающий обязан закрыть reader.
+func (s *AttachmentService) OpenAttachment(ctx context.Context, attachmentID, userID string) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.chatRepository.GetAttachment(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := s.requireParticipant(attachment.ChatID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.blobStore.Get(ctx, attachment.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+func (s *AttachmentService) requireParticipant(chatID, userID string) (*model.Chat, error) {
+	chat, err := s.chatRepository.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	isParticipant, err := s.chatRepository.IsParticipant(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isParticipant {
+		return nil, ErrNotParticipant
+	}
+
+	return chat, nil
+}
+
+// headBuffer запоминает первые limit байт потока для определения MIME-типа
+type headBuffer struct {
+	data  []byte
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// attachmentMetadata заполняет метаданные сообщения из загруженного вложения.
+// Клиентским значениям имени, размера и типа не доверяем.
+func attachmentMetadata(attachment *model.Attachment, messageType string) (*model.Metadata, error) {
+	metadata := &model.Metadata{
+		AttachmentID: attachment.ID,
+		FileName:     attachment.FileName,
+		FileSize:     attachment.Size,
+		MimeType:     attachment.MimeType,
+	}
+
+	if messageType == model.MessageTypeImage {
+		if !attachment.IsImage() {
+			return nil, ErrAttachmentNotAnImage
+		}
+		metadata.Width, metadata.Height = attachment.Width, attachment.Height
+	}
+
+	return metadata, nil
+}
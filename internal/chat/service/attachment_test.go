@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+	"golang-chat/internal/chat/storage"
+)
+
+// countingStore считает записи в хранилище, чтобы проверить дедупликацию
+type countingStore struct {
+	storage.BlobStore
+	puts int
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	s.puts++
+	return s.BlobStore.Put(ctx, key, r, size)
+}
+
+func newTestAttachmentService(t *testing.T, maxSize int64) (*ChatService, *AttachmentService, *countingStore) {
+	t.Helper()
+
+	blobStore, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore failed: %v", err)
+	}
+
+	chatRepository := repository.NewInMemoryChatRepository()
+	store := &countingStore{BlobStore: blobStore}
+	return NewChatService(chatRepository, newTestUserDirectory(), ChatServiceConfig{}), NewAttachmentService(chatRepository, store, maxSize), store
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAttachmentService_Upload(t *testing.T) {
+	chats, attachments, store := newTestAttachmentService(t, 1<<20)
+	ctx := context.Background()
+
+	chat, _ := chats.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	content := testPNG(t, 64, 32)
+
+	attachment, err := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "owner", FileName: "cat.png", Size: int64(len(content)),
+	}, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadAttachment failed: %v", err)
+	}
+
+	if attachment.MimeType != "image/png" || attachment.Width != 64 || attachment.Height != 32 {
+		t.Errorf("Unexpected attachment: %+v", attachment)
+	}
+	if attachment.Size != int64(len(content)) || len(attachment.SHA256) != 64 {
+		t.Errorf("Unexpected size or hash: %+v", attachment)
+	}
+
+	// То же содержимое - новая запись, но тот же блоб
+	again, err := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "alice", FileName: "copy.png",
+	}, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadAttachment failed: %v", err)
+	}
+	if again.ID == attachment.ID || again.SHA256 != attachment.SHA256 {
+		t.Errorf("Expected a new attachment with the same hash, got %+v", again)
+	}
+	if store.puts != 1 {
+		t.Errorf("Expected 1 blob write, got %d", store.puts)
+	}
+
+	found, reader, err := attachments.OpenAttachment(ctx, attachment.ID, "alice")
+	if err != nil {
+		t.Fatalf("OpenAttachment failed: %v", err)
+	}
+	defer reader.Close()
+
+	downloaded, _ := io.ReadAll(reader)
+	if found.ID != attachment.ID || !bytes.Equal(downloaded, content) {
+		t.Error("Downloaded content does not match upload")
+	}
+}
+
+func TestAttachmentService_UploadErrors(t *testing.T) {
+	chats, attachments, _ := newTestAttachmentService(t, 16)
+	ctx := context.Background()
+
+	chat, _ := chats.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+
+	tests := []struct {
+		name    string
+		input   UploadAttachmentInput
+		content string
+		wantErr error
+	}{
+		{"too large", UploadAttachmentInput{ChatID: chat.ID, UserID: "owner", FileName: "a.txt"}, strings.Repeat("x", 17), ErrAttachmentTooLarge},
+		{"declared too large", UploadAttachmentInput{ChatID: chat.ID, UserID: "owner", FileName: "a.txt", Size: 1 << 20}, "x", ErrAttachmentTooLarge},
+		{"size mismatch", UploadAttachmentInput{ChatID: chat.ID, UserID: "owner", FileName: "a.txt", Size: 5}, "x", ErrAttachmentSizeChanged},
+		{"empty", UploadAttachmentInput{ChatID: chat.ID, UserID: "owner", FileName: "a.txt"}, "", ErrEmptyAttachment},
+		{"bad file name", UploadAttachmentInput{ChatID: chat.ID, UserID: "owner", FileName: "../a.txt"}, "x", ErrInvalidFileName},
+		{"not participant", UploadAttachmentInput{ChatID: chat.ID, UserID: "mallory", FileName: "a.txt"}, "x", ErrNotParticipant},
+		{"unknown chat", UploadAttachmentInput{ChatID: "missing", UserID: "owner", FileName: "a.txt"}, "x", repository.ErrChatNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := attachments.UploadAttachment(ctx, tt.input, strings.NewReader(tt.content))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAttachmentService_OpenNotParticipant(t *testing.T) {
+	chats, attachments, _ := newTestAttachmentService(t, 1<<20)
+	ctx := context.Background()
+
+	chat, _ := chats.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	attachment, err := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "owner", FileName: "a.txt",
+	}, strings.NewReader("secret"))
+	if err != nil {
+		t.Fatalf("UploadAttachment failed: %v", err)
+	}
+
+	if _, _, err := attachments.OpenAttachment(ctx, attachment.ID, "mallory"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+	if _, _, err := attachments.OpenAttachment(ctx, "missing", "owner"); !errors.Is(err, repository.ErrAttachmentNotFound) {
+		t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+	}
+}
+
+func TestChatService_SendAttachment(t *testing.T) {
+	chats, attachments, _ := newTestAttachmentService(t, 1<<20)
+	ctx := context.Background()
+
+	chat, _ := chats.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	other, _ := chats.CreateChat(CreateChatInput{Name: "other", CreatedBy: "owner"})
+
+	picture, _ := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "owner", FileName: "cat.png",
+	}, bytes.NewReader(testPNG(t, 10, 20)))
+	text, _ := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "owner", FileName: "notes.txt",
+	}, strings.NewReader("hello"))
+
+	// Клиентские значения перезаписываются данными вложения
+	message, err := chats.SendMessage(context.Background(), SendMessageInput{
+		ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeImage,
+		Metadata: &model.Metadata{AttachmentID: picture.ID, FileName: "fake.png", FileSize: 1},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	metadata := message.Metadata
+	if metadata.FileName != "cat.png" || metadata.FileSize != picture.Size || metadata.MimeType != "image/png" ||
+		metadata.Width != 10 || metadata.Height != 20 || metadata.AttachmentID != picture.ID {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+
+	tests := []struct {
+		name        string
+		chatID      string
+		messageType string
+		attachment  string
+		wantErr     error
+	}{
+		{"file", chat.ID, model.MessageTypeFile, text.ID, nil},
+		{"text as image", chat.ID, model.MessageTypeImage, text.ID, ErrAttachmentNotAnImage},
+		{"other chat", other.ID, model.MessageTypeImage, picture.ID, ErrAttachmentOtherChat},
+		{"unknown attachment", chat.ID, model.MessageTypeFile, "missing", repository.ErrAttachmentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := chats.SendMessage(context.Background(), SendMessageInput{
+				ChatID: tt.chatID, UserID: "owner", Type: tt.messageType,
+				Metadata: &model.Metadata{AttachmentID: tt.attachment},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAttachmentService_OpenDeletedAttachment(t *testing.T) {
+	chats, attachments, _ := newTestAttachmentService(t, 1<<20)
+	ctx := context.Background()
+
+	chat, _ := chats.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	attachment, _ := attachments.UploadAttachment(ctx, UploadAttachmentInput{
+		ChatID: chat.ID, UserID: "owner", FileName: "report.txt",
+	}, strings.NewReader("quarterly numbers"))
+
+	send := func() *model.Message {
+		message, err := chats.SendMessage(ctx, SendMessageInput{
+			ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeFile,
+			Metadata: &model.Metadata{AttachmentID: attachment.ID},
+		})
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		return message
+	}
+	first, second := send(), send()
+
+	// Вложение доступно, пока на него ссылается хотя бы одно сообщение
+	if _, err := chats.DeleteMessage(first.ID, "owner"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+	_, reader, err := attachments.OpenAttachment(ctx, attachment.ID, "alice")
+	if err != nil {
+		t.Fatalf("Expected the attachment of a live message to open, got %v", err)
+	}
+	reader.Close()
+
+	if _, err := chats.DeleteMessage(second.ID, "owner"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+	if _, _, err := attachments.OpenAttachment(ctx, attachment.ID, "alice"); !errors.Is(err, repository.ErrAttachmentNotFound) {
+		t.Errorf("Expected ErrAttachmentNotFound after the message was deleted, got %v", err)
+	}
+
+	messages, _, _ := chats.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	for _, message := range messages {
+		if message.Metadata != nil {
+			t.Errorf("Expected deleted messages to lose their metadata, got %+v", message.Metadata)
+		}
+	}
+}
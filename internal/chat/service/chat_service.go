@@ -1,119 +1,942 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"sync"
+	"net"
+	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
 
 	"github.com/google/uuid"
 )
 
+const (
+	defaultMessagesLimit   = 50
+	defaultMaxParticipants = 100 // Совпадает с DEFAULT в scripts/init.sql
+	maxChatNameLength      = 255 // chats.name VARCHAR(255)
+
+	defaultMaxPinnedMessages   = 50
+	defaultMessageKeyRetention = 24 * time.Hour
+	maxClientMessageIDLength   = 64               // message_keys.client_message_id VARCHAR(64)
+	cleanupInterval            = 10 * time.Minute // Период удаления устаревших ключей и лимитов в Run
+	maxSlowModeSeconds         = 6 * 60 * 60
+)
+
+// Лимиты отправки сообщений по умолчанию
+var (
+	defaultUserMessageLimit = RateLimit{Rate: 5, Burst: 20}
+	defaultChatMessageLimit = RateLimit{Rate: 30, Burst: 60}
+)
+
+var (
+	ErrNotParticipant   = errors.New("user is not a participant of this chat")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrMessageDeleted   = errors.New("message is deleted")
+	ErrEmptyContent     = errors.New("message content is empty")
+	ErrReplyToOtherChat = errors.New("reply_to message belongs to another chat")
+	ErrInvalidLimit     = errors.New("max_participants must not be negative")
+	ErrInvalidChatName  = errors.New("chat name must be 1-255 characters")
+	ErrAnnouncementOnly = errors.New("only admins can post in an announcement channel")
+
+	ErrInvalidClientMessageID = errors.New("client_message_id must be at most 64 characters")
+	ErrInvalidSlowMode        = errors.New("slow mode interval must be 0-21600 seconds")
+)
+
+// ChatServiceConfig - настройки ChatService; нулевые значения заменяются значениями по умолчанию
+type ChatServiceConfig struct {
+	MaxPinnedMessages int // Закрепленных сообщений в одном чате
+
+	// Сколько помнить client_message_id отправленных сообщений: повтор
+	// SendMessage с тем же ключом в этом окне не создает дубликат
+	MessageKeyRetention time.Duration
+
+	// Лимиты SendMessage: на пользователя по всем чатам и на чат
+	// по всем участникам. Нулевое значение - лимит по умолчанию.
+	UserMessageLimit RateLimit
+	ChatMessageLimit RateLimit
+
+	// Доставка webhook'ов: попыток до переноса в список недоставленных,
+	// задержка перед второй попыткой (каждая следующая вдвое дольше)
+	// и таймаут одного запроса
+	WebhookMaxAttempts int
+	WebhookRetryBase   time.Duration
+	WebhookTimeout     time.Duration
+}
+
+func (c ChatServiceConfig) withDefaults() ChatServiceConfig {
+	if c.MaxPinnedMessages <= 0 {
+		c.MaxPinnedMessages = defaultMaxPinnedMessages
+	}
+	if c.MessageKeyRetention <= 0 {
+		c.MessageKeyRetention = defaultMessageKeyRetention
+	}
+	c.UserMessageLimit = c.UserMessageLimit.withDefault(defaultUserMessageLimit)
+	c.ChatMessageLimit = c.ChatMessageLimit.withDefault(defaultChatMessageLimit)
+	if c.WebhookMaxAttempts <= 0 {
+		c.WebhookMaxAttempts = defaultWebhookMaxAttempts
+	}
+	if c.WebhookRetryBase <= 0 {
+		c.WebhookRetryBase = defaultWebhookRetryBase
+	}
+	if c.WebhookTimeout <= 0 {
+		c.WebhookTimeout = defaultWebhookTimeout
+	}
+	return c
+}
+
 type ChatService struct {
-	chats    map[string]*model.Chat
-	messages map[string]*model.Message
-	mu       sync.RWMutex
+	chatRepository        repository.ChatRepository
+	users                 UserDirectory
+	config                ChatServiceConfig
+	hub                   *hub
+	presence              *presenceTracker
+	limiter               *rateLimiter
+	webhooks              *webhookCache
+	webhookClient         *http.Client
+	webhookAddressAllowed func(net.IP) bool // Куда можно доставлять webhook'и; в тестах разрешается loopback
+	now                   func() time.Time  // Часы сервиса; в тестах подменяются
 }
 
-func NewChatService() *ChatService {
-	return &ChatService{
-		chats:    make(map[string]*model.Chat),
-		messages: make(map[string]*model.Message),
+func NewChatService(chatRepository repository.ChatRepository, users UserDirectory, config ChatServiceConfig) *ChatService {
+	s := &ChatService{
+		chatRepository:        chatRepository,
+		users:                 users,
+		config:                config.withDefaults(),
+		hub:                   newHub(defaultSubscriberBuffer),
+		presence:              newPresenceTracker(time.Now),
+		limiter:               newRateLimiter(time.Now),
+		webhooks:              newWebhookCache(time.Now),
+		now:                   time.Now,
+		webhookAddressAllowed: publicAddress,
 	}
+	s.webhookClient = newWebhookClient(func(ip net.IP) bool {
+		return s.webhookAddressAllowed(ip)
+	})
+
+	s.hub.onClose = func(sub *Subscription) {
+		s.presence.disconnect(sub.UserID)
+	}
+
+	return s
+}
+
+// CreateChatInput - параметры создания чата
+type CreateChatInput struct {
+	Name            string
+	CreatedBy       string
+	Participants    []string // Кроме создателя, который добавляется всегда
+	IsPrivate       bool
+	IsAnnouncement  bool
+	MaxParticipants int // 0 - defaultMaxParticipants
 }
 
-func (s *ChatService) CreateChat(name, createdBy string, participants []string) (*model.Chat, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *ChatService) CreateChat(input CreateChatInput) (*model.Chat, error) {
+	if input.MaxParticipants < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	maxParticipants := input.MaxParticipants
+	if maxParticipants == 0 {
+		maxParticipants = defaultMaxParticipants
+	}
 
+	now := s.now()
 	chat := &model.Chat{
-		ID:           uuid.New().String(),
-		Name:         name,
-		CreatedBy:    createdBy,
-		CreatedAt:    time.Now(),
-		Participants: append([]string{createdBy}, participants...),
+		ID:              uuid.New().String(),
+		Name:            input.Name,
+		CreatedBy:       input.CreatedBy,
+		CreatedAt:       now,
+		IsPrivate:       input.IsPrivate,
+		IsAnnouncement:  input.IsAnnouncement,
+		MaxParticipants: maxParticipants,
+		Participants: []*model.Participant{
+			{UserID: input.CreatedBy, Role: model.RoleAdmin, JoinedAt: now},
+		},
 	}
 
-	s.chats[chat.ID] = chat
-	return chat, nil
+	seen := map[string]bool{input.CreatedBy: true}
+	for _, userID := range input.Participants {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		chat.Participants = append(chat.Participants, &model.Participant{
+			UserID:   userID,
+			Role:     model.RoleMember,
+			JoinedAt: now,
+		})
+	}
+
+	if len(chat.Participants) > chat.MaxParticipants {
+		return nil, repository.ErrChatFull
+	}
+
+	if err := s.chatRepository.CreateChat(chat); err != nil {
+		return nil, err
+	}
+
+	return s.chatRepository.GetChatByID(chat.ID)
 }
 
+// ConnectChat добавляет пользователя в открытый чат. Для закрытого чата
+// вместо этого создается заявка на вступление и возвращается
+// ErrJoinRequestPending: войти можно после ApproveJoinRequest или по приглашению.
 func (s *ChatService) ConnectChat(chatID, userID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	chat, err := s.chatRepository.GetChatByID(chatID)
+	if err != nil {
+		return err
+	}
+
+	isParticipant, err := s.chatRepository.IsParticipant(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isParticipant {
+		return nil // Уже участник
+	}
+
+	if chat.IsDirect() {
+		return ErrDirectChat
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	isBanned, err := s.chatRepository.IsBanned(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isBanned {
+		return ErrBanned
+	}
+
+	if chat.IsPrivate {
+		return s.requestToJoin(chatID, userID)
+	}
+
+	participant := &model.Participant{
+		ChatID:   chatID,
+		UserID:   userID,
+		Role:     model.RoleMember,
+		JoinedAt: s.now(),
+	}
+
+	if err := s.chatRepository.AddParticipant(participant); err != nil {
+		return err
+	}
+
+	return s.participantJoined(chatID, userID, userID)
+}
+
+// AddParticipant добавляет в чат пользователя или бота без заявки
+// и приглашения. Доступно администраторам чата; забаненного пользователя
+// добавить нельзя, повторное добавление участника ничего не делает.
+func (s *ChatService) AddParticipant(ctx context.Context, chatID, actorID, userID string) error {
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	if findParticipant(chat, userID) != nil {
+		return nil
+	}
+
+	if _, err := s.users.Username(ctx, userID); err != nil {
+		return err
+	}
+
+	isBanned, err := s.chatRepository.IsBanned(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isBanned {
+		return ErrBanned
+	}
+
+	participant := &model.Participant{
+		ChatID:   chatID,
+		UserID:   userID,
+		Role:     model.RoleMember,
+		JoinedAt: s.now(),
+	}
+
+	if err := s.chatRepository.AddParticipant(participant); err != nil {
+		return err
+	}
+
+	return s.participantJoined(chatID, actorID, userID)
+}
+
+// participantJoined оповещает чат о новом участнике. actorID - кто его
+// добавил (совпадает с userID, если пользователь вступил сам).
+func (s *ChatService) participantJoined(chatID, actorID, userID string) error {
+	s.publish(model.EventParticipantJoined, chatID, userID, nil)
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventJoined, TargetUserID: userID})
+}
+
+// RenameChat меняет название чата. Доступно администраторам чата.
+func (s *ChatService) RenameChat(chatID, actorID, name string) (*model.Chat, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxChatNameLength {
+		return nil, ErrInvalidChatName
+	}
 
-	chat, exists := s.chats[chatID]
-	if !exists {
-		return errors.New("chat not found")
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return nil, err
 	}
 
-	// Проверяем, является ли пользователь участником чата
-	for _, participant := range chat.Participants {
-		if participant == userID {
-			return nil // Уже участник
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if chat.Name == name {
+		return chat, nil
+	}
+
+	oldName := chat.Name
+	chat.Name = name
+	if err := s.chatRepository.UpdateChat(chat, "Name"); err != nil {
+		return nil, err
+	}
+
+	metadata := &model.Metadata{Event: model.SystemEventRenamed, OldName: oldName, NewName: name}
+	if err := s.postSystemMessage(chatID, actorID, metadata); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// SendMessageInput - параметры отправки сообщения
+type SendMessageInput struct {
+	ChatID   string
+	UserID   string
+	Type     string // По умолчанию text; system клиентам недоступен
+	Content  string // Для image/file - необязательная подпись
+	Metadata *model.Metadata
+	ReplyTo  string // ID сообщения этого же чата, на которое отвечаем (необязательно)
+
+	// Необязательный ключ идемпотентности, выбранный клиентом. Повтор с тем же
+	// ключом в пределах ChatServiceConfig.MessageKeyRetention возвращает
+	// исходное сообщение, даже если остальные поля отличаются.
+	ClientMessageID string
+
+	// Необязательное время жизни: по его истечении сообщение удаляется
+	// для всех участников. Отсчитывается от момента отправки.
+	ExpiresAfter time.Duration
+
+	// Опрос для сообщения типа poll: Options, MultipleChoice, Anonymous
+	// и необязательный ClosesAt; вопрос опроса - Content
+	Poll *model.Poll
+}
+
+// SendMessage отправляет сообщение в чат. Текст, начинающийся с "/",
+// выполняется как slash-команда (см. runCommand).
+func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (*model.Message, error) {
+	// Повтор проверяется до лимитов отправки: он не должен расходовать их
+	// и упираться в медленный режим
+	if input.ClientMessageID != "" {
+		since := s.now().Add(-s.config.MessageKeyRetention)
+		stored, err := s.chatRepository.GetMessageByKey(input.ChatID, input.UserID, input.ClientMessageID, since)
+		if err == nil {
+			return s.repeatedMessage(stored, input.UserID)
+		}
+		if !errors.Is(err, repository.ErrMessageNotFound) {
+			return nil, err
 		}
 	}
 
-	// Добавляем пользователя в участники
-	chat.Participants = append(chat.Participants, userID)
-	return nil
+	if line := parseCommandLine(&input); line != nil {
+		return s.runCommand(ctx, input, line)
+	}
+
+	chat, message, err := s.prepareMessage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeMessage(ctx, chat, message, input)
+}
+
+// storeMessage сохраняет подготовленное prepareMessage сообщение с учетом
+// ключа идемпотентности и рассылает его
+func (s *ChatService) storeMessage(ctx context.Context, chat *model.Chat, message *model.Message, input SendMessageInput) (*model.Message, error) {
+	message.Mentions = s.resolveMentions(ctx, chat, message.UserID, message.Content)
+
+	if input.ClientMessageID == "" {
+		if err := s.chatRepository.CreateMessage(message); err != nil {
+			return nil, err
+		}
+	} else {
+		since := message.CreatedAt.Add(-s.config.MessageKeyRetention)
+		stored, created, err := s.chatRepository.GetOrCreateMessage(message, input.ClientMessageID, since)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			// Параллельный повтор успел сохранить сообщение первым
+			return s.repeatedMessage(stored, input.UserID)
+		}
+	}
+
+	if err := s.messageCreated(message); err != nil {
+		return nil, err
+	}
+
+	s.stopTyping(input.ChatID, input.UserID)
+	s.touch(input.UserID)
+	return message, nil
+}
+
+// repeatedMessage возвращает автору уже разосланное сообщение при повторе
+// SendMessage с тем же client_message_id
+func (s *ChatService) repeatedMessage(stored *model.Message, userID string) (*model.Message, error) {
+	if err := s.fillMentions([]*model.Message{stored}); err != nil {
+		return nil, err
+	}
+	if err := s.fillPolls(userID, []*model.Message{stored}); err != nil {
+		return nil, err
+	}
+	return stored, nil
 }
 
-func (s *ChatService) SendMessage(chatID, userID, content string) (*model.Message, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// prepareMessage проверяет input и право автора писать в чат, списывает
+// лимиты отправки и возвращает чат и еще не сохраненное сообщение
+func (s *ChatService) prepareMessage(input SendMessageInput) (*model.Chat, *model.Message, error) {
+	if input.Type == "" {
+		input.Type = model.MessageTypeText
+	}
+
+	if utf8.RuneCountInString(input.ClientMessageID) > maxClientMessageIDLength {
+		return nil, nil, ErrInvalidClientMessageID
+	}
 
-	chat, exists := s.chats[chatID]
-	if !exists {
-		return nil, errors.New("chat not found")
+	if input.ExpiresAfter < 0 || input.ExpiresAfter > maxExpiresAfter {
+		return nil, nil, ErrInvalidExpiresAfter
 	}
 
-	// Проверяем, является ли пользователь участником чата
-	isParticipant := false
-	for _, participant := range chat.Participants {
-		if participant == userID {
-			isParticipant = true
-			break
+	if input.Type != model.MessageTypeText && input.Metadata != nil && input.Metadata.AttachmentID != "" {
+		attachment, err := s.chatRepository.GetAttachment(input.Metadata.AttachmentID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if attachment.ChatID != input.ChatID {
+			return nil, nil, ErrAttachmentOtherChat
+		}
+
+		if input.Metadata, err = attachmentMetadata(attachment, input.Type); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	if !isParticipant {
-		return nil, errors.New("user is not a participant of this chat")
+	if err := validateMessage(input.Type, input.Content, input.Metadata); err != nil {
+		return nil, nil, err
+	}
+
+	if err := validatePoll(input.Type, input.Poll, s.now()); err != nil {
+		return nil, nil, err
+	}
+
+	chat, err := s.requireParticipant(input.ChatID, input.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, nil, err
+	}
+
+	if chat.IsAnnouncement && participantRank(chat, input.UserID) < rankAdmin {
+		return nil, nil, ErrAnnouncementOnly
+	}
+
+	if err := s.limiter.allow(s.messageRateChecks(chat, input.UserID)...); err != nil {
+		return nil, nil, err
 	}
 
 	message := &model.Message{
 		ID:        uuid.New().String(),
-		ChatID:    chatID,
-		UserID:    userID,
-		Content:   content,
-		CreatedAt: time.Now(),
+		ChatID:    input.ChatID,
+		UserID:    input.UserID,
+		Type:      input.Type,
+		Content:   input.Content,
+		CreatedAt: s.now(),
+	}
+
+	if input.ExpiresAfter > 0 {
+		expiresAt := message.CreatedAt.Add(input.ExpiresAfter)
+		message.ExpiresAt = &expiresAt
+	}
+
+	if input.Type == model.MessageTypeImage || input.Type == model.MessageTypeFile {
+		metadata := *input.Metadata
+		message.Metadata = &metadata
+	}
+
+	if input.Poll != nil {
+		message.Poll = newPoll(input.Poll)
+	}
+
+	if input.ReplyTo != "" {
+		rootID, err := s.threadRoot(input.ChatID, input.ReplyTo)
+		if err != nil {
+			return nil, nil, err
+		}
+		message.ReplyTo = &rootID
+	}
+
+	return chat, message, nil
+}
+
+// messageCreated отмечает новое сообщение прочитанным автором
+// и рассылает его подписчикам
+func (s *ChatService) messageCreated(message *model.Message) error {
+	// Свое сообщение автор уже "прочитал"
+	if err := s.chatRepository.MarkRead(message.ChatID, message.UserID, message.Seq, message.CreatedAt); err != nil {
+		return err
+	}
+
+	s.publish(model.EventMessageCreated, message.ChatID, message.UserID, message)
+	if len(message.Mentions) > 0 {
+		s.publish(model.EventMessageMentioned, message.ChatID, message.UserID, message)
+	}
+	return nil
+}
+
+// messageRateChecks возвращает лимиты, которые проходит сообщение userID
+// в чат: общий лимит пользователя, лимит чата и медленный режим
+func (s *ChatService) messageRateChecks(chat *model.Chat, userID string) []rateCheck {
+	checks := []rateCheck{
+		{scope: RateLimitUser, key: "user:" + userID, limit: s.config.UserMessageLimit},
+		{scope: RateLimitChat, key: "chat:" + chat.ID, limit: s.config.ChatMessageLimit},
+	}
+
+	if chat.SlowModeSeconds > 0 && participantRank(chat, userID) < rankModerator {
+		checks = append(checks, rateCheck{
+			scope: RateLimitSlowMode,
+			key:   "slow:" + chat.ID + ":" + userID,
+			limit: RateLimit{Rate: 1 / float64(chat.SlowModeSeconds), Burst: 1},
+		})
+	}
+
+	return checks
+}
+
+// SetSlowMode задает интервал медленного режима в секундах (0 - выключить).
+// Доступно администраторам чата; модераторы и выше от режима освобождены.
+func (s *ChatService) SetSlowMode(chatID, actorID string, seconds int) (*model.Chat, error) {
+	if seconds < 0 || seconds > maxSlowModeSeconds {
+		return nil, ErrInvalidSlowMode
+	}
+
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if chat.SlowModeSeconds == seconds {
+		return chat, nil
+	}
+
+	chat.SlowModeSeconds = seconds
+	if err := s.chatRepository.UpdateChat(chat, "SlowModeSeconds"); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// threadRoot возвращает ID корня треда для ответа на сообщение replyToID.
+// Ответ на ответ попадает в тот же тред, поэтому треды остаются одноуровневыми.
+func (s *ChatService) threadRoot(chatID, replyToID string) (string, error) {
+	target, err := s.chatRepository.GetMessageByID(replyToID)
+	if err != nil {
+		return "", err
+	}
+
+	if target.ChatID != chatID {
+		return "", ErrReplyToOtherChat
+	}
+
+	if target.ReplyTo != nil {
+		return *target.ReplyTo, nil
+	}
+
+	return target.ID, nil
+}
+
+// GetMessages возвращает страницу сообщений в хронологическом порядке и признак
+// того, что в направлении прокрутки есть еще сообщения
+func (s *ChatService) GetMessages(userID string, query repository.MessageQuery) ([]*model.Message, bool, error) {
+	if _, err := s.requireParticipant(query.ChatID, userID); err != nil {
+		return nil, false, err
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultMessagesLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	// Запрашиваем на одно сообщение больше, чтобы узнать, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+
+	messages, err := s.chatRepository.GetMessages(query)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		if query.Backwards() {
+			messages = messages[1:] // лишнее сообщение - самое старое
+		} else {
+			messages = messages[:limit]
+		}
+	}
+
+	if err := s.fillReplyCounts(messages); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.fillReadCounts(query.ChatID, messages); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.fillReactions(userID, messages); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.fillMentions(messages); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.fillPolls(userID, messages); err != nil {
+		return nil, false, err
+	}
+
+	return messages, hasMore, nil
+}
+
+// GetThread возвращает корневое сообщение треда и ответы на него по порядку.
+// messageID может указывать как на корень, так и на любой ответ в треде.
+func (s *ChatService) GetThread(messageID, userID string) (*model.Message, []*model.Message, error) {
+	root, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if root.ReplyTo != nil {
+		if root, err = s.chatRepository.GetMessageByID(*root.ReplyTo); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if _, err := s.requireParticipant(root.ChatID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	replies, err := s.chatRepository.GetReplies(root.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root.ReplyCount = len(replies)
+
+	thread := append([]*model.Message{root}, replies...)
+	if err := s.fillReactions(userID, thread); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.fillMentions(thread); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.fillPolls(userID, thread); err != nil {
+		return nil, nil, err
+	}
+
+	return root, replies, nil
+}
+
+// fillReplyCounts заполняет ReplyCount у корневых сообщений
+func (s *ChatService) fillReplyCounts(messages []*model.Message) error {
+	var rootIDs []string
+	for _, message := range messages {
+		if message.ReplyTo == nil {
+			rootIDs = append(rootIDs, message.ID)
+		}
+	}
+
+	counts, err := s.chatRepository.CountReplies(rootIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		message.ReplyCount = counts[message.ID]
+	}
+
+	return nil
+}
+
+// EditMessage заменяет текст сообщения, сохраняя предыдущую версию в истории.
+// Редактировать может автор сообщения или администратор чата.
+func (s *ChatService) EditMessage(messageID, userID, content string) (*model.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyContent
+	}
+
+	message, err := s.messageForChange(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Type == model.MessageTypePoll {
+		return nil, ErrPollEdit
+	}
+
+	if message.Type == model.MessageTypeCommand {
+		return nil, ErrCommandEdit
+	}
+
+	if message.Content == content {
+		return message, nil
+	}
+
+	now := s.now()
+	revision := &model.MessageRevision{
+		ID:        uuid.New().String(),
+		MessageID: message.ID,
+		Content:   message.Content,
+		EditedBy:  userID,
+		CreatedAt: now,
+	}
+
+	message.Content = content
+	message.EditedAt = &now
+
+	if err := s.chatRepository.UpdateMessage(message, revision); err != nil {
+		return nil, err
+	}
+
+	s.publish(model.EventMessageEdited, message.ChatID, userID, message)
+	return message, nil
+}
+
+// DeleteMessage превращает сообщение в "надгробие": текст удаляется,
+// но само сообщение и его Seq остаются в истории чата.
+// Удалять может автор сообщения или администратор чата.
+func (s *ChatService) DeleteMessage(messageID, userID string) (*model.Message, error) {
+	message, err := s.messageForChange(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tombstone(message, userID); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// tombstone удаляет текст сообщения от имени actorID и снимает его с закрепления
+func (s *ChatService) tombstone(message *model.Message, actorID string) error {
+	var attachmentID string
+	if message.Metadata != nil {
+		attachmentID = message.Metadata.AttachmentID
+	}
+
+	now := s.now()
+	message.Content = ""
+	message.Metadata = nil // Имя, размер и ссылка на вложение тоже удаляются
+	message.DeletedAt = &now
+
+	if err := s.chatRepository.UpdateMessage(message, nil); err != nil {
+		return err
+	}
+
+	// Вложение, на которое больше не ссылаются сообщения, перестает отдаваться
+	if attachmentID != "" {
+		if err := s.chatRepository.ReleaseAttachment(attachmentID); err != nil {
+			return err
+		}
+	}
+
+	// Удаленное сообщение не должно занимать место среди закрепленных
+	err := s.chatRepository.UnpinMessage(message.ChatID, message.ID)
+	switch {
+	case err == nil:
+		s.publish(model.EventMessageUnpinned, message.ChatID, actorID, message)
+	case !errors.Is(err, repository.ErrPinNotFound):
+		return err
+	}
+
+	s.publish(model.EventMessageDeleted, message.ChatID, actorID, message)
+	return nil
+}
+
+// GetMessageHistory возвращает предыдущие версии сообщения, от старых к новым
+func (s *ChatService) GetMessageHistory(messageID, userID string) ([]*model.MessageRevision, error) {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireParticipant(message.ChatID, userID); err != nil {
+		return nil, err
+	}
+
+	if message.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+
+	return s.chatRepository.GetMessageRevisions(messageID)
+}
+
+// messageForChange возвращает сообщение, если пользователь может его изменить
+func (s *ChatService) messageForChange(messageID, userID string) (*model.Message, error) {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	participant, err := s.chatRepository.GetParticipant(message.ChatID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrParticipantNotFound) {
+			return nil, ErrNotParticipant
+		}
+		return nil, err
+	}
+
+	if message.UserID != userID && participant.Role != model.RoleAdmin {
+		return nil, ErrPermissionDenied
+	}
+
+	chat, err := s.chatRepository.GetChatByID(message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if message.Type == model.MessageTypeSystem {
+		return nil, ErrSystemMessageChange
+	}
+
+	if message.IsDeleted() {
+		return nil, ErrMessageDeleted
 	}
 
-	s.messages[message.ID] = message
 	return message, nil
 }
 
-func (s *ChatService) GetMessages(chatID string, limit, offset int) ([]*model.Message, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// SubscribeChat подписывает участника чата на события в реальном времени.
+// Вызывающий обязан закрыть подписку через Close.
+func (s *ChatService) SubscribeChat(chatID, userID string) (*Subscription, error) {
+	if _, err := s.requireParticipant(chatID, userID); err != nil {
+		return nil, err
+	}
 
-	var messages []*model.Message
-	for _, message := range s.messages {
-		if message.ChatID == chatID {
-			messages = append(messages, message)
+	// Оповещаем до подписки, чтобы собственный статус не попал в новый поток.
+	// Подключение учитывается сразу, а отключение - при закрытии подписки.
+	if presence, changed := s.presence.connect(userID); changed {
+		if err := s.announcePresence(presence); err != nil {
+			s.presence.disconnect(userID)
+			return nil, err
 		}
 	}
 
-	// Простая пагинация (в реальном приложении должна быть в базе данных)
-	if offset >= len(messages) {
-		return []*model.Message{}, nil
+	return s.hub.subscribe(chatID, userID), nil
+}
+
+// requireParticipant возвращает чат, если пользователь является его участником
+func (s *ChatService) requireParticipant(chatID, userID string) (*model.Chat, error) {
+	chat, err := s.chatRepository.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	isParticipant, err := s.chatRepository.IsParticipant(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isParticipant {
+		return nil, ErrNotParticipant
+	}
+
+	return chat, nil
+}
+
+// postSystemMessage публикует в чат системное сообщение о событии metadata
+// от имени инициатора действия
+func (s *ChatService) postSystemMessage(chatID, actorID string, metadata *model.Metadata) error {
+	message := &model.Message{
+		ID:        uuid.New().String(),
+		ChatID:    chatID,
+		UserID:    actorID,
+		Type:      model.MessageTypeSystem,
+		Content:   systemMessageText(actorID, metadata),
+		Metadata:  metadata,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.chatRepository.CreateMessage(message); err != nil {
+		return err
+	}
+
+	s.publish(model.EventMessageCreated, chatID, actorID, message)
+	return nil
+}
+
+// publish рассылает событие подписчикам чата. Сообщение копируется,
+// чтобы подписчики не видели последующих изменений исходного объекта.
+func (s *ChatService) publish(eventType, chatID, userID string, message *model.Message) {
+	event := &model.ChatEvent{
+		Type:   eventType,
+		ChatID: chatID,
+		UserID: userID,
+	}
+
+	if message != nil {
+		m := *message
+		event.Message = &m
 	}
 
-	end := offset + limit
-	if end > len(messages) {
-		end = len(messages)
+	s.publishEvent(event)
+}
+
+// publishEvent рассылает готовое событие, проставляя время создания
+func (s *ChatService) publishEvent(event *model.ChatEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = s.now()
 	}
 
-	return messages[offset:end], nil
+	s.hub.publish(event)
+	s.enqueueWebhooks(event)
 }
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+// fakeUserDirectory - справочник пользователей для тестов: id -> username.
+// Ботами считаются пользователи с id, оканчивающимся на "-bot".
+type fakeUserDirectory map[string]string
+
+func (d fakeUserDirectory) Username(ctx context.Context, userID string) (string, error) {
+	username, ok := d[userID]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	return username, nil
+}
+
+func (d fakeUserDirectory) IsBot(ctx context.Context, userID string) (bool, error) {
+	if _, ok := d[userID]; !ok {
+		return false, ErrUserNotFound
+	}
+	return strings.HasSuffix(userID, "-bot"), nil
+}
+
+func newTestUserDirectory() fakeUserDirectory {
+	return fakeUserDirectory{"owner": "Owner", "alice": "Alice", "bob": "Bob", "deploy-bot": "deploy"}
+}
+
+func newTestChatService() *ChatService {
+	return NewChatService(repository.NewInMemoryChatRepository(), newTestUserDirectory(), ChatServiceConfig{})
+}
+
+func TestChatService_CreateChat(t *testing.T) {
+	s := newTestChatService()
+
+	chat, err := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice", "owner"}})
+	if err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+
+	ids := chat.ParticipantIDs()
+	if len(ids) != 2 || ids[0] != "owner" {
+		t.Errorf("Unexpected participants: %v", ids)
+	}
+
+	if chat.Participants[0].Role != model.RoleAdmin || chat.Participants[1].Role != model.RoleMember {
+		t.Errorf("Unexpected roles: %s, %s", chat.Participants[0].Role, chat.Participants[1].Role)
+	}
+}
+
+func TestChatService_SendMessage(t *testing.T) {
+	s := newTestChatService()
+
+	chat, err := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	if err != nil {
+		t.Fatalf("CreateChat failed: %v", err)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "hi"}); err == nil {
+		t.Error("Expected error for non-participant")
+	}
+
+	if err := s.ConnectChat(chat.ID, "bob"); err != nil {
+		t.Fatalf("ConnectChat failed: %v", err)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "hi"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	messages, _, err := s.GetMessages("bob", repository.MessageQuery{ChatID: chat.ID})
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+
+	// Первым идет системное сообщение о вступлении bob
+	if len(messages) != 2 || messages[0].Type != model.MessageTypeSystem || messages[1].Content != "hi" {
+		t.Errorf("Unexpected messages: %+v", messages)
+	}
+
+	if _, _, err := s.GetMessages("bob", repository.MessageQuery{ChatID: "missing"}); err == nil {
+		t.Error("Expected error for missing chat")
+	}
+
+	if _, _, err := s.GetMessages("stranger", repository.MessageQuery{ChatID: chat.ID}); err == nil {
+		t.Error("Expected error for non-participant")
+	}
+}
+
+func TestChatService_SendMessageIdempotent(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	first, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi", ClientMessageID: "retry-1"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if event := nextEvent(sub); event == nil || event.Message.ID != first.ID {
+		t.Fatalf("Expected message.created for the first send, got %+v", event)
+	}
+	for nextEvent(sub) != nil { // presence.changed автора
+	}
+
+	// Повтор возвращает исходное сообщение и ничего не рассылает
+	retry, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi", ClientMessageID: "retry-1"})
+	if err != nil {
+		t.Fatalf("SendMessage retry failed: %v", err)
+	}
+	if retry.ID != first.ID || retry.Seq != first.Seq {
+		t.Errorf("Expected retry to return %s, got %s", first.ID, retry.ID)
+	}
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Expected no event for a retry, got %+v", event)
+	}
+
+	// Ключ уникален в пределах автора и чата
+	other, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hi", ClientMessageID: "retry-1"})
+	if other.ID == first.ID {
+		t.Error("Expected the same key from another user to create a new message")
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi", ClientMessageID: strings.Repeat("x", 65)}); !errors.Is(err, ErrInvalidClientMessageID) {
+		t.Errorf("Expected ErrInvalidClientMessageID, got %v", err)
+	}
+
+	// Параллельные повторы создают одно сообщение
+	var wg sync.WaitGroup
+	ids := make([]string, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if message, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "again", ClientMessageID: "retry-2"}); err == nil {
+				ids[i] = message.ID
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if id == "" || id != ids[0] {
+			t.Fatalf("Expected all retries to return one message, got %v", ids)
+		}
+	}
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if len(messages) != 3 {
+		t.Errorf("Expected 3 messages, got %d", len(messages))
+	}
+}
+
+func TestChatService_MessageKeyRetention(t *testing.T) {
+	repo := repository.NewInMemoryChatRepository()
+	s := NewChatService(repo, newTestUserDirectory(), ChatServiceConfig{MessageKeyRetention: time.Millisecond})
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	first, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hi", ClientMessageID: "key"})
+
+	time.Sleep(5 * time.Millisecond)
+
+	// После окна хранения тот же ключ создает новое сообщение
+	second, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hi", ClientMessageID: "key"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Error("Expected a new message after the retention window")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	s.sweepMessageKeys()
+	if deleted, _ := repo.DeleteMessageKeys(time.Now()); deleted != 0 {
+		t.Errorf("Expected sweep to delete expired keys, %d left", deleted)
+	}
+}
+
+func TestChatService_GetMessagesCursors(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	for i := 1; i <= 5; i++ {
+		if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: fmt.Sprintf("m%d", i)}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+
+	seqs := func(messages []*model.Message) []int64 {
+		var result []int64
+		for _, m := range messages {
+			result = append(result, m.Seq)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		query   repository.MessageQuery
+		want    []int64
+		hasMore bool
+	}{
+		{"offset page", repository.MessageQuery{Limit: 2, Offset: 1}, []int64{2, 3}, true},
+		{"last page", repository.MessageQuery{Limit: 2, Offset: 3}, []int64{4, 5}, false},
+		{"latest before cursor", repository.MessageQuery{Limit: 2, BeforeSeq: 5}, []int64{3, 4}, true},
+		{"oldest before cursor", repository.MessageQuery{Limit: 2, BeforeSeq: 3}, []int64{1, 2}, false},
+		{"catch up after cursor", repository.MessageQuery{Limit: 2, AfterSeq: 2}, []int64{3, 4}, true},
+		{"range", repository.MessageQuery{Limit: 10, AfterSeq: 1, BeforeSeq: 4}, []int64{2, 3}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.ChatID = chat.ID
+
+			messages, hasMore, err := s.GetMessages("owner", tt.query)
+			if err != nil {
+				t.Fatalf("GetMessages failed: %v", err)
+			}
+
+			if got := seqs(messages); !slices.Equal(got, tt.want) || hasMore != tt.hasMore {
+				t.Errorf("Expected %v (has_more=%v), got %v (has_more=%v)", tt.want, tt.hasMore, got, hasMore)
+			}
+		})
+	}
+}
+
+func TestChatService_EditAndDeleteMessage(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+	message, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "helo"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	if _, err := s.EditMessage(message.ID, "bob", "hacked"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for another member, got %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "bob")
+	defer sub.Close()
+
+	edited, err := s.EditMessage(message.ID, "alice", "hello")
+	if err != nil {
+		t.Fatalf("EditMessage failed: %v", err)
+	}
+	if edited.Content != "hello" || edited.EditedAt == nil {
+		t.Errorf("Unexpected edited message: %+v", edited)
+	}
+
+	history, err := s.GetMessageHistory(message.ID, "bob")
+	if err != nil || len(history) != 1 || history[0].Content != "helo" {
+		t.Errorf("Unexpected history: %+v, %v", history, err)
+	}
+
+	// Администратор чата может удалить чужое сообщение
+	if _, err := s.DeleteMessage(message.ID, "owner"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+
+	if _, err := s.EditMessage(message.ID, "alice", "again"); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("Expected ErrMessageDeleted, got %v", err)
+	}
+
+	messages, _, _ := s.GetMessages("bob", repository.MessageQuery{ChatID: chat.ID})
+	if len(messages) != 1 || !messages[0].IsDeleted() || messages[0].Content != "" {
+		t.Errorf("Expected tombstone, got %+v", messages)
+	}
+
+	for _, eventType := range []string{model.EventMessageEdited, model.EventMessageDeleted} {
+		event := <-sub.Events()
+		if event.Type != eventType {
+			t.Errorf("Expected %s, got %s", eventType, event.Type)
+		}
+	}
+}
+
+func TestChatService_Threads(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	other, _ := s.CreateChat(CreateChatInput{Name: "random", CreatedBy: "owner"})
+
+	root, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "release?"})
+	first, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "tomorrow", ReplyTo: root.ID})
+	if err != nil {
+		t.Fatalf("SendMessage reply failed: %v", err)
+	}
+
+	// Ответ на ответ попадает в тот же тред
+	second, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "ok", ReplyTo: first.ID})
+	if err != nil {
+		t.Fatalf("SendMessage nested reply failed: %v", err)
+	}
+	if second.ReplyTo == nil || *second.ReplyTo != root.ID {
+		t.Errorf("Expected nested reply to point at root, got %v", second.ReplyTo)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: other.ID, UserID: "owner", Content: "x", ReplyTo: root.ID}); !errors.Is(err, ErrReplyToOtherChat) {
+		t.Errorf("Expected ErrReplyToOtherChat, got %v", err)
+	}
+
+	threadRoot, replies, err := s.GetThread(second.ID, "alice")
+	if err != nil {
+		t.Fatalf("GetThread failed: %v", err)
+	}
+	if threadRoot.ID != root.ID || len(replies) != 2 || replies[0].ID != first.ID || replies[1].ID != second.ID {
+		t.Errorf("Unexpected thread: root=%s replies=%+v", threadRoot.ID, replies)
+	}
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if messages[0].ReplyCount != 2 || messages[1].ReplyCount != 0 {
+		t.Errorf("Expected reply count 2 on root only, got %d and %d", messages[0].ReplyCount, messages[1].ReplyCount)
+	}
+}
+
+func TestChatService_AddParticipant(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	if err := s.AddParticipant(ctx, chat.ID, "alice", "bob"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member, got %v", err)
+	}
+	if err := s.AddParticipant(ctx, chat.ID, "owner", "ghost"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for an unknown user, got %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	defer sub.Close()
+
+	if err := s.AddParticipant(ctx, chat.ID, "owner", "bob"); err != nil {
+		t.Fatalf("AddParticipant failed: %v", err)
+	}
+
+	event := nextEvent(sub)
+	for event != nil && event.Type == model.EventPresenceChanged {
+		event = nextEvent(sub)
+	}
+	if event == nil || event.Type != model.EventParticipantJoined || event.UserID != "bob" {
+		t.Fatalf("Expected participant.joined for bob, got %+v", event)
+	}
+
+	// Повторное добавление ничего не делает
+	if err := s.AddParticipant(ctx, chat.ID, "owner", "bob"); err != nil {
+		t.Fatalf("Repeated AddParticipant failed: %v", err)
+	}
+	if participants, _ := s.chatRepository.GetParticipants(chat.ID); len(participants) != 3 {
+		t.Errorf("Expected 3 participants, got %d", len(participants))
+	}
+
+	if err := s.BanParticipant(chat.ID, "owner", "bob", "spam"); err != nil {
+		t.Fatalf("BanParticipant failed: %v", err)
+	}
+	if err := s.AddParticipant(ctx, chat.ID, "owner", "bob"); !errors.Is(err, ErrBanned) {
+		t.Errorf("Expected ErrBanned, got %v", err)
+	}
+}
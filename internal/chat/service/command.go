@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	maxCommandDescriptionLength = 255
+	maxCommandArgs              = 10
+	maxCommandsPerChat          = 100
+	maxTopicLength              = 250
+)
+
+// commandNamePattern - допустимое имя команды или аргумента
+// (chat_commands.name VARCHAR(32))
+var commandNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+var (
+	ErrUnknownCommand     = errors.New("unknown command")
+	ErrInvalidCommandArgs = errors.New("invalid command arguments")
+	ErrInvalidCommand     = errors.New("invalid command")
+	ErrBuiltInCommand     = errors.New("built-in commands cannot be overridden")
+	ErrCommandLimit       = errors.New("chat has reached its commands limit")
+	ErrNotBot             = errors.New("only bots can register commands")
+	ErrScheduledCommand   = errors.New("commands cannot be scheduled")
+	ErrBotCommand         = errors.New("bots can only run /me and bot commands")
+	ErrCommandEdit        = errors.New("command messages cannot be edited")
+	ErrCommandMessage     = errors.New("command messages can only be created by slash commands")
+	ErrInvalidTopic       = errors.New("topic must be at most 250 characters")
+)
+
+// Коды CommandError
+const (
+	CommandErrorUnknown     = "unknown_command"
+	CommandErrorInvalidArgs = "invalid_arguments"
+)
+
+// CommandError - ошибка вызова slash-команды. Сообщение с такой командой
+// не отправляется; клиент может показать подсказку по Code и Usage.
+type CommandError struct {
+	Code    string
+	Command string // Имя команды без "/"
+	Usage   string // Синтаксис команды; пусто для неизвестной команды
+	Reason  string // Что не так с аргументами
+}
+
+func (e *CommandError) Error() string {
+	if e.Code == CommandErrorUnknown {
+		return fmt.Sprintf("%v: /%s", ErrUnknownCommand, e.Command)
+	}
+	return fmt.Sprintf("%v: %s (usage: %s)", ErrInvalidCommandArgs, e.Reason, e.Usage)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrUnknownCommand)
+// и errors.Is(err, ErrInvalidCommandArgs)
+func (e *CommandError) Unwrap() error {
+	if e.Code == CommandErrorUnknown {
+		return ErrUnknownCommand
+	}
+	return ErrInvalidCommandArgs
+}
+
+func invalidCommandArgs(command *model.Command, format string, args ...interface{}) error {
+	return &CommandError{
+		Code:    CommandErrorInvalidArgs,
+		Command: command.Name,
+		Usage:   command.Usage(),
+		Reason:  fmt.Sprintf(format, args...),
+	}
+}
+
+func invalidCommand(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCommand, fmt.Sprintf(format, args...))
+}
+
+// Встроенные команды
+const (
+	commandInvite = "invite"
+	commandMe     = "me"
+	commandTopic  = "topic"
+)
+
+var builtinCommands = []*model.Command{
+	{
+		Name:        commandInvite,
+		Description: "Add a user to the chat",
+		Args:        model.CommandArgs{{Name: "user", Type: model.CommandArgUser, Required: true}},
+		BuiltIn:     true,
+	},
+	{
+		Name:        commandMe,
+		Description: "Describe what you are doing",
+		Args:        model.CommandArgs{{Name: "action", Type: model.CommandArgText, Required: true}},
+		BuiltIn:     true,
+	},
+	{
+		Name:        commandTopic,
+		Description: "Set the chat topic, or clear it without arguments",
+		Args:        model.CommandArgs{{Name: "topic", Type: model.CommandArgText}},
+		BuiltIn:     true,
+	},
+}
+
+func builtinCommand(name string) *model.Command {
+	for _, command := range builtinCommands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// commandLine - slash-команда из текста сообщения
+type commandLine struct {
+	name string // В нижнем регистре, без "/"
+	args string
+}
+
+// parseCommandLine выделяет команду из текстового сообщения "/имя аргументы".
+// Сообщение, начинающееся с "//", командой не считается: первая косая черта
+// снимается, и оно отправляется как обычный текст.
+func parseCommandLine(input *SendMessageInput) *commandLine {
+	if input.Type != "" && input.Type != model.MessageTypeText || !strings.HasPrefix(input.Content, "/") {
+		return nil
+	}
+
+	if strings.HasPrefix(input.Content, "//") {
+		input.Content = input.Content[1:]
+		return nil
+	}
+
+	name, args := input.Content[1:], ""
+	if end := strings.IndexFunc(name, unicode.IsSpace); end >= 0 {
+		name, args = name[:end], strings.TrimSpace(name[end:])
+	}
+
+	if name == "" {
+		return nil // "/" без имени - обычный текст
+	}
+
+	return &commandLine{name: strings.ToLower(name), args: args}
+}
+
+// runCommand выполняет slash-команду из SendMessage. /me и команды ботов
+// сохраняются сообщениями (action и command); /topic и /invite только
+// выполняют действие, о котором чат узнает из системного сообщения,
+// и возвращают nil. Боты могут вызывать только /me и команды ботов.
+func (s *ChatService) runCommand(ctx context.Context, input SendMessageInput, line *commandLine) (*model.Message, error) {
+	chat, err := s.requireParticipant(input.ChatID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := s.findCommand(chat, line.name)
+	if err != nil {
+		return nil, err
+	}
+
+	// /topic и /invite меняют чат, а ботам это недоступно и через RPC
+	if command.BuiltIn && command.Name != commandMe {
+		isBot, err := s.users.IsBot(ctx, input.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if isBot {
+			return nil, ErrBotCommand
+		}
+	}
+
+	args, err := s.parseCommandArgs(ctx, command, line.args)
+	if err != nil {
+		return nil, err
+	}
+
+	if command.BuiltIn {
+		switch command.Name {
+		case commandTopic:
+			_, err := s.SetTopic(input.ChatID, input.UserID, args["topic"])
+			return nil, err
+		case commandInvite:
+			return nil, s.AddParticipant(ctx, input.ChatID, input.UserID, args["user"])
+		case commandMe:
+			input.Type = model.MessageTypeAction
+			input.Content = args["action"]
+		}
+	}
+
+	chat, message, err := s.prepareMessage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !command.BuiltIn {
+		message.Type = model.MessageTypeCommand
+		message.Metadata = &model.Metadata{
+			Command: &model.CommandCall{Name: command.Name, BotID: command.BotID, Args: args},
+		}
+	}
+
+	return s.storeMessage(ctx, chat, message, input)
+}
+
+// findCommand ищет команду среди встроенных и команд ботов чата. Команда
+// бота, покинувшего чат, считается неизвестной.
+func (s *ChatService) findCommand(chat *model.Chat, name string) (*model.Command, error) {
+	if command := builtinCommand(name); command != nil {
+		return command, nil
+	}
+
+	unknown := &CommandError{Code: CommandErrorUnknown, Command: name}
+	if !commandNamePattern.MatchString(name) {
+		return nil, unknown
+	}
+
+	command, err := s.chatRepository.GetCommand(chat.ID, name)
+	if errors.Is(err, repository.ErrCommandNotFound) {
+		return nil, unknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if findParticipant(chat, command.BotID) == nil {
+		return nil, unknown
+	}
+
+	return command, nil
+}
+
+// parseCommandArgs разбирает позиционные аргументы по схеме команды.
+// Аргументы разделяются пробелами; аргумент типа text забирает остаток строки.
+func (s *ChatService) parseCommandArgs(ctx context.Context, command *model.Command, text string) (map[string]string, error) {
+	args := make(map[string]string, len(command.Args))
+	rest := text
+
+	for _, arg := range command.Args {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			if arg.Required {
+				return nil, invalidCommandArgs(command, "%s is required", arg.Name)
+			}
+			break
+		}
+
+		var value string
+		if arg.Type == model.CommandArgText {
+			value, rest = strings.TrimSpace(rest), ""
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+
+		switch arg.Type {
+		case model.CommandArgNumber:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, invalidCommandArgs(command, "%s must be a number", arg.Name)
+			}
+		case model.CommandArgBool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, invalidCommandArgs(command, "%s must be true or false", arg.Name)
+			}
+			value = strconv.FormatBool(b)
+		case model.CommandArgUser:
+			_, err := s.users.Username(ctx, value)
+			if errors.Is(err, ErrUserNotFound) {
+				return nil, invalidCommandArgs(command, "%s: user %s not found", arg.Name, value)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		args[arg.Name] = value
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return nil, invalidCommandArgs(command, "too many arguments")
+	}
+
+	return args, nil
+}
+
+// SetTopic меняет тему чата; пустая тема удаляет ее.
+// Доступно модераторам и администраторам чата.
+func (s *ChatService) SetTopic(chatID, actorID, topic string) (*model.Chat, error) {
+	topic = strings.TrimSpace(topic)
+	if utf8.RuneCountInString(topic) > maxTopicLength {
+		return nil, ErrInvalidTopic
+	}
+
+	chat, err := s.requireRank(chatID, actorID, rankModerator)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if chat.Topic == topic {
+		return chat, nil
+	}
+
+	chat.Topic = topic
+	if err := s.chatRepository.UpdateChat(chat, "Topic"); err != nil {
+		return nil, err
+	}
+
+	metadata := &model.Metadata{Event: model.SystemEventTopic, Topic: topic}
+	if err := s.postSystemMessage(chatID, actorID, metadata); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// RegisterCommandInput - параметры регистрации команды бота
+type RegisterCommandInput struct {
+	ChatID      string
+	BotID       string
+	Name        string // Без "/": латинские буквы в нижнем регистре, цифры и _
+	Description string
+	Args        model.CommandArgs
+}
+
+// RegisterCommand регистрирует команду бота в чате, где бот состоит
+// участником. Повторная регистрация тем же ботом заменяет описание
+// и аргументы; имя, занятое другим ботом, возвращает ErrCommandTaken.
+func (s *ChatService) RegisterCommand(ctx context.Context, input RegisterCommandInput) (*model.Command, error) {
+	isBot, err := s.users.IsBot(ctx, input.BotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isBot {
+		return nil, ErrNotBot
+	}
+
+	now := s.now()
+	command := &model.Command{
+		ChatID:      input.ChatID,
+		Name:        strings.ToLower(strings.TrimSpace(input.Name)),
+		BotID:       input.BotID,
+		Description: strings.TrimSpace(input.Description),
+		Args:        input.Args,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := validateCommand(command); err != nil {
+		return nil, err
+	}
+
+	chat, err := s.requireParticipant(input.ChatID, input.BotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if builtinCommand(command.Name) != nil {
+		return nil, ErrBuiltInCommand
+	}
+
+	commands, err := s.chatRepository.GetCommands(input.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := slices.ContainsFunc(commands, func(c *model.Command) bool { return c.Name == command.Name })
+	if !registered && len(commands) >= maxCommandsPerChat {
+		return nil, ErrCommandLimit
+	}
+
+	if err := s.chatRepository.SaveCommand(command); err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// validateCommand проверяет имя, описание и схему аргументов команды
+func validateCommand(command *model.Command) error {
+	if !commandNamePattern.MatchString(command.Name) {
+		return invalidCommand("name must be 1-32 lowercase letters, digits or _ starting with a letter")
+	}
+
+	if utf8.RuneCountInString(command.Description) > maxCommandDescriptionLength {
+		return invalidCommand("description must be at most %d characters", maxCommandDescriptionLength)
+	}
+
+	if len(command.Args) > maxCommandArgs {
+		return invalidCommand("a command can have at most %d arguments", maxCommandArgs)
+	}
+
+	seen := make(map[string]bool, len(command.Args))
+	optional := false
+	for i, arg := range command.Args {
+		if arg == nil || !commandNamePattern.MatchString(arg.Name) {
+			return invalidCommand("argument names must be lowercase letters, digits or _ starting with a letter")
+		}
+		if seen[arg.Name] {
+			return invalidCommand("argument %s is declared twice", arg.Name)
+		}
+		seen[arg.Name] = true
+
+		if !model.ValidCommandArgType(arg.Type) {
+			return invalidCommand("argument %s has unknown type %q", arg.Name, arg.Type)
+		}
+		if arg.Type == model.CommandArgText && i != len(command.Args)-1 {
+			return invalidCommand("text argument %s must be the last one", arg.Name)
+		}
+		if arg.Required && optional {
+			return invalidCommand("required argument %s cannot follow an optional one", arg.Name)
+		}
+		optional = optional || !arg.Required
+
+		if utf8.RuneCountInString(arg.Description) > maxCommandDescriptionLength {
+			return invalidCommand("argument descriptions must be at most %d characters", maxCommandDescriptionLength)
+		}
+	}
+
+	return nil
+}
+
+// UnregisterCommand удаляет команду бота из чата. Удалить ее может сам бот
+// или администратор чата.
+func (s *ChatService) UnregisterCommand(chatID, userID, name string) error {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	command, err := s.chatRepository.GetCommand(chatID, strings.ToLower(name))
+	if err != nil {
+		return err
+	}
+
+	if command.BotID != userID && participantRank(chat, userID) < rankAdmin {
+		return ErrPermissionDenied
+	}
+
+	return s.chatRepository.DeleteCommand(chatID, command.Name)
+}
+
+// ListCommands возвращает команды, доступные в чате, для автодополнения:
+// встроенные и команды ботов, которые остаются участниками, по имени
+func (s *ChatService) ListCommands(chatID, userID string) ([]*model.Command, error) {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := s.chatRepository.GetCommands(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]*model.Command, 0, len(builtinCommands)+len(registered))
+	for _, builtin := range builtinCommands {
+		command := *builtin
+		command.ChatID = chatID
+		commands = append(commands, &command)
+	}
+
+	for _, command := range registered {
+		if findParticipant(chat, command.BotID) != nil {
+			commands = append(commands, command)
+		}
+	}
+
+	slices.SortFunc(commands, func(a, b *model.Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return commands, nil
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		content     string
+		name, args  string
+		isCommand   bool
+		sentContent string
+	}{
+		{content: "/me waves", name: "me", args: "waves", isCommand: true},
+		{content: "/Topic  Release  planning ", name: "topic", args: "Release  planning", isCommand: true},
+		{content: "/invite", name: "invite", isCommand: true},
+		{content: "/deploy\napi", name: "deploy", args: "api", isCommand: true},
+		{content: "//me is not a command", sentContent: "/me is not a command"},
+		{content: "/ alone", sentContent: "/ alone"},
+		{content: "hello /me", sentContent: "hello /me"},
+	}
+
+	for _, test := range tests {
+		input := SendMessageInput{Content: test.content}
+		line := parseCommandLine(&input)
+		if !test.isCommand {
+			if line != nil || input.Content != test.sentContent {
+				t.Errorf("Expected %q to be sent as %q, got command %+v and %q", test.content, test.sentContent, line, input.Content)
+			}
+			continue
+		}
+		if line == nil || line.name != test.name || line.args != test.args {
+			t.Errorf("Expected %q to be /%s with %q, got %+v", test.content, test.name, test.args, line)
+		}
+	}
+
+	input := SendMessageInput{Type: model.MessageTypeFile, Content: "/me"}
+	if parseCommandLine(&input) != nil {
+		t.Error("Expected captions of files not to be commands")
+	}
+}
+
+func TestChatService_BuiltinCommands(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+	nextEvent(sub) // presence.changed
+
+	message, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/me waves at @owner"})
+	if err != nil {
+		t.Fatalf("SendMessage /me failed: %v", err)
+	}
+	if message.Type != model.MessageTypeAction || message.Content != "waves at @owner" || len(message.Mentions) != 1 {
+		t.Errorf("Expected an action message with a mention, got %+v", message)
+	}
+	for nextEvent(sub) != nil { // message.created и message.mentioned
+	}
+
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/topic Release"}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member setting the topic, got %v", err)
+	}
+
+	message, err = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/topic Release 2.0 planning"})
+	if err != nil || message != nil {
+		t.Fatalf("Expected /topic to return no message, got %+v, %v", message, err)
+	}
+	if event := nextEvent(sub); event == nil || event.Message.Type != model.MessageTypeSystem || event.Message.Metadata.Topic != "Release 2.0 planning" {
+		t.Fatalf("Expected a topic_changed system message, got %+v", event)
+	}
+	if updated, _ := s.chatRepository.GetChatByID(chat.ID); updated.Topic != "Release 2.0 planning" {
+		t.Errorf("Expected the topic to be saved, got %q", updated.Topic)
+	}
+
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/invite"}); !errors.Is(err, ErrInvalidCommandArgs) {
+		t.Errorf("Expected ErrInvalidCommandArgs without a user, got %v", err)
+	}
+	_, err = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/invite nobody"})
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) || commandErr.Code != CommandErrorInvalidArgs || commandErr.Usage != "/invite <user>" {
+		t.Errorf("Expected invalid_arguments with usage for an unknown user, got %v", err)
+	}
+
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/invite bob"}); err != nil {
+		t.Fatalf("SendMessage /invite failed: %v", err)
+	}
+	if isParticipant, _ := s.chatRepository.IsParticipant(chat.ID, "bob"); !isParticipant {
+		t.Error("Expected /invite to add bob")
+	}
+
+	_, err = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/shrug"})
+	if !errors.As(err, &commandErr) || commandErr.Code != CommandErrorUnknown || commandErr.Command != "shrug" {
+		t.Errorf("Expected unknown_command, got %v", err)
+	}
+
+	message, err = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "//shrug"})
+	if err != nil || message.Type != model.MessageTypeText || message.Content != "/shrug" {
+		t.Errorf("Expected the escaped command to be sent as text, got %+v, %v", message, err)
+	}
+
+	if _, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "/me later"}, time.Now().Add(time.Hour)); !errors.Is(err, ErrScheduledCommand) {
+		t.Errorf("Expected ErrScheduledCommand, got %v", err)
+	}
+}
+
+func TestChatService_BotCommands(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "ops", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	deploy := RegisterCommandInput{
+		ChatID:      chat.ID,
+		BotID:       "deploy-bot",
+		Name:        "deploy",
+		Description: "Deploy a service",
+		Args: model.CommandArgs{
+			{Name: "service", Type: model.CommandArgString, Required: true},
+			{Name: "canary", Type: model.CommandArgBool},
+		},
+	}
+
+	if _, err := s.RegisterCommand(ctx, deploy); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant before the bot is added, got %v", err)
+	}
+
+	notBot := deploy
+	notBot.BotID = "alice"
+	if _, err := s.RegisterCommand(ctx, notBot); !errors.Is(err, ErrNotBot) {
+		t.Errorf("Expected ErrNotBot, got %v", err)
+	}
+
+	if err := s.AddParticipant(ctx, chat.ID, "owner", "deploy-bot"); err != nil {
+		t.Fatalf("AddParticipant failed: %v", err)
+	}
+
+	command, err := s.RegisterCommand(ctx, deploy)
+	if err != nil {
+		t.Fatalf("RegisterCommand failed: %v", err)
+	}
+	if command.Usage() != "/deploy <service> [canary]" {
+		t.Errorf("Unexpected usage: %s", command.Usage())
+	}
+
+	builtin := deploy
+	builtin.Name = "me"
+	if _, err := s.RegisterCommand(ctx, builtin); !errors.Is(err, ErrBuiltInCommand) {
+		t.Errorf("Expected ErrBuiltInCommand, got %v", err)
+	}
+
+	invalid := deploy
+	invalid.Args = model.CommandArgs{{Name: "reason", Type: model.CommandArgText}, {Name: "service", Type: model.CommandArgString}}
+	if _, err := s.RegisterCommand(ctx, invalid); !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("Expected ErrInvalidCommand for a text argument before the last one, got %v", err)
+	}
+
+	commands, err := s.ListCommands(chat.ID, "alice")
+	if err != nil {
+		t.Fatalf("ListCommands failed: %v", err)
+	}
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+	if len(names) != 4 || names[0] != "deploy" || commands[0].BuiltIn || names[1] != "invite" || !commands[1].BuiltIn {
+		t.Errorf("Expected built-in and bot commands ordered by name, got %v", names)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "deploy-bot")
+	defer sub.Close()
+
+	message, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/deploy api yes"})
+	var commandErr *CommandError
+	if !errors.As(err, &commandErr) || commandErr.Code != CommandErrorInvalidArgs {
+		t.Errorf("Expected invalid_arguments for a non-boolean canary, got %v", err)
+	}
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/deploy api true now"}); !errors.Is(err, ErrInvalidCommandArgs) {
+		t.Errorf("Expected ErrInvalidCommandArgs for too many arguments, got %v", err)
+	}
+
+	message, err = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/deploy api 1"})
+	if err != nil {
+		t.Fatalf("SendMessage /deploy failed: %v", err)
+	}
+	call := message.Metadata.Command
+	if message.Type != model.MessageTypeCommand || call.BotID != "deploy-bot" || call.Args["service"] != "api" || call.Args["canary"] != "true" {
+		t.Errorf("Unexpected command message: %+v %+v", message, call)
+	}
+
+	var event *model.ChatEvent
+	for event = nextEvent(sub); event != nil && event.Type != model.EventMessageCreated; event = nextEvent(sub) {
+	}
+	if event == nil || event.Message.ID != message.ID || event.Message.Metadata.Command.Name != "deploy" {
+		t.Errorf("Expected the bot to receive the command, got %+v", event)
+	}
+
+	if _, err := s.EditMessage(message.ID, "alice", "/deploy web"); !errors.Is(err, ErrCommandEdit) {
+		t.Errorf("Expected ErrCommandEdit, got %v", err)
+	}
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Type: model.MessageTypeCommand, Content: "deploy"}); !errors.Is(err, ErrCommandMessage) {
+		t.Errorf("Expected ErrCommandMessage for a client-typed command, got %v", err)
+	}
+
+	if err := s.UnregisterCommand(chat.ID, "alice", "deploy"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member, got %v", err)
+	}
+
+	// Команды бота, покинувшего чат, недоступны
+	if err := s.LeaveChat(chat.ID, "deploy-bot"); err != nil {
+		t.Fatalf("LeaveChat failed: %v", err)
+	}
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "/deploy api"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Expected ErrUnknownCommand after the bot left, got %v", err)
+	}
+	if commands, _ := s.ListCommands(chat.ID, "alice"); len(commands) != len(builtinCommands) {
+		t.Errorf("Expected only built-in commands, got %d", len(commands))
+	}
+
+	if err := s.UnregisterCommand(chat.ID, "owner", "deploy"); err != nil {
+		t.Errorf("UnregisterCommand by an admin failed: %v", err)
+	}
+}
+
+func TestChatService_BotCommandLimits(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"deploy-bot"}})
+	// Даже с правами модератора бот не меняет чат через команды
+	if err := s.SetParticipantRole(chat.ID, "owner", "deploy-bot", model.RoleModerator); err != nil {
+		t.Fatalf("SetParticipantRole failed: %v", err)
+	}
+
+	for _, content := range []string{"/topic Deploys", "/invite @alice"} {
+		if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "deploy-bot", Content: content}); !errors.Is(err, ErrBotCommand) {
+			t.Errorf("Expected ErrBotCommand for %q, got %v", content, err)
+		}
+	}
+
+	updated, _ := s.chatRepository.GetChatByID(chat.ID)
+	if updated.Topic != "" || len(updated.Participants) != 2 {
+		t.Errorf("Expected the chat to stay unchanged, got topic %q and %d participants", updated.Topic, len(updated.Participants))
+	}
+
+	if message, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "deploy-bot", Content: "/me deploys"}); err != nil || message.Type != model.MessageTypeAction {
+		t.Errorf("Expected bots to use /me, got %+v (err=%v)", message, err)
+	}
+
+	if _, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "deploy-bot", Content: "later"}, time.Now().Add(time.Hour)); !errors.Is(err, ErrBotScheduling) {
+		t.Errorf("Expected ErrBotScheduling, got %v", err)
+	}
+}
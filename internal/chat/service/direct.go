@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDirectChat         = errors.New("operation is not available in a direct chat")
+	ErrDirectChatWithSelf = errors.New("cannot open a direct chat with yourself")
+)
+
+// directChatParticipants - в личном чате всегда ровно два участника
+const directChatParticipants = 2
+
+// OpenDirectChat возвращает личный чат пользователя с peerID, создавая его при
+// первом обращении. Второй результат - был ли чат создан этим вызовом.
+// Название возвращаемого чата - имя собеседника.
+func (s *ChatService) OpenDirectChat(ctx context.Context, userID, peerID string) (*model.Chat, bool, error) {
+	if userID == peerID {
+		return nil, false, ErrDirectChatWithSelf
+	}
+
+	peerName, err := s.users.Username(ctx, peerID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := s.now()
+	key := model.DirectChatKey(userID, peerID)
+	chat := &model.Chat{
+		ID:              uuid.New().String(),
+		CreatedBy:       userID,
+		CreatedAt:       now,
+		IsPrivate:       true,
+		MaxParticipants: directChatParticipants,
+		DirectKey:       &key,
+		Participants: []*model.Participant{
+			{UserID: userID, Role: model.RoleMember, JoinedAt: now},
+			{UserID: peerID, Role: model.RoleMember, JoinedAt: now},
+		},
+	}
+
+	chat, created, err := s.chatRepository.GetOrCreateDirectChat(chat)
+	if err != nil {
+		return nil, false, err
+	}
+
+	chat.Name = peerName
+	return chat, created, nil
+}
+
+// fillDirectChatNames подставляет имя собеседника вместо названия личных чатов.
+// Если справочник недоступен, используется идентификатор собеседника.
+func (s *ChatService) fillDirectChatNames(ctx context.Context, userID string, summaries []*repository.ChatSummary) {
+	for _, summary := range summaries {
+		if !summary.Chat.IsDirect() {
+			continue
+		}
+
+		peerID := summary.Chat.DirectPeer(userID)
+		name, err := s.users.Username(ctx, peerID)
+		if err != nil {
+			name = peerID
+		}
+		summary.Chat.Name = name
+	}
+}
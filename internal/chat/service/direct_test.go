@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestChatService_OpenDirectChat(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, created, err := s.OpenDirectChat(ctx, "alice", "bob")
+	if err != nil {
+		t.Fatalf("OpenDirectChat failed: %v", err)
+	}
+	if !created || !chat.IsDirect() || chat.Name != "Bob" || len(chat.Participants) != 2 {
+		t.Errorf("Unexpected direct chat: created=%v %+v", created, chat)
+	}
+
+	// Пара неупорядочена: собеседник получает тот же чат
+	again, created, err := s.OpenDirectChat(ctx, "bob", "alice")
+	if err != nil {
+		t.Fatalf("OpenDirectChat failed: %v", err)
+	}
+	if created || again.ID != chat.ID || again.Name != "Alice" {
+		t.Errorf("Expected existing chat %s named Alice, got created=%v %+v", chat.ID, created, again)
+	}
+
+	if _, _, err := s.OpenDirectChat(ctx, "alice", "alice"); !errors.Is(err, ErrDirectChatWithSelf) {
+		t.Errorf("Expected ErrDirectChatWithSelf, got %v", err)
+	}
+	if _, _, err := s.OpenDirectChat(ctx, "alice", "ghost"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestChatService_OpenDirectChatConcurrent(t *testing.T) {
+	s := newTestChatService()
+
+	var wg sync.WaitGroup
+	ids := make([]string, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			userID, peerID := "alice", "bob"
+			if i%2 == 1 {
+				userID, peerID = peerID, userID
+			}
+			chat, _, err := s.OpenDirectChat(context.Background(), userID, peerID)
+			if err != nil {
+				t.Errorf("OpenDirectChat failed: %v", err)
+				return
+			}
+			ids[i] = chat.ID
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("Expected a single direct chat, got %v", ids)
+		}
+	}
+}
+
+func TestChatService_DirectChatRestrictions(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _, _ := s.OpenDirectChat(ctx, "alice", "bob")
+
+	if err := s.ConnectChat(chat.ID, "owner"); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("ConnectChat: expected ErrDirectChat, got %v", err)
+	}
+	if err := s.ConnectChat(chat.ID, "alice"); err != nil {
+		t.Errorf("ConnectChat for a participant failed: %v", err)
+	}
+	if _, err := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "alice"}); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("CreateInvite: expected ErrDirectChat, got %v", err)
+	}
+	if _, err := s.RenameChat(chat.ID, "alice", "ours"); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("RenameChat: expected ErrDirectChat, got %v", err)
+	}
+	if err := s.KickParticipant(chat.ID, "alice", "bob"); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("KickParticipant: expected ErrDirectChat, got %v", err)
+	}
+	if err := s.TransferOwnership(chat.ID, "alice", "bob"); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("TransferOwnership: expected ErrDirectChat, got %v", err)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "hi"}); err != nil {
+		t.Errorf("SendMessage failed: %v", err)
+	}
+}
+
+func TestChatService_ListMyChatsDirect(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	direct, _, _ := s.OpenDirectChat(ctx, "alice", "bob")
+
+	summaries, err := s.ListMyChats(ctx, "alice", false)
+	if err != nil {
+		t.Fatalf("ListMyChats failed: %v", err)
+	}
+
+	names := map[string]string{}
+	for _, summary := range summaries {
+		names[summary.Chat.ID] = summary.Chat.Name
+	}
+	if len(names) != 2 || names[direct.ID] != "Bob" {
+		t.Errorf("Unexpected chat names: %v", names)
+	}
+
+	summaries, _ = s.ListMyChats(ctx, "bob", false)
+	if len(summaries) != 1 || summaries[0].Chat.Name != "Alice" {
+		t.Errorf("Expected the direct chat named Alice, got %+v", summaries)
+	}
+}
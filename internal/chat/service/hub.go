@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"sync"
+
+	"golang-chat/internal/chat/model"
+)
+
+// defaultSubscriberBuffer - сколько событий может накопиться у подписчика,
+// прежде чем он будет признан медленным и отключен
+const defaultSubscriberBuffer = 64
+
+// Причины завершения подписки
+var (
+	ErrSlowConsumer       = errors.New("subscriber is too slow and was disconnected")
+	ErrSubscriptionClosed = errors.New("subscription closed")
+	ErrRemovedFromChat    = errors.New("user was removed from the chat")
+	ErrLeftChat           = errors.New("user left the chat")
+	ErrChatDeleted        = errors.New("chat was deleted")
+)
+
+// Subscription - подписка одного клиента на события чата
+type Subscription struct {
+	ChatID string
+	UserID string
+
+	events chan *model.ChatEvent
+	hub    *hub
+	err    error // причина закрытия, защищена hub.mu
+	closed bool  // защищено hub.mu
+}
+
+// Events возвращает канал событий. Канал закрывается, когда подписка
+// завершена; причину можно узнать через Err.
+func (s *Subscription) Events() <-chan *model.ChatEvent {
+	return s.events
+}
+
+// Err возвращает причину закрытия подписки или nil, если она активна
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close отписывает клиента. Повторный вызов безопасен.
+func (s *Subscription) Close() {
+	s.hub.remove(s, ErrSubscriptionClosed)
+}
+
+// hub рассылает события всем подписчикам чата. Отправка никогда не блокируется:
+// если буфер подписчика переполнен, подписчик отключается, чтобы один
+// зависший клиент не тормозил SendMessage для остальных.
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{} // chat_id -> подписчики
+	bufferSize  int
+
+	// onClose вызывается под mu при закрытии любой подписки,
+	// поэтому не должен обращаться к hub
+	onClose func(sub *Subscription)
+}
+
+func newHub(bufferSize int) *hub {
+	return &hub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+func (h *hub) subscribe(chatID, userID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		ChatID: chatID,
+		UserID: userID,
+		events: make(chan *model.ChatEvent, h.bufferSize),
+		hub:    h,
+	}
+
+	if h.subscribers[chatID] == nil {
+		h.subscribers[chatID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[chatID][sub] = struct{}{}
+
+	return sub
+}
+
+func (h *hub) publish(event *model.ChatEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[event.ChatID] {
+		select {
+		case sub.events <- event:
+		default:
+			h.removeLocked(sub, ErrSlowConsumer)
+		}
+	}
+}
+
+// removeUser закрывает все подписки пользователя на чат
+func (h *hub) removeUser(chatID, userID string, reason error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[chatID] {
+		if sub.UserID == userID {
+			h.removeLocked(sub, reason)
+		}
+	}
+}
+
+// removeChat закрывает все подписки на чат
+func (h *hub) removeChat(chatID string, reason error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[chatID] {
+		h.removeLocked(sub, reason)
+	}
+}
+
+func (h *hub) remove(sub *Subscription, reason error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(sub, reason)
+}
+
+func (h *hub) removeLocked(sub *Subscription, reason error) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	sub.err = reason
+	close(sub.events)
+
+	if h.onClose != nil {
+		h.onClose(sub)
+	}
+
+	delete(h.subscribers[sub.ChatID], sub)
+	if len(h.subscribers[sub.ChatID]) == 0 {
+		delete(h.subscribers, sub.ChatID)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+func TestHub_FanOut(t *testing.T) {
+	h := newHub(4)
+
+	first := h.subscribe("chat", "alice")
+	second := h.subscribe("chat", "bob")
+	other := h.subscribe("other", "carol")
+
+	h.publish(&model.ChatEvent{Type: model.EventMessageCreated, ChatID: "chat"})
+
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case event := <-sub.Events():
+			if event.Type != model.EventMessageCreated {
+				t.Errorf("Unexpected event type %s", event.Type)
+			}
+		default:
+			t.Errorf("Subscriber %s did not receive the event", sub.UserID)
+		}
+	}
+
+	select {
+	case event := <-other.Events():
+		t.Errorf("Subscriber of another chat received %+v", event)
+	default:
+	}
+}
+
+func TestHub_EvictsSlowConsumer(t *testing.T) {
+	h := newHub(2)
+
+	slow := h.subscribe("chat", "slow")
+	fast := h.subscribe("chat", "fast")
+
+	for i := 0; i < 3; i++ {
+		h.publish(&model.ChatEvent{Type: model.EventMessageCreated, ChatID: "chat"})
+		<-fast.Events()
+	}
+
+	// Буфер медленного подписчика содержит 2 события, после чего канал закрыт
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+
+	if received != 2 {
+		t.Errorf("Expected 2 buffered events, got %d", received)
+	}
+
+	if !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("Expected ErrSlowConsumer, got %v", slow.Err())
+	}
+
+	if fast.Err() != nil {
+		t.Errorf("Fast subscriber should stay connected, got %v", fast.Err())
+	}
+
+	slow.Close() // повторное закрытие безопасно
+}
+
+func TestChatService_SubscribeChat(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+
+	if _, err := s.SubscribeChat(chat.ID, "stranger"); !errors.Is(err, ErrNotParticipant) {
+		t.Fatalf("Expected ErrNotParticipant, got %v", err)
+	}
+
+	sub, err := s.SubscribeChat(chat.ID, "owner")
+	if err != nil {
+		t.Fatalf("SubscribeChat failed: %v", err)
+	}
+	defer sub.Close()
+
+	if err := s.ConnectChat(chat.ID, "bob"); err != nil {
+		t.Fatalf("ConnectChat failed: %v", err)
+	}
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "hi"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	expected := []string{model.EventParticipantJoined, model.EventMessageCreated}
+	for _, eventType := range expected {
+		select {
+		case event := <-sub.Events():
+			if event.Type != eventType || event.UserID != "bob" {
+				t.Errorf("Expected %s from bob, got %+v", eventType, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %s", eventType)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+
+	"github.com/google/uuid"
+)
+
+// inviteTokenBytes - длина случайной части токена приглашения
+const inviteTokenBytes = 18
+
+var (
+	ErrInviteExpired      = errors.New("invite has expired")
+	ErrInviteRevoked      = errors.New("invite has been revoked")
+	ErrInviteExhausted    = errors.New("invite has no uses left")
+	ErrInvalidInvite      = errors.New("invalid invite parameters")
+	ErrJoinRequestPending = errors.New("chat is private: join request is pending approval")
+)
+
+// CreateInviteInput - параметры нового приглашения
+type CreateInviteInput struct {
+	ChatID    string
+	UserID    string        // Кто создает приглашение
+	MaxUses   int           // 0 - без ограничения
+	ExpiresIn time.Duration // 0 - бессрочное
+}
+
+// CreateInvite создает приглашение в чат. Доступно администраторам чата.
+func (s *ChatService) CreateInvite(input CreateInviteInput) (*model.Invite, error) {
+	if input.MaxUses < 0 || input.ExpiresIn < 0 {
+		return nil, ErrInvalidInvite
+	}
+
+	chat, err := s.requireRank(input.ChatID, input.UserID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	invite := &model.Invite{
+		ID:        uuid.New().String(),
+		ChatID:    input.ChatID,
+		Token:     token,
+		CreatedBy: input.UserID,
+		MaxUses:   input.MaxUses,
+		CreatedAt: now,
+	}
+
+	if input.ExpiresIn > 0 {
+		expiresAt := now.Add(input.ExpiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.chatRepository.CreateInvite(invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// RevokeInvite отзывает приглашение. Доступно администраторам чата.
+func (s *ChatService) RevokeInvite(chatID, userID, token string) error {
+	if _, err := s.requireRank(chatID, userID, rankAdmin); err != nil {
+		return err
+	}
+
+	invite, err := s.chatRepository.GetInviteByToken(token)
+	if err != nil {
+		return err
+	}
+
+	if invite.ChatID != chatID {
+		return repository.ErrInviteNotFound
+	}
+
+	return s.chatRepository.RevokeInvite(invite.ID, s.now())
+}
+
+// JoinByInvite добавляет пользователя в чат по токену приглашения.
+// Если пользователь уже участник, приглашение не проверяется и не расходуется.
+func (s *ChatService) JoinByInvite(token, userID string) (*model.Chat, error) {
+	invite, err := s.chatRepository.GetInviteByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	isParticipant, err := s.chatRepository.IsParticipant(invite.ChatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isParticipant {
+		return s.chatRepository.GetChatByID(invite.ChatID)
+	}
+
+	chat, err := s.chatRepository.GetChatByID(invite.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case invite.IsRevoked():
+		return nil, ErrInviteRevoked
+	case invite.IsExpired(s.now()):
+		return nil, ErrInviteExpired
+	case invite.IsExhausted():
+		return nil, ErrInviteExhausted
+	}
+
+	isBanned, err := s.chatRepository.IsBanned(invite.ChatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBanned {
+		return nil, ErrBanned
+	}
+
+	participant := &model.Participant{
+		ChatID:   invite.ChatID,
+		UserID:   userID,
+		Role:     model.RoleMember,
+		JoinedAt: s.now(),
+	}
+
+	if err := s.chatRepository.RedeemInvite(invite, participant); err != nil {
+		return nil, err
+	}
+
+	// Приглашение заменяет заявку, если она была подана
+	if err := s.chatRepository.DeleteJoinRequest(invite.ChatID, userID); err != nil && !errors.Is(err, repository.ErrJoinRequestNotFound) {
+		return nil, err
+	}
+
+	if err := s.participantJoined(invite.ChatID, userID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.chatRepository.GetChatByID(invite.ChatID)
+}
+
+// ListJoinRequests возвращает заявки на вступление. Доступно администраторам чата.
+func (s *ChatService) ListJoinRequests(chatID, userID string) ([]*model.JoinRequest, error) {
+	if _, err := s.requireRank(chatID, userID, rankAdmin); err != nil {
+		return nil, err
+	}
+
+	return s.chatRepository.GetJoinRequests(chatID)
+}
+
+// ApproveJoinRequest принимает пользователя по его заявке. Доступно
+// администраторам чата; лимит участников проверяется при добавлении.
+func (s *ChatService) ApproveJoinRequest(chatID, actorID, userID string) error {
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	isBanned, err := s.chatRepository.IsBanned(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if isBanned {
+		// Заявка забаненного пользователя больше не нужна
+		if err := s.chatRepository.DeleteJoinRequest(chatID, userID); err != nil && !errors.Is(err, repository.ErrJoinRequestNotFound) {
+			return err
+		}
+		return ErrBanned
+	}
+
+	requests, err := s.chatRepository.GetJoinRequests(chatID)
+	if err != nil {
+		return err
+	}
+
+	if !hasJoinRequest(requests, userID) {
+		return repository.ErrJoinRequestNotFound
+	}
+
+	participant := &model.Participant{
+		ChatID:   chatID,
+		UserID:   userID,
+		Role:     model.RoleMember,
+		JoinedAt: s.now(),
+	}
+
+	if err := s.chatRepository.AddParticipant(participant); err != nil {
+		return err
+	}
+
+	if err := s.chatRepository.DeleteJoinRequest(chatID, userID); err != nil && !errors.Is(err, repository.ErrJoinRequestNotFound) {
+		return err
+	}
+
+	return s.participantJoined(chatID, actorID, userID)
+}
+
+// RejectJoinRequest отклоняет заявку на вступление. Доступно администраторам чата.
+func (s *ChatService) RejectJoinRequest(chatID, actorID, userID string) error {
+	if _, err := s.requireRank(chatID, actorID, rankAdmin); err != nil {
+		return err
+	}
+
+	return s.chatRepository.DeleteJoinRequest(chatID, userID)
+}
+
+// requestToJoin регистрирует заявку на вступление в закрытый чат
+func (s *ChatService) requestToJoin(chatID, userID string) error {
+	request := &model.JoinRequest{
+		ChatID:    chatID,
+		UserID:    userID,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.chatRepository.CreateJoinRequest(request); err != nil {
+		return err
+	}
+
+	return ErrJoinRequestPending
+}
+
+// requireRank возвращает чат, если ранг пользователя в нем не ниже minRank
+func (s *ChatService) requireRank(chatID, userID string, minRank int) (*model.Chat, error) {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// В личном чате нет администраторов: переименование, приглашения
+	// и модерация недоступны
+	if chat.IsDirect() {
+		return nil, ErrDirectChat
+	}
+
+	if participantRank(chat, userID) < minRank {
+		return nil, ErrPermissionDenied
+	}
+
+	return chat, nil
+}
+
+func hasJoinRequest(requests []*model.JoinRequest, userID string) bool {
+	for _, request := range requests {
+		if request.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// newInviteToken генерирует случайный токен, пригодный для ссылки
+func newInviteToken() (string, error) {
+	buf := make([]byte, inviteTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
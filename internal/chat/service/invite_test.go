@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_PrivateChatJoinRequests(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "secret", CreatedBy: "owner", Participants: []string{"alice"}, IsPrivate: true})
+
+	if err := s.ConnectChat(chat.ID, "bob"); !errors.Is(err, ErrJoinRequestPending) {
+		t.Fatalf("Expected ErrJoinRequestPending, got %v", err)
+	}
+	if _, err := s.SubscribeChat(chat.ID, "bob"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected pending user to stay outside, got %v", err)
+	}
+
+	if _, err := s.ListJoinRequests(chat.ID, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected member to be unable to list requests, got %v", err)
+	}
+
+	requests, err := s.ListJoinRequests(chat.ID, "owner")
+	if err != nil || len(requests) != 1 || requests[0].UserID != "bob" {
+		t.Fatalf("Unexpected join requests: %v, %v", requests, err)
+	}
+
+	if err := s.ApproveJoinRequest(chat.ID, "owner", "bob"); err != nil {
+		t.Fatalf("ApproveJoinRequest failed: %v", err)
+	}
+	if err := s.ConnectChat(chat.ID, "bob"); err != nil {
+		t.Errorf("Expected approved user to be a participant, got %v", err)
+	}
+
+	if err := s.ApproveJoinRequest(chat.ID, "owner", "carol"); !errors.Is(err, repository.ErrJoinRequestNotFound) {
+		t.Errorf("Expected ErrJoinRequestNotFound, got %v", err)
+	}
+
+	s.ConnectChat(chat.ID, "carol")
+	if err := s.RejectJoinRequest(chat.ID, "owner", "carol"); err != nil {
+		t.Fatalf("RejectJoinRequest failed: %v", err)
+	}
+	if requests, _ := s.ListJoinRequests(chat.ID, "owner"); len(requests) != 0 {
+		t.Errorf("Expected no pending requests, got %d", len(requests))
+	}
+}
+
+func TestChatService_Invites(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "secret", CreatedBy: "owner", Participants: []string{"alice"}, IsPrivate: true})
+
+	if _, err := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "alice"}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected member to be unable to invite, got %v", err)
+	}
+
+	invite, err := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "owner", MaxUses: 1})
+	if err != nil {
+		t.Fatalf("CreateInvite failed: %v", err)
+	}
+
+	if _, err := s.JoinByInvite(invite.Token, "bob"); err != nil {
+		t.Fatalf("JoinByInvite failed: %v", err)
+	}
+	// Повторный вход участника не тратит использование
+	if _, err := s.JoinByInvite(invite.Token, "bob"); err != nil {
+		t.Errorf("Expected participant to re-use invite, got %v", err)
+	}
+	if _, err := s.JoinByInvite(invite.Token, "carol"); !errors.Is(err, ErrInviteExhausted) {
+		t.Errorf("Expected ErrInviteExhausted, got %v", err)
+	}
+
+	expiring, _ := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "owner", ExpiresIn: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	if _, err := s.JoinByInvite(expiring.Token, "carol"); !errors.Is(err, ErrInviteExpired) {
+		t.Errorf("Expected ErrInviteExpired, got %v", err)
+	}
+
+	revoked, _ := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "owner"})
+	if err := s.RevokeInvite(chat.ID, "owner", revoked.Token); err != nil {
+		t.Fatalf("RevokeInvite failed: %v", err)
+	}
+	if _, err := s.JoinByInvite(revoked.Token, "carol"); !errors.Is(err, ErrInviteRevoked) {
+		t.Errorf("Expected ErrInviteRevoked, got %v", err)
+	}
+
+	if _, err := s.JoinByInvite("unknown", "carol"); !errors.Is(err, repository.ErrInviteNotFound) {
+		t.Errorf("Expected ErrInviteNotFound, got %v", err)
+	}
+}
+
+func TestChatService_MaxParticipants(t *testing.T) {
+	s := newTestChatService()
+
+	if _, err := s.CreateChat(CreateChatInput{Name: "tiny", CreatedBy: "owner", Participants: []string{"alice", "bob"}, MaxParticipants: 2}); !errors.Is(err, repository.ErrChatFull) {
+		t.Errorf("Expected ErrChatFull on create, got %v", err)
+	}
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "tiny", CreatedBy: "owner", Participants: []string{"alice"}, MaxParticipants: 2})
+	if err := s.ConnectChat(chat.ID, "bob"); !errors.Is(err, repository.ErrChatFull) {
+		t.Errorf("Expected ErrChatFull on connect, got %v", err)
+	}
+
+	invite, _ := s.CreateInvite(CreateInviteInput{ChatID: chat.ID, UserID: "owner", MaxUses: 1})
+	if _, err := s.JoinByInvite(invite.Token, "bob"); !errors.Is(err, repository.ErrChatFull) {
+		t.Errorf("Expected ErrChatFull on invite, got %v", err)
+	}
+
+	// Неудачная попытка не тратит использование приглашения
+	s.KickParticipant(chat.ID, "owner", "alice")
+	if _, err := s.JoinByInvite(invite.Token, "bob"); err != nil {
+		t.Errorf("Expected invite to remain usable, got %v", err)
+	}
+}
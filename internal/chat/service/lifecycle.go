@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+
+	"golang-chat/internal/chat/model"
+)
+
+var (
+	ErrChatArchived    = errors.New("chat is archived")
+	ErrLastParticipant = errors.New("owner is the last participant; delete the chat instead")
+)
+
+// requireActive возвращает ErrChatArchived для архивного чата:
+// в нем нельзя писать, менять сообщения и состав участников
+func requireActive(chat *model.Chat) error {
+	if chat.IsArchived() {
+		return ErrChatArchived
+	}
+	return nil
+}
+
+// LeaveChat выводит пользователя из чата. Если уходит владелец, владение
+// переходит к участнику с наивысшей ролью, а среди равных - к тому,
+// кто присоединился раньше. Последний участник покинуть чат не может:
+// его нужно удалить через DeleteChat.
+func (s *ChatService) LeaveChat(chatID, userID string) error {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if chat.IsDirect() {
+		return ErrDirectChat
+	}
+
+	if chat.IsOwner(userID) {
+		successor := nextOwner(chat, userID)
+		if successor == nil {
+			return ErrLastParticipant
+		}
+
+		if err := s.chatRepository.TransferOwnership(chatID, successor.UserID); err != nil {
+			return err
+		}
+
+		s.publish(model.EventParticipantRole, chatID, successor.UserID, nil)
+		metadata := &model.Metadata{Event: model.SystemEventOwnership, TargetUserID: successor.UserID}
+		if err := s.postSystemMessage(chatID, userID, metadata); err != nil {
+			return err
+		}
+	}
+
+	if err := s.chatRepository.RemoveParticipant(chatID, userID); err != nil {
+		return err
+	}
+
+	s.hub.removeUser(chatID, userID, ErrLeftChat)
+	s.stopTyping(chatID, userID)
+	s.publish(model.EventParticipantLeft, chatID, userID, nil)
+
+	return s.postSystemMessage(chatID, userID, &model.Metadata{Event: model.SystemEventLeft})
+}
+
+// nextOwner выбирает преемника владельца ownerID (nil, если других участников нет)
+func nextOwner(chat *model.Chat, ownerID string) *model.Participant {
+	var successor *model.Participant
+	for _, participant := range chat.Participants {
+		if participant.UserID == ownerID {
+			continue
+		}
+
+		if successor == nil {
+			successor = participant
+			continue
+		}
+
+		rank, best := roleRank(participant.Role), roleRank(successor.Role)
+		if rank > best || rank == best && participant.JoinedAt.Before(successor.JoinedAt) {
+			successor = participant
+		}
+	}
+	return successor
+}
+
+// DeleteChat удаляет чат со всеми сообщениями. Доступно только владельцу.
+// Подписчики получают событие chat.deleted, после чего их подписки закрываются.
+func (s *ChatService) DeleteChat(chatID, actorID string) error {
+	chat, err := s.requireParticipant(chatID, actorID)
+	if err != nil {
+		return err
+	}
+
+	if chat.IsDirect() {
+		return ErrDirectChat
+	}
+
+	if !chat.IsOwner(actorID) {
+		return ErrPermissionDenied
+	}
+
+	if err := s.chatRepository.DeleteChat(chatID); err != nil {
+		return err
+	}
+
+	s.publish(model.EventChatDeleted, chatID, actorID, nil)
+	s.hub.removeChat(chatID, ErrChatDeleted)
+	return nil
+}
+
+// ArchiveChat переносит чат в архив (archived = true) или возвращает из него.
+// Архивный чат доступен только для чтения и скрыт из ListMyChats по умолчанию.
+// Доступно администраторам чата.
+func (s *ChatService) ArchiveChat(chatID, actorID string, archived bool) (*model.Chat, error) {
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.IsArchived() == archived {
+		return chat, nil
+	}
+
+	event := model.SystemEventUnarchived
+	chat.ArchivedAt = nil
+	if archived {
+		now := s.now()
+		event = model.SystemEventArchived
+		chat.ArchivedAt = &now
+	}
+
+	if err := s.chatRepository.UpdateChat(chat, "ArchivedAt"); err != nil {
+		return nil, err
+	}
+
+	if err := s.postSystemMessage(chatID, actorID, &model.Metadata{Event: event}); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
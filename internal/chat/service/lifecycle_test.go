@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_LeaveChat(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+	if err := s.SetParticipantRole(chat.ID, "owner", "bob", model.RoleModerator); err != nil {
+		t.Fatalf("SetParticipantRole failed: %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	if err := s.LeaveChat(chat.ID, "owner"); err != nil {
+		t.Fatalf("LeaveChat failed: %v", err)
+	}
+	for range sub.Events() {
+	}
+	if !errors.Is(sub.Err(), ErrLeftChat) {
+		t.Errorf("Expected subscription to be closed with ErrLeftChat, got %v", sub.Err())
+	}
+
+	// Модератор bob старше участника alice и становится владельцем
+	updated, _ := s.chatRepository.GetChatByID(chat.ID)
+	if !updated.IsOwner("bob") || findParticipant(updated, "bob").Role != model.RoleAdmin {
+		t.Errorf("Expected bob to become the owner, got %+v", updated)
+	}
+	if findParticipant(updated, "owner") != nil {
+		t.Error("Expected owner to leave the chat")
+	}
+
+	messages, _, _ := s.GetMessages("bob", repository.MessageQuery{ChatID: chat.ID})
+	last := messages[len(messages)-2:]
+	if last[0].Metadata.Event != model.SystemEventOwnership || last[0].Metadata.TargetUserID != "bob" || last[1].Metadata.Event != model.SystemEventLeft {
+		t.Errorf("Expected ownership and left system messages, got %q and %q", last[0].Content, last[1].Content)
+	}
+
+	if err := s.LeaveChat(chat.ID, "alice"); err != nil {
+		t.Fatalf("LeaveChat failed: %v", err)
+	}
+	if err := s.LeaveChat(chat.ID, "alice"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+	if err := s.LeaveChat(chat.ID, "bob"); !errors.Is(err, ErrLastParticipant) {
+		t.Errorf("Expected ErrLastParticipant, got %v", err)
+	}
+
+	direct, _, _ := s.OpenDirectChat(context.Background(), "alice", "bob")
+	if err := s.LeaveChat(direct.ID, "alice"); !errors.Is(err, ErrDirectChat) {
+		t.Errorf("Expected ErrDirectChat, got %v", err)
+	}
+}
+
+func TestChatService_DeleteChat(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hello"}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	if err := s.DeleteChat(chat.ID, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for non-owner, got %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	if err := s.DeleteChat(chat.ID, "owner"); err != nil {
+		t.Fatalf("DeleteChat failed: %v", err)
+	}
+
+	event := <-sub.Events()
+	if event == nil || event.Type != model.EventChatDeleted {
+		t.Errorf("Expected chat.deleted event, got %+v", event)
+	}
+	for range sub.Events() {
+	}
+	if !errors.Is(sub.Err(), ErrChatDeleted) {
+		t.Errorf("Expected subscription to be closed with ErrChatDeleted, got %v", sub.Err())
+	}
+
+	if _, _, err := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID}); !errors.Is(err, repository.ErrChatNotFound) {
+		t.Errorf("Expected ErrChatNotFound, got %v", err)
+	}
+	if summaries, _ := s.ListMyChats(context.Background(), "alice", true); len(summaries) != 0 {
+		t.Errorf("Expected no chats after delete, got %d", len(summaries))
+	}
+}
+
+func TestChatService_ArchiveChat(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	message, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hello"})
+
+	if _, err := s.ArchiveChat(chat.ID, "alice", true); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for member, got %v", err)
+	}
+
+	archived, err := s.ArchiveChat(chat.ID, "owner", true)
+	if err != nil {
+		t.Fatalf("ArchiveChat failed: %v", err)
+	}
+	if !archived.IsArchived() {
+		t.Error("Expected chat to be archived")
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "still here?"}); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on send, got %v", err)
+	}
+	if _, err := s.EditMessage(message.ID, "alice", "edited"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on edit, got %v", err)
+	}
+	if _, err := s.AddReaction(message.ID, "owner", "👍"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on reaction, got %v", err)
+	}
+	if err := s.ConnectChat(chat.ID, "bob"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on join, got %v", err)
+	}
+	if err := s.KickParticipant(chat.ID, "owner", "alice"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on kick, got %v", err)
+	}
+	if err := s.BanParticipant(chat.ID, "owner", "bob", ""); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on ban, got %v", err)
+	}
+	if err := s.UnbanParticipant(chat.ID, "owner", "bob"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on unban, got %v", err)
+	}
+	if err := s.SetParticipantRole(chat.ID, "owner", "alice", model.RoleModerator); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on role change, got %v", err)
+	}
+	if err := s.TransferOwnership(chat.ID, "owner", "alice"); !errors.Is(err, ErrChatArchived) {
+		t.Errorf("Expected ErrChatArchived on ownership transfer, got %v", err)
+	}
+
+	// Читать архив можно
+	messages, _, err := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if last := messages[len(messages)-1]; last.Metadata == nil || last.Metadata.Event != model.SystemEventArchived {
+		t.Errorf("Expected archived system message, got %q", last.Content)
+	}
+	if err := s.MarkRead(chat.ID, "alice", messages[len(messages)-1].ID); err != nil {
+		t.Errorf("Expected MarkRead to work in an archived chat, got %v", err)
+	}
+
+	if summaries, _ := s.ListMyChats(ctx, "alice", false); len(summaries) != 0 {
+		t.Errorf("Expected archived chat to be hidden, got %d chats", len(summaries))
+	}
+	if summaries, _ := s.ListMyChats(ctx, "alice", true); len(summaries) != 1 {
+		t.Errorf("Expected archived chat with include_archived, got %d chats", len(summaries))
+	}
+
+	if _, err := s.ArchiveChat(chat.ID, "owner", false); err != nil {
+		t.Fatalf("ArchiveChat(false) failed: %v", err)
+	}
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "back"}); err != nil {
+		t.Errorf("Expected SendMessage after unarchive, got %v", err)
+	}
+	if summaries, _ := s.ListMyChats(ctx, "alice", false); len(summaries) != 1 {
+		t.Errorf("Expected unarchived chat to be listed, got %d chats", len(summaries))
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	defaultMentionsLimit = 20
+	maxMentionsLimit     = 100
+)
+
+// mentionPattern находит @username (буквы, цифры и _ как в Auth Service).
+// Перед @ не должно быть символов слова, поэтому адреса почты не считаются упоминаниями.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]+)`)
+
+// parseMentions возвращает упомянутые имена в нижнем регистре и признак @all
+func parseMentions(content string) (map[string]bool, bool) {
+	usernames := map[string]bool{}
+	all := false
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.ToLower(match[1])
+		if username == model.MentionAll {
+			all = true
+			continue
+		}
+		usernames[username] = true
+	}
+
+	return usernames, all
+}
+
+// resolveMentions сопоставляет упоминания в content участникам чата, кроме
+// автора. Имена, не принадлежащие участникам, игнорируются. Если справочник
+// пользователей недоступен, участник считается неупомянутым: упоминания не
+// должны мешать отправке сообщения. Имена участников справочник кэширует,
+// перебор останавливается, как только найдены все упомянутые.
+func (s *ChatService) resolveMentions(ctx context.Context, chat *model.Chat, authorID, content string) []string {
+	usernames, all := parseMentions(content)
+	if !all && len(usernames) == 0 {
+		return nil
+	}
+
+	var mentioned []string
+	remaining := len(usernames)
+
+	for _, participant := range chat.Participants {
+		if participant.UserID == authorID {
+			continue
+		}
+
+		if all {
+			mentioned = append(mentioned, participant.UserID)
+			continue
+		}
+
+		username, err := s.users.Username(ctx, participant.UserID)
+		if err != nil || !usernames[strings.ToLower(username)] {
+			continue
+		}
+
+		mentioned = append(mentioned, participant.UserID)
+		if remaining--; remaining == 0 {
+			break
+		}
+	}
+
+	return mentioned
+}
+
+// fillMentions заполняет Mentions у неудаленных сообщений
+func (s *ChatService) fillMentions(messages []*model.Message) error {
+	var messageIDs []string
+	for _, message := range messages {
+		if !message.IsDeleted() {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	mentions, err := s.chatRepository.GetMentions(messageIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		message.Mentions = mentions[message.ID]
+	}
+
+	return nil
+}
+
+// ListMentions возвращает сообщения, в которых упомянут query.UserID, от новых
+// к старым, с признаком непрочитанности. Если указан query.ChatID,
+// пользователь должен быть его участником. Второй результат сообщает,
+// есть ли следующая страница.
+func (s *ChatService) ListMentions(query repository.MentionQuery) ([]*repository.UserMention, bool, error) {
+	if query.ChatID != "" {
+		if _, err := s.requireParticipant(query.ChatID, query.UserID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultMentionsLimit
+	}
+	query.Limit = min(query.Limit, maxMentionsLimit)
+	query.Offset = max(query.Offset, 0)
+
+	// Запрашиваем на одно упоминание больше, чтобы узнать, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+
+	mentions, err := s.chatRepository.GetUserMentions(query)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(mentions) > limit
+	if hasMore {
+		mentions = mentions[:limit]
+	}
+
+	messages := make([]*model.Message, len(mentions))
+	for i, mention := range mentions {
+		messages[i] = mention.Message
+	}
+
+	if err := s.fillMentions(messages); err != nil {
+		return nil, false, err
+	}
+
+	return mentions, hasMore, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := []struct {
+		content   string
+		usernames []string
+		all       bool
+	}{
+		{"hi @Alice and @bob", []string{"alice", "bob"}, false},
+		{"@alice, @alice!", []string{"alice"}, false},
+		{"write to alice@example.com", nil, false},
+		{"@@alice", nil, false},
+		{"@ALL deploy", nil, true},
+		{"no mentions", nil, false},
+	}
+
+	for _, c := range cases {
+		usernames, all := parseMentions(c.content)
+		if all != c.all {
+			t.Errorf("%q: expected all=%v, got %v", c.content, c.all, all)
+		}
+		if len(usernames) != len(c.usernames) {
+			t.Errorf("%q: expected %v, got %v", c.content, c.usernames, usernames)
+			continue
+		}
+		for _, username := range c.usernames {
+			if !usernames[username] {
+				t.Errorf("%q: expected %q to be mentioned, got %v", c.content, username, usernames)
+			}
+		}
+	}
+}
+
+func TestChatService_SendMessageMentions(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	// bob не участник, автор не упоминает сам себя
+	message, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "@alice @bob @owner look"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if !slices.Equal(message.Mentions, []string{"alice"}) {
+		t.Errorf("Expected mentions [alice], got %v", message.Mentions)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	defer sub.Close()
+
+	message, _ = s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "@all release"})
+	if !slices.Equal(message.Mentions, []string{"owner"}) {
+		t.Errorf("Expected @all to mention owner, got %v", message.Mentions)
+	}
+
+	var types []string
+	for event := nextEvent(sub); event != nil; event = nextEvent(sub) {
+		types = append(types, event.Type)
+	}
+	if !slices.Contains(types, model.EventMessageMentioned) {
+		t.Errorf("Expected %s event, got %v", model.EventMessageMentioned, types)
+	}
+
+	plain, _ := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "no mentions"})
+	if plain.Mentions != nil {
+		t.Errorf("Expected no mentions, got %v", plain.Mentions)
+	}
+	for event := nextEvent(sub); event != nil; event = nextEvent(sub) {
+		if event.Type == model.EventMessageMentioned {
+			t.Errorf("Unexpected %s event for a message without mentions", event.Type)
+		}
+	}
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if !slices.Equal(messages[0].Mentions, []string{"alice"}) || !slices.Equal(messages[1].Mentions, []string{"owner"}) {
+		t.Errorf("Expected mentions to be stored, got %v and %v", messages[0].Mentions, messages[1].Mentions)
+	}
+}
+
+func TestChatService_ListMentions(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	general, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	random, _ := s.CreateChat(CreateChatInput{Name: "random", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	first, _ := s.SendMessage(ctx, SendMessageInput{ChatID: general.ID, UserID: "owner", Content: "@alice first"})
+	second, _ := s.SendMessage(ctx, SendMessageInput{ChatID: general.ID, UserID: "owner", Content: "@alice second"})
+	other, _ := s.SendMessage(ctx, SendMessageInput{ChatID: random.ID, UserID: "owner", Content: "@all other"})
+
+	mentions, hasMore, err := s.ListMentions(repository.MentionQuery{UserID: "alice"})
+	if err != nil {
+		t.Fatalf("ListMentions failed: %v", err)
+	}
+	if hasMore || len(mentions) != 3 {
+		t.Fatalf("Expected 3 mentions, got %d (hasMore=%v)", len(mentions), hasMore)
+	}
+	if mentions[0].Message.ID != other.ID || mentions[2].Message.ID != first.ID {
+		t.Errorf("Expected newest first, got %s, %s, %s", mentions[0].Message.ID, mentions[1].Message.ID, mentions[2].Message.ID)
+	}
+	for _, mention := range mentions {
+		if !mention.Unread {
+			t.Errorf("Expected %s to be unread", mention.Message.ID)
+		}
+	}
+
+	if err := s.MarkRead(general.ID, "alice", first.ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+
+	unread, _, _ := s.ListMentions(repository.MentionQuery{UserID: "alice", ChatID: general.ID, UnreadOnly: true})
+	if len(unread) != 1 || unread[0].Message.ID != second.ID {
+		t.Errorf("Expected only the second mention to be unread, got %d", len(unread))
+	}
+
+	page, hasMore, _ := s.ListMentions(repository.MentionQuery{UserID: "alice", Limit: 2})
+	if len(page) != 2 || !hasMore {
+		t.Errorf("Expected a page of 2 with more, got %d (hasMore=%v)", len(page), hasMore)
+	}
+
+	// Удаленное сообщение из упоминаний пропадает
+	if _, err := s.DeleteMessage(second.ID, "owner"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+	mentions, _, _ = s.ListMentions(repository.MentionQuery{UserID: "alice", ChatID: general.ID})
+	if len(mentions) != 1 || mentions[0].Message.ID != first.ID || mentions[0].Unread {
+		t.Errorf("Expected only the read first mention, got %d", len(mentions))
+	}
+
+	if _, _, err := s.ListMentions(repository.MentionQuery{UserID: "bob", ChatID: general.ID}); err != ErrNotParticipant {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+}
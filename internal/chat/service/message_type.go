@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang-chat/internal/chat/model"
+)
+
+const (
+	maxFileNameLength = 255
+	maxImageDimension = 20000
+)
+
+var (
+	ErrInvalidMessageType  = errors.New("unknown message type")
+	ErrSystemMessage       = errors.New("system messages can only be created by the server")
+	ErrInvalidMetadata     = errors.New("invalid message metadata")
+	ErrUnexpectedMetadata  = errors.New("text messages do not accept metadata")
+	ErrSystemMessageChange = errors.New("system messages cannot be edited or deleted")
+)
+
+// validateMessage проверяет, что содержимое и метаданные соответствуют типу
+// сообщения, отправленного клиентом
+func validateMessage(messageType, content string, metadata *model.Metadata) error {
+	switch messageType {
+	case model.MessageTypeText, model.MessageTypeAction:
+		if strings.TrimSpace(content) == "" {
+			return ErrEmptyContent
+		}
+		if metadata != nil && *metadata != (model.Metadata{}) {
+			return ErrUnexpectedMetadata
+		}
+		return nil
+	case model.MessageTypeImage:
+		if err := validateFileMetadata(metadata); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(metadata.MimeType, "image/") {
+			return invalidMetadata("mime_type must be an image type")
+		}
+		if metadata.Width <= 0 || metadata.Height <= 0 || metadata.Width > maxImageDimension || metadata.Height > maxImageDimension {
+			return invalidMetadata("width and height must be between 1 and %d", maxImageDimension)
+		}
+		return nil
+	case model.MessageTypeFile:
+		if err := validateFileMetadata(metadata); err != nil {
+			return err
+		}
+		if metadata.Width != 0 || metadata.Height != 0 {
+			return invalidMetadata("width and height are only allowed for images")
+		}
+		return nil
+	case model.MessageTypePoll:
+		if strings.TrimSpace(content) == "" {
+			return ErrEmptyContent
+		}
+		if utf8.RuneCountInString(content) > maxPollQuestionLength {
+			return invalidPoll("question must be at most %d characters", maxPollQuestionLength)
+		}
+		if metadata != nil && *metadata != (model.Metadata{}) {
+			return invalidMetadata("polls do not accept metadata")
+		}
+		return nil
+	case model.MessageTypeCommand:
+		return ErrCommandMessage
+	case model.MessageTypeSystem:
+		return ErrSystemMessage
+	default:
+		return ErrInvalidMessageType
+	}
+}
+
+// validateFileMetadata проверяет поля, общие для файлов и изображений
+func validateFileMetadata(metadata *model.Metadata) error {
+	if metadata == nil {
+		return invalidMetadata("file_name, file_size and mime_type are required")
+	}
+
+	if metadata.Event != "" || metadata.TargetUserID != "" || metadata.Role != "" || metadata.OldName != "" || metadata.NewName != "" ||
+		metadata.Topic != "" || metadata.Command != nil {
+		return invalidMetadata("system fields are not allowed")
+	}
+
+	if !validFileName(metadata.FileName) {
+		return invalidMetadata("file_name must be a plain name up to %d characters", maxFileNameLength)
+	}
+
+	if metadata.FileSize <= 0 {
+		return invalidMetadata("file_size must be positive")
+	}
+
+	if _, _, err := mime.ParseMediaType(metadata.MimeType); err != nil || !strings.Contains(metadata.MimeType, "/") {
+		return invalidMetadata("mime_type is not a valid media type")
+	}
+
+	return nil
+}
+
+// validFileName проверяет, что имя файла непустое, не слишком длинное
+// и не содержит путей
+func validFileName(name string) bool {
+	name = strings.TrimSpace(name)
+	return name != "" && utf8.RuneCountInString(name) <= maxFileNameLength && !strings.ContainsAny(name, "/\\\x00")
+}
+
+func invalidMetadata(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMetadata, fmt.Sprintf(format, args...))
+}
+
+// systemMessageText возвращает текст системного сообщения для клиентов,
+// которые не разбирают метаданные
+func systemMessageText(actorID string, metadata *model.Metadata) string {
+	switch metadata.Event {
+	case model.SystemEventJoined:
+		if metadata.TargetUserID != actorID {
+			return fmt.Sprintf("%s added %s to the chat", actorID, metadata.TargetUserID)
+		}
+		return fmt.Sprintf("%s joined the chat", actorID)
+	case model.SystemEventLeft:
+		return fmt.Sprintf("%s left the chat", actorID)
+	case model.SystemEventKicked:
+		return fmt.Sprintf("%s removed %s from the chat", actorID, metadata.TargetUserID)
+	case model.SystemEventBanned:
+		return fmt.Sprintf("%s banned %s", actorID, metadata.TargetUserID)
+	case model.SystemEventUnbanned:
+		return fmt.Sprintf("%s unbanned %s", actorID, metadata.TargetUserID)
+	case model.SystemEventRoleChanged:
+		return fmt.Sprintf("%s changed role of %s to %s", actorID, metadata.TargetUserID, metadata.Role)
+	case model.SystemEventOwnership:
+		return fmt.Sprintf("%s transferred ownership to %s", actorID, metadata.TargetUserID)
+	case model.SystemEventRenamed:
+		return fmt.Sprintf("%s renamed the chat to %q", actorID, metadata.NewName)
+	case model.SystemEventArchived:
+		return fmt.Sprintf("%s archived the chat", actorID)
+	case model.SystemEventUnarchived:
+		return fmt.Sprintf("%s restored the chat from the archive", actorID)
+	case model.SystemEventTopic:
+		if metadata.Topic == "" {
+			return fmt.Sprintf("%s cleared the topic", actorID)
+		}
+		return fmt.Sprintf("%s changed the topic to %q", actorID, metadata.Topic)
+	default:
+		return metadata.Event
+	}
+}
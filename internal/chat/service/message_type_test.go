@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestValidateMessage(t *testing.T) {
+	image := func(modify func(m *model.Metadata)) *model.Metadata {
+		m := &model.Metadata{FileName: "cat.png", FileSize: 1024, MimeType: "image/png", Width: 640, Height: 480}
+		if modify != nil {
+			modify(m)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name        string
+		messageType string
+		content     string
+		metadata    *model.Metadata
+		wantErr     error
+	}{
+		{"text", model.MessageTypeText, "hello", nil, nil},
+		{"empty text", model.MessageTypeText, "  ", nil, ErrEmptyContent},
+		{"text with metadata", model.MessageTypeText, "hello", &model.Metadata{FileName: "a.txt"}, ErrUnexpectedMetadata},
+		{"image", model.MessageTypeImage, "", image(nil), nil},
+		{"image without metadata", model.MessageTypeImage, "", nil, ErrInvalidMetadata},
+		{"image without dimensions", model.MessageTypeImage, "", image(func(m *model.Metadata) { m.Width = 0 }), ErrInvalidMetadata},
+		{"image with non-image mime", model.MessageTypeImage, "", image(func(m *model.Metadata) { m.MimeType = "application/pdf" }), ErrInvalidMetadata},
+		{"file", model.MessageTypeFile, "report", &model.Metadata{FileName: "report.pdf", FileSize: 10, MimeType: "application/pdf"}, nil},
+		{"file with path", model.MessageTypeFile, "", &model.Metadata{FileName: "../etc/passwd", FileSize: 10, MimeType: "text/plain"}, ErrInvalidMetadata},
+		{"file with zero size", model.MessageTypeFile, "", &model.Metadata{FileName: "a.txt", MimeType: "text/plain"}, ErrInvalidMetadata},
+		{"file with bad mime", model.MessageTypeFile, "", &model.Metadata{FileName: "a.txt", FileSize: 1, MimeType: "text"}, ErrInvalidMetadata},
+		{"file with dimensions", model.MessageTypeFile, "", image(func(m *model.Metadata) { m.MimeType = "text/plain" }), ErrInvalidMetadata},
+		{"file with system fields", model.MessageTypeFile, "", &model.Metadata{FileName: "a.txt", FileSize: 1, MimeType: "text/plain", Event: "joined"}, ErrInvalidMetadata},
+		{"system", model.MessageTypeSystem, "hacked", nil, ErrSystemMessage},
+		{"unknown", "video", "", nil, ErrInvalidMessageType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMessage(tt.messageType, tt.content, tt.metadata)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestChatService_SendImage(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+
+	metadata := &model.Metadata{FileName: "cat.png", FileSize: 1024, MimeType: "image/png", Width: 640, Height: 480}
+	message, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeImage, Content: "my cat", Metadata: metadata})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	// Сервис хранит свою копию метаданных
+	metadata.Width = 1
+
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	if len(messages) != 1 || messages[0].ID != message.ID || messages[0].Type != model.MessageTypeImage {
+		t.Fatalf("Unexpected messages %+v", messages)
+	}
+	if messages[0].Metadata == nil || messages[0].Metadata.Width != 640 {
+		t.Errorf("Unexpected metadata %+v", messages[0].Metadata)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Type: model.MessageTypeSystem, Content: "fake"}); !errors.Is(err, ErrSystemMessage) {
+		t.Errorf("Expected ErrSystemMessage, got %v", err)
+	}
+}
+
+func TestChatService_SystemMessages(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	if _, err := s.RenameChat(chat.ID, "alice", "mine"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected member to be unable to rename, got %v", err)
+	}
+	if _, err := s.RenameChat(chat.ID, "owner", "  "); !errors.Is(err, ErrInvalidChatName) {
+		t.Errorf("Expected ErrInvalidChatName, got %v", err)
+	}
+
+	renamed, err := s.RenameChat(chat.ID, "owner", "random")
+	if err != nil || renamed.Name != "random" {
+		t.Fatalf("RenameChat failed: %+v, %v", renamed, err)
+	}
+
+	s.ConnectChat(chat.ID, "bob")
+	s.SetParticipantRole(chat.ID, "owner", "bob", model.RoleModerator)
+
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	expected := []model.Metadata{
+		{Event: model.SystemEventRenamed, OldName: "general", NewName: "random"},
+		{Event: model.SystemEventJoined, TargetUserID: "bob"},
+		{Event: model.SystemEventRoleChanged, TargetUserID: "bob", Role: model.RoleModerator},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d system messages, got %d", len(expected), len(messages))
+	}
+
+	for i, message := range messages {
+		if message.Type != model.MessageTypeSystem || message.Metadata == nil || *message.Metadata != expected[i] {
+			t.Errorf("Message %d: expected %+v, got %+v", i, expected[i], message.Metadata)
+		}
+		if message.Content == "" {
+			t.Errorf("Message %d has no fallback text", i)
+		}
+	}
+
+	if _, err := s.DeleteMessage(messages[0].ID, "owner"); !errors.Is(err, ErrSystemMessageChange) {
+		t.Errorf("Expected ErrSystemMessageChange, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"errors"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+var (
+	ErrBanned             = errors.New("user is banned in this chat")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrCannotModerateSelf = errors.New("cannot apply this action to yourself")
+)
+
+// Ранги для проверки прав: действовать можно только над участниками
+// с рангом ниже собственного
+const (
+	rankNone = iota
+	rankMember
+	rankModerator
+	rankAdmin
+	rankOwner
+)
+
+func roleRank(role string) int {
+	switch role {
+	case model.RoleAdmin:
+		return rankAdmin
+	case model.RoleModerator:
+		return rankModerator
+	case model.RoleMember:
+		return rankMember
+	default:
+		return rankNone
+	}
+}
+
+// participantRank возвращает ранг пользователя в чате (rankNone, если он не участник)
+func participantRank(chat *model.Chat, userID string) int {
+	participant := findParticipant(chat, userID)
+	if participant == nil {
+		return rankNone
+	}
+
+	if chat.IsOwner(userID) {
+		return rankOwner
+	}
+
+	return roleRank(participant.Role)
+}
+
+func findParticipant(chat *model.Chat, userID string) *model.Participant {
+	for _, participant := range chat.Participants {
+		if participant.UserID == userID {
+			return participant
+		}
+	}
+	return nil
+}
+
+// moderate загружает чат и проверяет, что actorID может применить действие
+// с минимальным рангом minRank к targetID
+func (s *ChatService) moderate(chatID, actorID, targetID string, minRank int) (*model.Chat, error) {
+	chat, err := s.requireParticipant(chatID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.IsDirect() {
+		return nil, ErrDirectChat
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if actorID == targetID {
+		return nil, ErrCannotModerateSelf
+	}
+
+	actorRank := participantRank(chat, actorID)
+	if actorRank < minRank || actorRank <= participantRank(chat, targetID) {
+		return nil, ErrPermissionDenied
+	}
+
+	return chat, nil
+}
+
+// KickParticipant удаляет участника из чата. Доступно модераторам и выше
+// по отношению к участникам с меньшим рангом; повторно присоединиться можно.
+func (s *ChatService) KickParticipant(chatID, actorID, targetID string) error {
+	if _, err := s.moderate(chatID, actorID, targetID, rankModerator); err != nil {
+		return err
+	}
+
+	if err := s.chatRepository.RemoveParticipant(chatID, targetID); err != nil {
+		return err
+	}
+
+	s.hub.removeUser(chatID, targetID, ErrRemovedFromChat)
+	s.publish(model.EventParticipantLeft, chatID, targetID, nil)
+
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventKicked, TargetUserID: targetID})
+}
+
+// BanParticipant удаляет пользователя из чата и запрещает ему ConnectChat.
+// Забанить можно и того, кто еще не присоединился.
+func (s *ChatService) BanParticipant(chatID, actorID, targetID, reason string) error {
+	chat, err := s.moderate(chatID, actorID, targetID, rankModerator)
+	if err != nil {
+		return err
+	}
+
+	ban := &model.Ban{
+		ChatID:    chatID,
+		UserID:    targetID,
+		BannedBy:  actorID,
+		Reason:    reason,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.chatRepository.BanParticipant(ban); err != nil {
+		return err
+	}
+
+	if findParticipant(chat, targetID) != nil {
+		s.hub.removeUser(chatID, targetID, ErrRemovedFromChat)
+		s.publish(model.EventParticipantLeft, chatID, targetID, nil)
+	}
+
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventBanned, TargetUserID: targetID})
+}
+
+// UnbanParticipant снимает бан. Доступно модераторам и выше.
+func (s *ChatService) UnbanParticipant(chatID, actorID, targetID string) error {
+	chat, err := s.requireRank(chatID, actorID, rankModerator)
+	if err != nil {
+		return err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	if err := s.chatRepository.RemoveBan(chatID, targetID); err != nil {
+		return err
+	}
+
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventUnbanned, TargetUserID: targetID})
+}
+
+// SetParticipantRole меняет роль участника. Доступно администраторам;
+// назначить роль не ниже собственной может только владелец.
+func (s *ChatService) SetParticipantRole(chatID, actorID, targetID, role string) error {
+	if !model.ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	chat, err := s.moderate(chatID, actorID, targetID, rankAdmin)
+	if err != nil {
+		return err
+	}
+
+	target := findParticipant(chat, targetID)
+	if target == nil {
+		return repository.ErrParticipantNotFound
+	}
+
+	if roleRank(role) >= participantRank(chat, actorID) {
+		return ErrPermissionDenied
+	}
+
+	if target.Role == role {
+		return nil
+	}
+
+	if err := s.chatRepository.UpdateParticipantRole(chatID, targetID, role); err != nil {
+		return err
+	}
+
+	s.publish(model.EventParticipantRole, chatID, targetID, nil)
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventRoleChanged, TargetUserID: targetID, Role: role})
+}
+
+// TransferOwnership передает владение чатом другому участнику.
+// Новый владелец получает роль admin, прежний остается администратором.
+func (s *ChatService) TransferOwnership(chatID, actorID, newOwnerID string) error {
+	chat, err := s.requireParticipant(chatID, actorID)
+	if err != nil {
+		return err
+	}
+
+	if chat.IsDirect() {
+		return ErrDirectChat
+	}
+
+	if !chat.IsOwner(actorID) {
+		return ErrPermissionDenied
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	if actorID == newOwnerID {
+		return ErrCannotModerateSelf
+	}
+
+	if findParticipant(chat, newOwnerID) == nil {
+		return repository.ErrParticipantNotFound
+	}
+
+	if err := s.chatRepository.TransferOwnership(chatID, newOwnerID); err != nil {
+		return err
+	}
+
+	s.publish(model.EventParticipantRole, chatID, newOwnerID, nil)
+	return s.postSystemMessage(chatID, actorID, &model.Metadata{Event: model.SystemEventOwnership, TargetUserID: newOwnerID})
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_KickAndBan(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"mod", "alice", "bob"}})
+	if err := s.SetParticipantRole(chat.ID, "owner", "mod", model.RoleModerator); err != nil {
+		t.Fatalf("SetParticipantRole failed: %v", err)
+	}
+
+	if err := s.KickParticipant(chat.ID, "alice", "bob"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected member to be unable to kick, got %v", err)
+	}
+	if err := s.KickParticipant(chat.ID, "mod", "owner"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected moderator to be unable to kick owner, got %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	if err := s.KickParticipant(chat.ID, "mod", "alice"); err != nil {
+		t.Fatalf("KickParticipant failed: %v", err)
+	}
+	for range sub.Events() {
+	}
+	if !errors.Is(sub.Err(), ErrRemovedFromChat) {
+		t.Errorf("Expected kicked user's subscription to be closed, got %v", sub.Err())
+	}
+
+	// После кика можно вернуться, после бана - нет
+	if err := s.ConnectChat(chat.ID, "alice"); err != nil {
+		t.Errorf("Expected kicked user to rejoin, got %v", err)
+	}
+
+	if err := s.BanParticipant(chat.ID, "mod", "bob", "spam"); err != nil {
+		t.Fatalf("BanParticipant failed: %v", err)
+	}
+	if err := s.ConnectChat(chat.ID, "bob"); !errors.Is(err, ErrBanned) {
+		t.Errorf("Expected ErrBanned, got %v", err)
+	}
+
+	if err := s.UnbanParticipant(chat.ID, "alice", "bob"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected member to be unable to unban, got %v", err)
+	}
+	if err := s.UnbanParticipant(chat.ID, "mod", "bob"); err != nil {
+		t.Fatalf("UnbanParticipant failed: %v", err)
+	}
+	if err := s.ConnectChat(chat.ID, "bob"); err != nil {
+		t.Errorf("Expected unbanned user to rejoin, got %v", err)
+	}
+
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	// Смена роли, кик, повторное вступление, бан, разбан, повторное вступление
+	if len(messages) != 6 {
+		t.Fatalf("Expected 6 system messages, got %d", len(messages))
+	}
+	for _, message := range messages {
+		if message.Type != model.MessageTypeSystem {
+			t.Errorf("Expected system message, got %+v", message)
+		}
+	}
+}
+
+func TestChatService_RolesAndOwnership(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"admin", "alice"}})
+
+	if err := s.SetParticipantRole(chat.ID, "owner", "admin", "superuser"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+	if err := s.SetParticipantRole(chat.ID, "owner", "admin", model.RoleAdmin); err != nil {
+		t.Fatalf("SetParticipantRole failed: %v", err)
+	}
+
+	// Администратор не может назначать администраторов
+	if err := s.SetParticipantRole(chat.ID, "admin", "alice", model.RoleAdmin); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+	if err := s.SetParticipantRole(chat.ID, "admin", "alice", model.RoleModerator); err != nil {
+		t.Errorf("Expected admin to promote to moderator, got %v", err)
+	}
+
+	if err := s.TransferOwnership(chat.ID, "admin", "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected only owner to transfer ownership, got %v", err)
+	}
+	if err := s.TransferOwnership(chat.ID, "owner", "alice"); err != nil {
+		t.Fatalf("TransferOwnership failed: %v", err)
+	}
+
+	if err := s.KickParticipant(chat.ID, "alice", "owner"); err != nil {
+		t.Errorf("Expected new owner to kick previous owner, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import "golang-chat/internal/chat/model"
+
+// PinMessage закрепляет сообщение. Доступно администраторам чата;
+// число закреплений ограничено ChatServiceConfig.MaxPinnedMessages.
+func (s *ChatService) PinMessage(messageID, actorID string) (*model.Pin, error) {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := s.requireRank(message.ChatID, actorID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if message.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+
+	pin := &model.Pin{
+		ChatID:    message.ChatID,
+		MessageID: message.ID,
+		PinnedBy:  actorID,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.chatRepository.PinMessage(pin, s.config.MaxPinnedMessages); err != nil {
+		return nil, err
+	}
+
+	pin.Message = message
+	s.publish(model.EventMessagePinned, message.ChatID, actorID, message)
+	return pin, nil
+}
+
+// UnpinMessage открепляет сообщение. Доступно администраторам чата.
+func (s *ChatService) UnpinMessage(messageID, actorID string) error {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return err
+	}
+
+	chat, err := s.requireRank(message.ChatID, actorID, rankAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	if err := s.chatRepository.UnpinMessage(message.ChatID, message.ID); err != nil {
+		return err
+	}
+
+	s.publish(model.EventMessageUnpinned, message.ChatID, actorID, message)
+	return nil
+}
+
+// ListPinnedMessages возвращает закрепленные сообщения чата,
+// начиная с последнего закрепленного
+func (s *ChatService) ListPinnedMessages(chatID, userID string) ([]*model.Pin, error) {
+	if _, err := s.requireParticipant(chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.chatRepository.GetPins(chatID)
+}
+
+// SetAnnouncementMode включает или выключает режим канала объявлений.
+// Доступно администраторам чата.
+func (s *ChatService) SetAnnouncementMode(chatID, actorID string, enabled bool) (*model.Chat, error) {
+	chat, err := s.requireRank(chatID, actorID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if chat.IsAnnouncement == enabled {
+		return chat, nil
+	}
+
+	chat.IsAnnouncement = enabled
+	if err := s.chatRepository.UpdateChat(chat, "IsAnnouncement"); err != nil {
+		return nil, err
+	}
+
+	return chat, nil
+}
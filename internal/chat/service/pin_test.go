@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_PinMessage(t *testing.T) {
+	s := NewChatService(repository.NewInMemoryChatRepository(), newTestUserDirectory(), ChatServiceConfig{MaxPinnedMessages: 2})
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	var messages []*model.Message
+	for _, content := range []string{"rules", "schedule", "faq"} {
+		message, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: content})
+		messages = append(messages, message)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	defer sub.Close()
+
+	if _, err := s.PinMessage(messages[0].ID, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member, got %v", err)
+	}
+
+	pin, err := s.PinMessage(messages[0].ID, "owner")
+	if err != nil {
+		t.Fatalf("PinMessage failed: %v", err)
+	}
+	if pin.PinnedBy != "owner" || pin.Message.Content != "rules" {
+		t.Errorf("Unexpected pin: %+v", pin)
+	}
+
+	if event := nextEvent(sub); event == nil || event.Type != model.EventMessagePinned || event.Message.ID != messages[0].ID {
+		t.Errorf("Expected message.pinned, got %+v", event)
+	}
+
+	if _, err := s.PinMessage(messages[0].ID, "owner"); !errors.Is(err, repository.ErrAlreadyPinned) {
+		t.Errorf("Expected ErrAlreadyPinned, got %v", err)
+	}
+
+	s.PinMessage(messages[1].ID, "owner")
+	if _, err := s.PinMessage(messages[2].ID, "owner"); !errors.Is(err, repository.ErrPinLimit) {
+		t.Errorf("Expected ErrPinLimit, got %v", err)
+	}
+
+	pins, err := s.ListPinnedMessages(chat.ID, "alice")
+	if err != nil {
+		t.Fatalf("ListPinnedMessages failed: %v", err)
+	}
+	if len(pins) != 2 || pins[0].Message.Content != "schedule" || pins[1].Message.Content != "rules" {
+		t.Errorf("Expected newest pin first, got %+v", pins)
+	}
+
+	if _, err := s.ListPinnedMessages(chat.ID, "mallory"); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+
+	if err := s.UnpinMessage(messages[1].ID, "owner"); err != nil {
+		t.Fatalf("UnpinMessage failed: %v", err)
+	}
+	if err := s.UnpinMessage(messages[1].ID, "owner"); !errors.Is(err, repository.ErrPinNotFound) {
+		t.Errorf("Expected ErrPinNotFound, got %v", err)
+	}
+
+	// Удаление сообщения освобождает место среди закрепленных
+	s.DeleteMessage(messages[0].ID, "owner")
+	if pins, _ := s.ListPinnedMessages(chat.ID, "alice"); len(pins) != 0 {
+		t.Errorf("Expected deleted message to be unpinned, got %+v", pins)
+	}
+	if _, err := s.PinMessage(messages[0].ID, "owner"); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("Expected ErrMessageDeleted, got %v", err)
+	}
+}
+
+func TestChatService_AnnouncementMode(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "news", CreatedBy: "owner", Participants: []string{"alice", "bob"}, IsAnnouncement: true})
+	s.SetParticipantRole(chat.ID, "owner", "bob", model.RoleAdmin)
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi"}); !errors.Is(err, ErrAnnouncementOnly) {
+		t.Errorf("Expected ErrAnnouncementOnly for a member, got %v", err)
+	}
+
+	announcement, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "release today"})
+	if err != nil {
+		t.Fatalf("SendMessage by admin failed: %v", err)
+	}
+
+	// Участники по-прежнему читают и реагируют
+	if _, err := s.AddReaction(announcement.ID, "alice", "👍"); err != nil {
+		t.Errorf("AddReaction failed: %v", err)
+	}
+	if err := s.MarkRead(chat.ID, "alice", announcement.ID); err != nil {
+		t.Errorf("MarkRead failed: %v", err)
+	}
+
+	if _, err := s.SetAnnouncementMode(chat.ID, "alice", false); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied, got %v", err)
+	}
+
+	updated, err := s.SetAnnouncementMode(chat.ID, "owner", false)
+	if err != nil || updated.IsAnnouncement {
+		t.Fatalf("SetAnnouncementMode failed: %v", err)
+	}
+
+	if _, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi"}); err != nil {
+		t.Errorf("SendMessage after disabling announcement mode failed: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	minPollOptions        = 2
+	maxPollOptions        = 10
+	maxPollOptionLength   = 100
+	maxPollQuestionLength = 300
+)
+
+var (
+	ErrInvalidPoll   = errors.New("invalid poll")
+	ErrInvalidVote   = errors.New("invalid poll vote")
+	ErrNotPoll       = errors.New("message is not a poll")
+	ErrPollEdit      = errors.New("polls cannot be edited")
+	ErrScheduledPoll = errors.New("polls cannot be scheduled")
+)
+
+func invalidPoll(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPoll, fmt.Sprintf(format, args...))
+}
+
+// validatePoll проверяет опрос сообщения: он обязателен для типа poll
+// и запрещен для остальных типов
+func validatePoll(messageType string, poll *model.Poll, now time.Time) error {
+	if messageType != model.MessageTypePoll {
+		if poll != nil {
+			return invalidPoll("only poll messages accept a poll")
+		}
+		return nil
+	}
+
+	if poll == nil {
+		return invalidPoll("options are required")
+	}
+
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return invalidPoll("a poll must have %d-%d options", minPollOptions, maxPollOptions)
+	}
+
+	seen := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOptionLength {
+			return invalidPoll("options must be 1-%d characters", maxPollOptionLength)
+		}
+		if seen[option] {
+			return invalidPoll("options must be unique")
+		}
+		seen[option] = true
+	}
+
+	if poll.ClosesAt != nil && !poll.ClosesAt.After(now) {
+		return invalidPoll("closes_at must be in the future")
+	}
+
+	return nil
+}
+
+// newPoll копирует настройки опроса из SendMessageInput.Poll
+func newPoll(input *model.Poll) *model.Poll {
+	poll := &model.Poll{
+		Options:        make(model.PollOptions, len(input.Options)),
+		MultipleChoice: input.MultipleChoice,
+		Anonymous:      input.Anonymous,
+		ClosesAt:       input.ClosesAt,
+	}
+
+	for i, option := range input.Options {
+		poll.Options[i] = strings.TrimSpace(option)
+	}
+
+	tallyPoll(poll, nil, "")
+	return poll
+}
+
+// VotePoll заменяет голос пользователя в опросе на варианты options
+// (индексы в Poll.Options). В опросе с одним ответом можно выбрать
+// только один вариант. Возвращает опрос с новыми итогами.
+func (s *ChatService) VotePoll(messageID, userID string, options []int) (*model.Poll, error) {
+	message, poll, err := s.pollTarget(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options = slices.Compact(slices.Sorted(slices.Values(options)))
+	if len(options) == 0 || len(options) > 1 && !poll.MultipleChoice {
+		return nil, fmt.Errorf("%w: choose exactly one option", ErrInvalidVote)
+	}
+	if options[0] < 0 || options[len(options)-1] >= len(poll.Options) {
+		return nil, fmt.Errorf("%w: option must be between 0 and %d", ErrInvalidVote, len(poll.Options)-1)
+	}
+
+	now := s.now()
+	votes := make([]*model.PollVote, len(options))
+	for i, option := range options {
+		votes[i] = &model.PollVote{
+			MessageID: message.ID,
+			UserID:    userID,
+			Option:    option,
+			ChatID:    message.ChatID,
+			CreatedAt: now,
+		}
+	}
+
+	if err := s.chatRepository.SetPollVotes(message.ID, userID, votes, now); err != nil {
+		return nil, err
+	}
+
+	return s.pollChanged(model.EventPollUpdated, message, userID)
+}
+
+// RetractVote отзывает голос пользователя в открытом опросе
+func (s *ChatService) RetractVote(messageID, userID string) (*model.Poll, error) {
+	message, _, err := s.pollTarget(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chatRepository.SetPollVotes(message.ID, userID, nil, s.now()); err != nil {
+		return nil, err
+	}
+
+	return s.pollChanged(model.EventPollUpdated, message, userID)
+}
+
+// ClosePoll досрочно закрывает опрос. Доступно автору опроса
+// и администраторам чата.
+func (s *ChatService) ClosePoll(messageID, actorID string) (*model.Poll, error) {
+	message, _, err := s.pollTarget(messageID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserID != actorID {
+		if _, err := s.requireRank(message.ChatID, actorID, rankAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.chatRepository.ClosePoll(message.ID, s.now()); err != nil {
+		return nil, err
+	}
+
+	return s.pollChanged(model.EventPollClosed, message, actorID)
+}
+
+// pollTarget возвращает сообщение с открытым опросом в чате, где состоит userID
+func (s *ChatService) pollTarget(messageID, userID string) (*model.Message, *model.Poll, error) {
+	message, err := s.reactionTarget(messageID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.Type != model.MessageTypePoll {
+		return nil, nil, ErrNotPoll
+	}
+
+	polls, err := s.chatRepository.GetPolls([]string{message.ID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	poll, exists := polls[message.ID]
+	if !exists {
+		return nil, nil, repository.ErrPollNotFound
+	}
+
+	if poll.IsClosed(s.now()) {
+		return nil, nil, repository.ErrPollClosed
+	}
+
+	return message, poll, nil
+}
+
+// pollChanged рассылает новые итоги опроса и возвращает их с точки зрения
+// userID. В событии анонимного опроса не указывается, кто голосовал.
+func (s *ChatService) pollChanged(eventType string, message *model.Message, userID string) (*model.Poll, error) {
+	polls, err := s.chatRepository.GetPolls([]string{message.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := s.chatRepository.GetPollVotes([]string{message.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	poll, exists := polls[message.ID]
+	if !exists {
+		return nil, repository.ErrPollNotFound
+	}
+
+	broadcast := *poll
+	tallyPoll(&broadcast, votes[message.ID], "")
+
+	event := *message
+	event.Poll = &broadcast
+
+	actorID := userID
+	if poll.Anonymous {
+		actorID = ""
+	}
+	s.publish(eventType, message.ChatID, actorID, &event)
+
+	tallyPoll(poll, votes[message.ID], userID)
+	return poll, nil
+}
+
+// closeDuePolls закрывает опросы, у которых наступил ClosesAt, и рассылает итоги
+func (s *ChatService) closeDuePolls() error {
+	for {
+		due, err := s.chatRepository.GetDuePolls(s.now(), schedulerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, poll := range due {
+			err := s.chatRepository.ClosePoll(poll.MessageID, *poll.ClosesAt)
+			if errors.Is(err, repository.ErrPollClosed) || errors.Is(err, repository.ErrPollNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			message, err := s.chatRepository.GetMessageByID(poll.MessageID)
+			if err != nil {
+				log.Printf("Failed to announce closed poll %s: %v", poll.MessageID, err)
+				continue
+			}
+
+			if _, err := s.pollChanged(model.EventPollClosed, message, message.UserID); err != nil {
+				return err
+			}
+		}
+
+		if len(due) < schedulerBatchSize {
+			return nil
+		}
+	}
+}
+
+// fillPolls заполняет опросы с итогами у неудаленных сообщений типа poll
+// с точки зрения userID
+func (s *ChatService) fillPolls(userID string, messages []*model.Message) error {
+	var messageIDs []string
+	for _, message := range messages {
+		if message.Type == model.MessageTypePoll && !message.IsDeleted() {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	polls, err := s.chatRepository.GetPolls(messageIDs)
+	if err != nil {
+		return err
+	}
+
+	votes, err := s.chatRepository.GetPollVotes(messageIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if poll, exists := polls[message.ID]; exists {
+			tallyPoll(poll, votes[message.ID], userID)
+			message.Poll = poll
+		}
+	}
+
+	return nil
+}
+
+// tallyPoll подсчитывает итоги опроса по голосам. Голосовавшие перечисляются
+// только в неанонимных опросах; MyVotes заполняется, если указан userID.
+func tallyPoll(poll *model.Poll, votes []*model.PollVote, userID string) {
+	poll.Votes = make([]int, len(poll.Options))
+	poll.Voters = nil
+	poll.MyVotes = nil
+	if !poll.Anonymous {
+		poll.Voters = make([][]string, len(poll.Options))
+	}
+
+	voters := make(map[string]bool)
+	for _, vote := range votes {
+		if vote.Option < 0 || vote.Option >= len(poll.Options) {
+			continue
+		}
+
+		poll.Votes[vote.Option]++
+		voters[vote.UserID] = true
+
+		if !poll.Anonymous {
+			poll.Voters[vote.Option] = append(poll.Voters[vote.Option], vote.UserID)
+		}
+		if userID != "" && vote.UserID == userID {
+			poll.MyVotes = append(poll.MyVotes, vote.Option)
+		}
+	}
+
+	poll.TotalVoters = len(voters)
+	slices.Sort(poll.MyVotes)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestValidatePoll(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	valid := []*model.Poll{
+		{Options: model.PollOptions{"yes", "no"}},
+		{Options: model.PollOptions{"a", "b", "c"}, MultipleChoice: true, Anonymous: true, ClosesAt: &future},
+	}
+	for _, poll := range valid {
+		if err := validatePoll(model.MessageTypePoll, poll, now); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", poll, err)
+		}
+	}
+
+	invalid := []*model.Poll{
+		nil,
+		{Options: model.PollOptions{"only"}},
+		{Options: model.PollOptions{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}},
+		{Options: model.PollOptions{"yes", "  "}},
+		{Options: model.PollOptions{"yes", " yes "}},
+		{Options: model.PollOptions{"yes", strings.Repeat("x", maxPollOptionLength+1)}},
+		{Options: model.PollOptions{"yes", "no"}, ClosesAt: &past},
+	}
+	for _, poll := range invalid {
+		if err := validatePoll(model.MessageTypePoll, poll, now); !errors.Is(err, ErrInvalidPoll) {
+			t.Errorf("Expected ErrInvalidPoll for %+v, got %v", poll, err)
+		}
+	}
+
+	if err := validatePoll(model.MessageTypeText, &model.Poll{Options: model.PollOptions{"yes", "no"}}, now); !errors.Is(err, ErrInvalidPoll) {
+		t.Errorf("Expected ErrInvalidPoll for a text message with a poll, got %v", err)
+	}
+}
+
+func TestChatService_Polls(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "standup", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+
+	message, err := s.SendMessage(ctx, SendMessageInput{
+		ChatID:  chat.ID,
+		UserID:  "owner",
+		Type:    model.MessageTypePoll,
+		Content: "Standup time?",
+		Poll:    &model.Poll{Options: model.PollOptions{" 10:00 ", "11:00"}},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if message.Poll == nil || message.Poll.Options[0] != "10:00" || !slices.Equal(message.Poll.Votes, []int{0, 0}) {
+		t.Fatalf("Expected an empty poll with trimmed options, got %+v", message.Poll)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	poll, err := s.VotePoll(message.ID, "alice", []int{0})
+	if err != nil {
+		t.Fatalf("VotePoll failed: %v", err)
+	}
+	if !slices.Equal(poll.Votes, []int{1, 0}) || !slices.Equal(poll.MyVotes, []int{0}) || poll.TotalVoters != 1 {
+		t.Errorf("Unexpected tally: %+v", poll)
+	}
+
+	event := nextEvent(sub)
+	if event == nil || event.Type != model.EventPollUpdated || event.UserID != "alice" {
+		t.Fatalf("Expected poll.updated from alice, got %+v", event)
+	}
+	if got := event.Message.Poll; !slices.Equal(got.Votes, []int{1, 0}) || !slices.Equal(got.Voters[0], []string{"alice"}) || got.MyVotes != nil {
+		t.Errorf("Unexpected broadcast tally: %+v", got)
+	}
+
+	if _, err := s.VotePoll(message.ID, "bob", []int{0, 1}); !errors.Is(err, ErrInvalidVote) {
+		t.Errorf("Expected ErrInvalidVote for two options in a single-choice poll, got %v", err)
+	}
+	if _, err := s.VotePoll(message.ID, "bob", []int{2}); !errors.Is(err, ErrInvalidVote) {
+		t.Errorf("Expected ErrInvalidVote for an unknown option, got %v", err)
+	}
+
+	// Повторный голос заменяет предыдущий
+	s.VotePoll(message.ID, "bob", []int{1})
+	poll, _ = s.VotePoll(message.ID, "alice", []int{1})
+	if !slices.Equal(poll.Votes, []int{0, 2}) || !slices.Equal(poll.Voters[1], []string{"bob", "alice"}) || poll.TotalVoters != 2 {
+		t.Errorf("Unexpected tally after changing the vote: %+v", poll)
+	}
+
+	poll, err = s.RetractVote(message.ID, "bob")
+	if err != nil {
+		t.Fatalf("RetractVote failed: %v", err)
+	}
+	if !slices.Equal(poll.Votes, []int{0, 1}) || poll.MyVotes != nil {
+		t.Errorf("Unexpected tally after retracting: %+v", poll)
+	}
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if got := messages[len(messages)-1].Poll; got == nil || !slices.Equal(got.MyVotes, []int{1}) || got.TotalVoters != 1 {
+		t.Errorf("Expected GetMessages to include the poll, got %+v", got)
+	}
+
+	if _, err := s.EditMessage(message.ID, "owner", "Other question?"); !errors.Is(err, ErrPollEdit) {
+		t.Errorf("Expected ErrPollEdit, got %v", err)
+	}
+
+	if _, err := s.ClosePoll(message.ID, "alice"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member, got %v", err)
+	}
+
+	for nextEvent(sub) != nil { // Итоги голосования выше
+	}
+
+	poll, err = s.ClosePoll(message.ID, "owner")
+	if err != nil {
+		t.Fatalf("ClosePoll failed: %v", err)
+	}
+	if poll.ClosedAt == nil {
+		t.Error("Expected closed_at to be set")
+	}
+	if event := nextEvent(sub); event == nil || event.Type != model.EventPollClosed {
+		t.Errorf("Expected poll.closed, got %+v", event)
+	}
+
+	if _, err := s.VotePoll(message.ID, "bob", []int{0}); !errors.Is(err, repository.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed, got %v", err)
+	}
+	if _, err := s.ClosePoll(message.ID, "owner"); !errors.Is(err, repository.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed on a repeated close, got %v", err)
+	}
+
+	text, _ := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "not a poll"})
+	if _, err := s.VotePoll(text.ID, "alice", []int{0}); !errors.Is(err, ErrNotPoll) {
+		t.Errorf("Expected ErrNotPoll, got %v", err)
+	}
+
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Type: model.MessageTypePoll, Content: "No options?"}); !errors.Is(err, ErrInvalidPoll) {
+		t.Errorf("Expected ErrInvalidPoll without options, got %v", err)
+	}
+}
+
+func TestChatService_AnonymousPoll(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "standup", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+	message, _ := s.SendMessage(context.Background(), SendMessageInput{
+		ChatID:  chat.ID,
+		UserID:  "owner",
+		Type:    model.MessageTypePoll,
+		Content: "Blockers?",
+		Poll:    &model.Poll{Options: model.PollOptions{"CI", "Reviews", "None"}, MultipleChoice: true, Anonymous: true},
+	})
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	poll, err := s.VotePoll(message.ID, "alice", []int{1, 0, 1})
+	if err != nil {
+		t.Fatalf("VotePoll failed: %v", err)
+	}
+	if !slices.Equal(poll.Votes, []int{1, 1, 0}) || !slices.Equal(poll.MyVotes, []int{0, 1}) || poll.Voters != nil {
+		t.Errorf("Unexpected anonymous tally: %+v", poll)
+	}
+
+	event := nextEvent(sub)
+	if event == nil || event.Type != model.EventPollUpdated {
+		t.Fatalf("Expected poll.updated, got %+v", event)
+	}
+	if event.UserID != "" || event.Message.Poll.Voters != nil {
+		t.Errorf("Expected the anonymous vote not to reveal the voter, got %+v", event)
+	}
+
+	s.VotePoll(message.ID, "bob", []int{1})
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	got := messages[len(messages)-1].Poll
+	if !slices.Equal(got.Votes, []int{1, 2, 0}) || got.TotalVoters != 2 || got.Voters != nil || got.MyVotes != nil {
+		t.Errorf("Unexpected anonymous poll for the author: %+v", got)
+	}
+}
+
+func TestChatService_PollClosesAt(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "standup", CreatedBy: "owner", Participants: []string{"alice"}})
+	closesAt := clock.Now().Add(time.Hour)
+	message, err := s.SendMessage(context.Background(), SendMessageInput{
+		ChatID:  chat.ID,
+		UserID:  "owner",
+		Type:    model.MessageTypePoll,
+		Content: "Lunch?",
+		Poll:    &model.Poll{Options: model.PollOptions{"yes", "no"}, ClosesAt: &closesAt},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	if _, err := s.VotePoll(message.ID, "alice", []int{0}); err != nil {
+		t.Fatalf("VotePoll failed: %v", err)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	clock.Advance(time.Hour)
+
+	// Голосовать нельзя сразу, даже если планировщик еще не закрыл опрос
+	if _, err := s.VotePoll(message.ID, "alice", []int{1}); !errors.Is(err, repository.ErrPollClosed) {
+		t.Errorf("Expected ErrPollClosed after closes_at, got %v", err)
+	}
+
+	s.runScheduler(context.Background())
+
+	event := nextEvent(sub)
+	if event == nil || event.Type != model.EventPollClosed || event.UserID != "owner" {
+		t.Fatalf("Expected poll.closed, got %+v", event)
+	}
+	if got := event.Message.Poll; got.ClosedAt == nil || !got.ClosedAt.Equal(closesAt) || !slices.Equal(got.Votes, []int{1, 0}) {
+		t.Errorf("Unexpected closed poll: %+v", got)
+	}
+
+	s.runScheduler(context.Background())
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Expected the poll to be closed once, got %+v", event)
+	}
+
+	if _, err := s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Type: model.MessageTypePoll, Content: "Later?"}, clock.Now().Add(time.Hour)); !errors.Is(err, ErrScheduledPoll) {
+		t.Errorf("Expected ErrScheduledPoll, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+// SetTyping включает или выключает индикатор набора текста в чате.
+// Включенный индикатор гаснет сам через typingTTL, если его не продлевать.
+func (s *ChatService) SetTyping(chatID, userID string, typing bool) error {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !typing {
+		s.stopTyping(chatID, userID)
+		return nil
+	}
+
+	if err := requireActive(chat); err != nil {
+		return err
+	}
+
+	s.touch(userID)
+	if s.presence.startTyping(chatID, userID) {
+		s.publishEvent(&model.ChatEvent{Type: model.EventTypingStarted, ChatID: chatID, UserID: userID})
+	}
+
+	return nil
+}
+
+// Heartbeat продлевает присутствие пользователя. Клиент без активной
+// подписки должен вызывать его чаще, чем раз в heartbeatTimeout;
+// away = true сообщает, что пользователь отошел.
+func (s *ChatService) Heartbeat(userID string, away bool) error {
+	if presence, changed := s.presence.heartbeat(userID, away); changed {
+		return s.announcePresence(presence)
+	}
+	return nil
+}
+
+// GetChatPresence возвращает присутствие всех участников чата
+// и признак набора текста в этом чате
+func (s *ChatService) GetChatPresence(chatID, userID string) ([]*model.Presence, error) {
+	chat, err := s.requireParticipant(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	typing := s.presence.typingUsers(chatID)
+
+	presences := make([]*model.Presence, 0, len(chat.Participants))
+	for _, participant := range chat.Participants {
+		presence := s.presence.presence(participant.UserID)
+		presence.Typing = typing[participant.UserID]
+		presences = append(presences, presence)
+	}
+
+	return presences, nil
+}
+
+// Run выполняет фоновые задачи сервиса до отмены ctx: гасит истекшие
+// индикаторы набора, переводит неактивных пользователей в away/offline,
+// отправляет отложенные и удаляет истекшие сообщения, удаляет устаревшие
+// ключи идемпотентности и восстановившиеся лимиты, доставляет webhook'и
+func (s *ChatService) Run(ctx context.Context) {
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+
+	cleanupTicker := time.NewTicker(cleanupInterval)
+	defer cleanupTicker.Stop()
+
+	schedulerTicker := time.NewTicker(schedulerInterval)
+	defer schedulerTicker.Stop()
+
+	go s.runWebhooks(ctx)
+	s.runScheduler(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweepPresence()
+		case <-schedulerTicker.C:
+			s.runScheduler(ctx)
+		case <-cleanupTicker.C:
+			s.sweepMessageKeys()
+			s.limiter.sweep()
+		}
+	}
+}
+
+// sweepMessageKeys удаляет ключи, вышедшие за окно хранения
+func (s *ChatService) sweepMessageKeys() {
+	if _, err := s.chatRepository.DeleteMessageKeys(s.now().Add(-s.config.MessageKeyRetention)); err != nil {
+		log.Printf("Failed to delete expired message keys: %v", err)
+	}
+}
+
+// sweepPresence рассылает события об истекших индикаторах и смене статусов
+func (s *ChatService) sweepPresence() {
+	for chatID, userIDs := range s.presence.expireTyping() {
+		for _, userID := range userIDs {
+			s.publishEvent(&model.ChatEvent{Type: model.EventTypingStopped, ChatID: chatID, UserID: userID})
+		}
+	}
+
+	for _, presence := range s.presence.sweep() {
+		if err := s.announcePresence(presence); err != nil {
+			log.Printf("Failed to announce presence of %s: %v", presence.UserID, err)
+		}
+	}
+}
+
+// touch отмечает действие пользователя и оповещает о возврате из away
+func (s *ChatService) touch(userID string) {
+	if presence, changed := s.presence.touch(userID); changed {
+		if err := s.announcePresence(presence); err != nil {
+			log.Printf("Failed to announce presence of %s: %v", userID, err)
+		}
+	}
+}
+
+func (s *ChatService) stopTyping(chatID, userID string) {
+	if s.presence.stopTyping(chatID, userID) {
+		s.publishEvent(&model.ChatEvent{Type: model.EventTypingStopped, ChatID: chatID, UserID: userID})
+	}
+}
+
+// announcePresence рассылает новое состояние пользователя во все его чаты
+func (s *ChatService) announcePresence(presence *model.Presence) error {
+	chatIDs, err := s.chatRepository.GetUserChatIDs(presence.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, chatID := range chatIDs {
+		p := *presence
+		s.publishEvent(&model.ChatEvent{
+			Type:     model.EventPresenceChanged,
+			ChatID:   chatID,
+			UserID:   presence.UserID,
+			Presence: &p,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+// fakeClock - управляемые часы для проверки истечения таймаутов
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newPresenceTestService() (*ChatService, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestChatService()
+	s.presence.now = clock.Now
+	return s, clock
+}
+
+// nextEvent возвращает следующее событие подписки или nil, если его нет
+func nextEvent(sub *Subscription) *model.ChatEvent {
+	select {
+	case event := <-sub.Events():
+		return event
+	default:
+		return nil
+	}
+}
+
+func TestChatService_Typing(t *testing.T) {
+	s, clock := newPresenceTestService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	if err := s.SetTyping(chat.ID, "stranger", true); err == nil {
+		t.Error("Expected non-participant to be rejected")
+	}
+
+	// Первое действие alice заодно делает ее онлайн
+	s.SetTyping(chat.ID, "alice", true)
+	expectPresence(t, sub, "alice", model.PresenceOnline)
+	if event := nextEvent(sub); event == nil || event.Type != model.EventTypingStarted || event.UserID != "alice" {
+		t.Fatalf("Expected typing.started, got %+v", event)
+	}
+
+	// Продление не рассылает событие повторно
+	clock.Advance(3 * time.Second)
+	s.SetTyping(chat.ID, "alice", true)
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	presences, _ := s.GetChatPresence(chat.ID, "owner")
+	if !presences[1].Typing || presences[0].Typing {
+		t.Errorf("Expected only alice to be typing, got %+v, %+v", presences[0], presences[1])
+	}
+
+	clock.Advance(typingTTL)
+	s.sweepPresence()
+	if event := nextEvent(sub); event == nil || event.Type != model.EventTypingStopped {
+		t.Fatalf("Expected typing.stopped after expiry, got %+v", event)
+	}
+
+	// Отправка сообщения гасит индикатор
+	s.SetTyping(chat.ID, "alice", true)
+	nextEvent(sub)
+	s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi"})
+	if event := nextEvent(sub); event == nil || event.Type != model.EventMessageCreated {
+		t.Fatalf("Expected message.created, got %+v", event)
+	}
+	if event := nextEvent(sub); event == nil || event.Type != model.EventTypingStopped {
+		t.Fatalf("Expected typing.stopped after message, got %+v", event)
+	}
+}
+
+func TestChatService_Presence(t *testing.T) {
+	s, clock := newPresenceTestService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	ownerSub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer ownerSub.Close()
+
+	presences, _ := s.GetChatPresence(chat.ID, "owner")
+	if presences[0].Status != model.PresenceOnline || presences[1].Status != model.PresenceOffline {
+		t.Fatalf("Unexpected presence %+v, %+v", presences[0], presences[1])
+	}
+	if !presences[1].LastSeen.IsZero() {
+		t.Errorf("Expected unknown last seen for alice, got %v", presences[1].LastSeen)
+	}
+
+	aliceSub, _ := s.SubscribeChat(chat.ID, "alice")
+	expectPresence(t, ownerSub, "alice", model.PresenceOnline)
+
+	// Без активности пользователь становится away, а после действия - снова online
+	clock.Advance(idleTimeout)
+	s.sweepPresence()
+	expectPresence(t, ownerSub, "alice", model.PresenceAway)
+	expectPresence(t, ownerSub, "owner", model.PresenceAway)
+
+	s.Heartbeat("alice", false)
+	expectPresence(t, ownerSub, "alice", model.PresenceOnline)
+
+	// После закрытия подписки пользователь остается онлайн до heartbeatTimeout
+	aliceSub.Close()
+	lastSeen := clock.Now()
+	s.sweepPresence()
+	if event := nextEvent(ownerSub); event != nil {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	clock.Advance(heartbeatTimeout)
+	s.sweepPresence()
+	presence := expectPresence(t, ownerSub, "alice", model.PresenceOffline)
+	if !presence.LastSeen.Equal(lastSeen) {
+		t.Errorf("Expected last seen %v, got %v", lastSeen, presence.LastSeen)
+	}
+	if _, exists := s.presence.users["alice"]; exists {
+		t.Error("Expected the offline user's state to be dropped")
+	}
+	if presence := s.presence.presence("alice"); presence.Status != model.PresenceOffline {
+		t.Errorf("Expected alice to stay offline, got %s", presence.Status)
+	}
+
+	// Heartbeat без подписки тоже держит пользователя онлайн
+	s.Heartbeat("alice", true)
+	expectPresence(t, ownerSub, "alice", model.PresenceAway)
+}
+
+func expectPresence(t *testing.T, sub *Subscription, userID, status string) *model.Presence {
+	t.Helper()
+
+	event := nextEvent(sub)
+	if event == nil || event.Type != model.EventPresenceChanged || event.UserID != userID || event.Presence.Status != status {
+		t.Fatalf("Expected %s to become %s, got %+v", userID, status, event)
+	}
+	return event.Presence
+}
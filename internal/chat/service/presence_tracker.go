@@ -0,0 +1,244 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+const (
+	// typingTTL - через сколько индикатор набора гаснет без повторного SetTyping
+	typingTTL = 5 * time.Second
+	// heartbeatTimeout - сколько пользователь без подписок считается онлайн
+	// после последнего сигнала от клиента (переживает переподключения)
+	heartbeatTimeout = 45 * time.Second
+	// idleTimeout - после какого времени без активности пользователь становится away
+	idleTimeout = 5 * time.Minute
+	// presenceSweepInterval - период проверки истекших индикаторов в Run
+	presenceSweepInterval = time.Second
+)
+
+// presenceState - состояние присутствия одного пользователя
+type presenceState struct {
+	connections int       // Активные подписки SubscribeChat
+	lastSeen    time.Time // Последний сигнал от клиента: подписка, heartbeat или действие
+	lastActive  time.Time // Последнее действие пользователя
+	away        bool      // Клиент сам сообщил, что пользователь неактивен
+	announced   string    // Последний разосланный статус
+}
+
+// presenceTracker хранит в памяти присутствие пользователей и индикаторы
+// набора текста. Сам ничего не рассылает: методы сообщают, изменилось ли
+// состояние, а события публикует ChatService.
+type presenceTracker struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	users  map[string]*presenceState
+	typing map[string]map[string]time.Time // chat_id -> user_id -> когда индикатор истекает
+}
+
+func newPresenceTracker(now func() time.Time) *presenceTracker {
+	return &presenceTracker{
+		now:    now,
+		users:  make(map[string]*presenceState),
+		typing: make(map[string]map[string]time.Time),
+	}
+}
+
+// connect учитывает новую подписку пользователя
+func (t *presenceTracker) connect(userID string) (*model.Presence, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	state := t.stateLocked(userID)
+	state.connections++
+	state.lastSeen = now
+	state.lastActive = now
+	state.away = false
+	return t.announceLocked(userID, state)
+}
+
+// disconnect учитывает закрытие подписки. Пользователь становится offline
+// не сразу, а после heartbeatTimeout - это заметит sweep.
+func (t *presenceTracker) disconnect(userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.stateLocked(userID)
+	if state.connections > 0 {
+		state.connections--
+	}
+	state.lastSeen = t.now()
+}
+
+// heartbeat продлевает присутствие пользователя. away = true означает,
+// что клиент открыт, но пользователь им не пользуется.
+func (t *presenceTracker) heartbeat(userID string, away bool) (*model.Presence, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	state := t.stateLocked(userID)
+	state.lastSeen = now
+	state.away = away
+	if !away {
+		state.lastActive = now
+	}
+	return t.announceLocked(userID, state)
+}
+
+// touch отмечает действие пользователя (отправку сообщения, набор текста)
+func (t *presenceTracker) touch(userID string) (*model.Presence, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	state := t.stateLocked(userID)
+	state.lastSeen = now
+	state.lastActive = now
+	state.away = false
+	return t.announceLocked(userID, state)
+}
+
+// presence возвращает текущее состояние пользователя
+func (t *presenceTracker) presence(userID string) *model.Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.users[userID]
+	if !exists {
+		return &model.Presence{UserID: userID, Status: model.PresenceOffline}
+	}
+	return t.presenceLocked(userID, state)
+}
+
+// sweep возвращает пользователей, чей статус изменился с течением времени
+// (истек heartbeat или наступил простой). Состояние ушедших в offline
+// пользователей без подписок забывается: presence и так вернет для них
+// offline, а карта не растет со всеми, кто когда-либо подключался.
+func (t *presenceTracker) sweep() []*model.Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changed []*model.Presence
+	for userID, state := range t.users {
+		if presence, ok := t.announceLocked(userID, state); ok {
+			changed = append(changed, presence)
+		}
+		if state.connections == 0 && state.announced == model.PresenceOffline {
+			delete(t.users, userID)
+		}
+	}
+
+	slices.SortFunc(changed, func(a, b *model.Presence) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return changed
+}
+
+// startTyping включает или продлевает индикатор набора.
+// Возвращает true, если пользователь только начал печатать.
+func (t *presenceTracker) startTyping(chatID, userID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.typing[chatID] == nil {
+		t.typing[chatID] = make(map[string]time.Time)
+	}
+
+	expiresAt, exists := t.typing[chatID][userID]
+	t.typing[chatID][userID] = now.Add(typingTTL)
+	return !exists || !now.Before(expiresAt)
+}
+
+// stopTyping гасит индикатор. Возвращает true, если он был включен.
+func (t *presenceTracker) stopTyping(chatID, userID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, exists := t.typing[chatID][userID]
+	t.deleteTypingLocked(chatID, userID)
+	return exists
+}
+
+// typingUsers возвращает пользователей, печатающих в чате
+func (t *presenceTracker) typingUsers(chatID string) map[string]bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	users := make(map[string]bool)
+	for userID, expiresAt := range t.typing[chatID] {
+		if now.Before(expiresAt) {
+			users[userID] = true
+		}
+	}
+	return users
+}
+
+// expireTyping удаляет истекшие индикаторы и возвращает их как chat_id -> user_id
+func (t *presenceTracker) expireTyping() map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	expired := make(map[string][]string)
+	for chatID, users := range t.typing {
+		for userID, expiresAt := range users {
+			if !now.Before(expiresAt) {
+				expired[chatID] = append(expired[chatID], userID)
+				t.deleteTypingLocked(chatID, userID)
+			}
+		}
+		slices.Sort(expired[chatID])
+	}
+	return expired
+}
+
+func (t *presenceTracker) deleteTypingLocked(chatID, userID string) {
+	delete(t.typing[chatID], userID)
+	if len(t.typing[chatID]) == 0 {
+		delete(t.typing, chatID)
+	}
+}
+
+func (t *presenceTracker) stateLocked(userID string) *presenceState {
+	state, exists := t.users[userID]
+	if !exists {
+		state = &presenceState{announced: model.PresenceOffline}
+		t.users[userID] = state
+	}
+	return state
+}
+
+// announceLocked возвращает состояние пользователя и true, если статус
+// изменился с последнего оповещения
+func (t *presenceTracker) announceLocked(userID string, state *presenceState) (*model.Presence, bool) {
+	presence := t.presenceLocked(userID, state)
+	if presence.Status == state.announced {
+		return presence, false
+	}
+
+	state.announced = presence.Status
+	return presence, true
+}
+
+func (t *presenceTracker) presenceLocked(userID string, state *presenceState) *model.Presence {
+	now := t.now()
+	presence := &model.Presence{UserID: userID, Status: model.PresenceOnline, LastSeen: now}
+
+	switch {
+	case state.connections == 0 && now.Sub(state.lastSeen) >= heartbeatTimeout:
+		presence.Status = model.PresenceOffline
+		presence.LastSeen = state.lastSeen
+	case state.away || now.Sub(state.lastActive) >= idleTimeout:
+		presence.Status = model.PresenceAway
+	}
+
+	return presence
+}
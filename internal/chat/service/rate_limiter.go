@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited - общая причина отказов ограничителя; конкретный отказ
+// описывает *RateLimitError
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Области ограничений отправки сообщений
+const (
+	RateLimitUser     = "user"      // Все сообщения пользователя
+	RateLimitChat     = "chat"      // Все сообщения чата
+	RateLimitSlowMode = "slow_mode" // Медленный режим чата для участника
+)
+
+// RateLimitError сообщает, какое ограничение сработало и когда можно повторить
+type RateLimitError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (%s), retry after %s", ErrRateLimited, e.Scope, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimit - параметры token bucket: Rate токенов в секунду, не больше Burst.
+// Отрицательный Rate отключает ограничение.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) disabled() bool {
+	return l.Rate <= 0
+}
+
+// withDefault заменяет нулевой лимит на def; в bucket'е всегда помещается
+// хотя бы один токен
+func (l RateLimit) withDefault(def RateLimit) RateLimit {
+	if l == (RateLimit{}) {
+		return def
+	}
+	l.Burst = max(l.Burst, 1)
+	return l
+}
+
+// bucket - состояние одного ключа; limit запоминается для sweep
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+// refill возвращает число токенов к моменту now
+func (b *bucket) refill(now time.Time) float64 {
+	elapsed := now.Sub(b.updated).Seconds()
+	return math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+}
+
+// rateCheck - ключ и лимит, которые проверяются при одной отправке
+type rateCheck struct {
+	scope string
+	key   string
+	limit RateLimit
+}
+
+// rateLimiter хранит token bucket'ы в памяти процесса. Параметры лимита
+// передаются при каждой проверке, поэтому медленный режим с разным
+// интервалом в разных чатах обслуживается одним ограничителем.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func newRateLimiter(now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*bucket),
+		now:     now,
+	}
+}
+
+// allow списывает по токену со всех ключей или, если хотя бы в одном
+// токенов нет, не списывает ничего и возвращает ошибку с наибольшим
+// временем ожидания
+func (l *rateLimiter) allow(checks ...rateCheck) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var limited *RateLimitError
+	available := make([]float64, len(checks))
+
+	for i, check := range checks {
+		if check.limit.disabled() {
+			continue
+		}
+
+		b := l.bucket(check, now)
+		available[i] = b.refill(now)
+
+		if available[i] < 1 {
+			wait := time.Duration((1 - available[i]) / check.limit.Rate * float64(time.Second))
+			if limited == nil || wait > limited.RetryAfter {
+				limited = &RateLimitError{Scope: check.scope, RetryAfter: wait}
+			}
+		}
+	}
+
+	if limited != nil {
+		return limited
+	}
+
+	for i, check := range checks {
+		if check.limit.disabled() {
+			continue
+		}
+
+		b := l.buckets[check.key]
+		b.tokens = available[i] - 1
+		b.updated = now
+	}
+
+	return nil
+}
+
+// bucket возвращает состояние ключа, создавая полный bucket для нового
+func (l *rateLimiter) bucket(check rateCheck, now time.Time) *bucket {
+	b, exists := l.buckets[check.key]
+	if !exists {
+		b = &bucket{tokens: float64(check.limit.Burst), updated: now}
+		l.buckets[check.key] = b
+	}
+	b.limit = check.limit
+	return b
+}
+
+// sweep удаляет полностью восстановившиеся bucket'ы: они ничем не
+// отличаются от отсутствующих
+func (l *rateLimiter) sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.refill(now) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
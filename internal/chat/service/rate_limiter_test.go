@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := newRateLimiter(clock.Now)
+
+	user := rateCheck{scope: RateLimitUser, key: "user:alice", limit: RateLimit{Rate: 1, Burst: 2}}
+	chat := rateCheck{scope: RateLimitChat, key: "chat:general", limit: RateLimit{Rate: 0.5, Burst: 3}}
+
+	for range 2 {
+		if err := limiter.allow(user, chat); err != nil {
+			t.Fatalf("Expected burst to be allowed, got %v", err)
+		}
+	}
+
+	var limited *RateLimitError
+	err := limiter.allow(user, chat)
+	if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected RateLimitError, got %v", err)
+	}
+	if limited.Scope != RateLimitUser || limited.RetryAfter != time.Second {
+		t.Errorf("Expected user limit with 1s retry, got %s after %s", limited.Scope, limited.RetryAfter)
+	}
+
+	// Отказ не списывает токены с других ключей: в чате остался один
+	if err := limiter.allow(chat); err != nil {
+		t.Errorf("Expected chat token to be kept after rejection, got %v", err)
+	}
+	err = limiter.allow(chat)
+	if !errors.As(err, &limited) || limited.Scope != RateLimitChat || limited.RetryAfter != 2*time.Second {
+		t.Errorf("Expected chat limit with 2s retry, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := limiter.allow(user); err != nil {
+		t.Errorf("Expected token to refill after 1s, got %v", err)
+	}
+
+	if err := limiter.allow(rateCheck{key: "off", limit: RateLimit{Rate: -1}}); err != nil {
+		t.Errorf("Expected disabled limit to allow, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	limiter.sweep()
+	if len(limiter.buckets) != 0 {
+		t.Errorf("Expected refilled buckets to be swept, got %d", len(limiter.buckets))
+	}
+}
+
+func TestChatService_MessageRateLimits(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestChatService()
+	s.config.UserMessageLimit = RateLimit{Rate: 1, Burst: 2}
+	s.limiter.now = clock.Now
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	send := func(userID string) error {
+		_, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: userID, Content: "hi"})
+		return err
+	}
+
+	if err := send("alice"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if err := send("alice"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if err := send("alice"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	if err := send("owner"); err != nil {
+		t.Errorf("Expected other user to be unaffected, got %v", err)
+	}
+}
+
+func TestChatService_SlowMode(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestChatService()
+	s.limiter.now = clock.Now
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	send := func(userID string) error {
+		_, err := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: userID, Content: "hi"})
+		return err
+	}
+
+	if _, err := s.SetSlowMode(chat.ID, "alice", 30); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for member, got %v", err)
+	}
+	if _, err := s.SetSlowMode(chat.ID, "owner", -1); !errors.Is(err, ErrInvalidSlowMode) {
+		t.Errorf("Expected ErrInvalidSlowMode, got %v", err)
+	}
+
+	updated, err := s.SetSlowMode(chat.ID, "owner", 30)
+	if err != nil {
+		t.Fatalf("SetSlowMode failed: %v", err)
+	}
+	if updated.SlowModeSeconds != 30 {
+		t.Errorf("Expected 30s slow mode, got %d", updated.SlowModeSeconds)
+	}
+
+	if err := send("alice"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	var limited *RateLimitError
+	if err := send("alice"); !errors.As(err, &limited) || limited.Scope != RateLimitSlowMode || limited.RetryAfter != 30*time.Second {
+		t.Errorf("Expected slow mode rejection with 30s retry, got %v", err)
+	}
+
+	// Администраторы от медленного режима освобождены
+	for range 3 {
+		if err := send("owner"); err != nil {
+			t.Errorf("Expected owner to bypass slow mode, got %v", err)
+		}
+	}
+
+	clock.Advance(30 * time.Second)
+	if err := send("alice"); err != nil {
+		t.Errorf("Expected alice to send after the interval, got %v", err)
+	}
+
+	if _, err := s.SetSlowMode(chat.ID, "owner", 0); err != nil {
+		t.Fatalf("SetSlowMode(0) failed: %v", err)
+	}
+	if err := send("alice"); err != nil {
+		t.Errorf("Expected slow mode to be off, got %v", err)
+	}
+}
+
+func TestChatService_RetryBypassesRateLimits(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestChatService()
+	s.config.UserMessageLimit = RateLimit{Rate: 1, Burst: 1}
+	s.limiter.now = clock.Now
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	if _, err := s.SetSlowMode(chat.ID, "owner", 30); err != nil {
+		t.Fatalf("SetSlowMode failed: %v", err)
+	}
+	send := func(key string) (*model.Message, error) {
+		return s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hi", ClientMessageID: key})
+	}
+
+	first, err := send("k1")
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	// Медленный режим и исчерпанная корзина не мешают повтору
+	for range 3 {
+		retried, err := send("k1")
+		if err != nil {
+			t.Fatalf("Expected retry to return the stored message, got %v", err)
+		}
+		if retried.ID != first.ID {
+			t.Errorf("Expected the same message on retry, got %s and %s", first.ID, retried.ID)
+		}
+	}
+
+	var limited *RateLimitError
+	if _, err := send("k2"); !errors.As(err, &limited) {
+		t.Errorf("Expected a new message to be rate limited, got %v", err)
+	}
+
+	// Повторы не расходуют лимиты: после интервала новое сообщение проходит
+	clock.Advance(30 * time.Second)
+	if _, err := send("k2"); err != nil {
+		t.Errorf("Expected a new message after the interval, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	maxEmojiLength  = 64     // message_reactions.emoji VARCHAR(64)
+	combiningKeycap = 0x20E3 // Входит в emoji-цифры вида 1️⃣
+)
+
+var ErrInvalidEmoji = errors.New("reaction must be an emoji")
+
+// AddReaction добавляет реакцию пользователя на сообщение и возвращает
+// обновленную сводку реакций. Повторная реакция тем же emoji ничего не меняет.
+func (s *ChatService) AddReaction(messageID, userID, emoji string) ([]*model.ReactionCount, error) {
+	if !validEmoji(emoji) {
+		return nil, ErrInvalidEmoji
+	}
+
+	message, err := s.reactionTarget(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction := &model.Reaction{
+		MessageID: message.ID,
+		UserID:    userID,
+		Emoji:     emoji,
+		ChatID:    message.ChatID,
+		CreatedAt: s.now(),
+	}
+
+	err = s.chatRepository.AddReaction(reaction)
+	switch {
+	case err == nil:
+		s.publishReaction(model.EventReactionAdded, reaction)
+	case !errors.Is(err, repository.ErrReactionExists):
+		return nil, err
+	}
+
+	return s.messageReactions(message.ID, userID)
+}
+
+// RemoveReaction снимает реакцию пользователя и возвращает обновленную
+// сводку реакций. Снятие отсутствующей реакции ничего не меняет.
+func (s *ChatService) RemoveReaction(messageID, userID, emoji string) ([]*model.ReactionCount, error) {
+	message, err := s.reactionTarget(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.chatRepository.RemoveReaction(message.ID, userID, emoji)
+	switch {
+	case err == nil:
+		s.publishReaction(model.EventReactionRemoved, &model.Reaction{
+			MessageID: message.ID,
+			UserID:    userID,
+			Emoji:     emoji,
+			ChatID:    message.ChatID,
+			CreatedAt: s.now(),
+		})
+	case !errors.Is(err, repository.ErrReactionNotFound):
+		return nil, err
+	}
+
+	return s.messageReactions(message.ID, userID)
+}
+
+// reactionTarget возвращает неудаленное сообщение чата, где состоит userID
+func (s *ChatService) reactionTarget(messageID, userID string) (*model.Message, error) {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err := s.requireParticipant(message.ChatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	if message.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+
+	return message, nil
+}
+
+func (s *ChatService) messageReactions(messageID, userID string) ([]*model.ReactionCount, error) {
+	counts, err := s.chatRepository.GetReactionCounts([]string{messageID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return counts[messageID], nil
+}
+
+func (s *ChatService) publishReaction(eventType string, reaction *model.Reaction) {
+	s.publishEvent(&model.ChatEvent{
+		Type:     eventType,
+		ChatID:   reaction.ChatID,
+		UserID:   reaction.UserID,
+		Reaction: reaction,
+	})
+}
+
+// fillReactions заполняет сводку реакций у сообщений с точки зрения userID.
+// У удаленных сообщений реакции не показываются.
+func (s *ChatService) fillReactions(userID string, messages []*model.Message) error {
+	var messageIDs []string
+	for _, message := range messages {
+		if !message.IsDeleted() {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	counts, err := s.chatRepository.GetReactionCounts(messageIDs, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		message.Reactions = counts[message.ID]
+	}
+
+	return nil
+}
+
+// validEmoji допускает последовательность emoji: хотя бы один символ-пиктограмма,
+// без букв, пробелов и управляющих символов (модификаторы и ZWJ разрешены)
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiLength || !utf8.ValidString(emoji) {
+		return false
+	}
+
+	hasSymbol := false
+	for _, r := range emoji {
+		switch {
+		case unicode.IsLetter(r), unicode.IsSpace(r), unicode.IsControl(r):
+			return false
+		case unicode.Is(unicode.So, r), r == combiningKeycap:
+			hasSymbol = true
+		}
+	}
+
+	return hasSymbol
+}
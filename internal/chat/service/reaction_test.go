@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestValidEmoji(t *testing.T) {
+	valid := []string{"👍", "❤️", "✅", "👍🏽", "👨‍👩‍👧", "🇷🇺", "1️⃣"}
+	for _, emoji := range valid {
+		if !validEmoji(emoji) {
+			t.Errorf("Expected %q to be valid", emoji)
+		}
+	}
+
+	invalid := []string{"", "ok", "👍 👍", "1", "!!", "👍\n", "a👍"}
+	for _, emoji := range invalid {
+		if validEmoji(emoji) {
+			t.Errorf("Expected %q to be invalid", emoji)
+		}
+	}
+}
+
+func TestChatService_Reactions(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+	message, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "deploy done"})
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	reactions, err := s.AddReaction(message.ID, "alice", "👍")
+	if err != nil {
+		t.Fatalf("AddReaction failed: %v", err)
+	}
+	if len(reactions) != 1 || reactions[0].Count != 1 || !reactions[0].ReactedByMe {
+		t.Errorf("Unexpected reactions: %+v", reactions)
+	}
+
+	event := nextEvent(sub)
+	if event == nil || event.Type != model.EventReactionAdded || event.UserID != "alice" || event.Reaction.Emoji != "👍" {
+		t.Fatalf("Expected reaction.added from alice, got %+v", event)
+	}
+
+	// Повторная реакция не дублируется и не рассылается
+	if _, err := s.AddReaction(message.ID, "alice", "👍"); err != nil {
+		t.Fatalf("Repeated AddReaction failed: %v", err)
+	}
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Expected no event for a repeated reaction, got %+v", event)
+	}
+
+	s.AddReaction(message.ID, "bob", "👍")
+	nextEvent(sub)
+	s.AddReaction(message.ID, "bob", "🎉")
+	nextEvent(sub)
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	reacted := messages[len(messages)-1]
+	if len(reacted.Reactions) != 2 {
+		t.Fatalf("Expected 2 emoji, got %+v", reacted.Reactions)
+	}
+	if r := reacted.Reactions[0]; r.Emoji != "👍" || r.Count != 2 || !r.ReactedByMe {
+		t.Errorf("Unexpected 👍 summary: %+v", r)
+	}
+	if r := reacted.Reactions[1]; r.Emoji != "🎉" || r.Count != 1 || r.ReactedByMe {
+		t.Errorf("Unexpected 🎉 summary: %+v", r)
+	}
+
+	reactions, err = s.RemoveReaction(message.ID, "alice", "👍")
+	if err != nil {
+		t.Fatalf("RemoveReaction failed: %v", err)
+	}
+	if reactions[0].Count != 1 || reactions[0].ReactedByMe {
+		t.Errorf("Unexpected reactions after removal: %+v", reactions)
+	}
+
+	event = nextEvent(sub)
+	if event == nil || event.Type != model.EventReactionRemoved || event.Reaction.Emoji != "👍" {
+		t.Fatalf("Expected reaction.removed, got %+v", event)
+	}
+
+	if _, err := s.RemoveReaction(message.ID, "alice", "👍"); err != nil {
+		t.Errorf("Removing a missing reaction failed: %v", err)
+	}
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Expected no event for a missing reaction, got %+v", event)
+	}
+}
+
+func TestChatService_ReactionErrors(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	message, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hello"})
+	deleted, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "oops"})
+	s.DeleteMessage(deleted.ID, "owner")
+
+	tests := []struct {
+		name      string
+		messageID string
+		userID    string
+		emoji     string
+		wantErr   error
+	}{
+		{"not an emoji", message.ID, "alice", "ok", ErrInvalidEmoji},
+		{"not participant", message.ID, "mallory", "👍", ErrNotParticipant},
+		{"deleted message", deleted.ID, "alice", "👍", ErrMessageDeleted},
+		{"unknown message", "missing", "alice", "👍", repository.ErrMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.AddReaction(tt.messageID, tt.userID, tt.emoji); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+// ReadReceipt - отметка о том, что участник прочитал сообщение
+type ReadReceipt struct {
+	UserID string
+	ReadAt *time.Time // Когда участник в последний раз сдвигал отметку прочтения
+}
+
+// MarkRead отмечает прочитанными все сообщения чата до upToMessageID включительно.
+// Отметка не сдвигается назад; остальные участники получают событие message.read.
+func (s *ChatService) MarkRead(chatID, userID, upToMessageID string) error {
+	if _, err := s.requireParticipant(chatID, userID); err != nil {
+		return err
+	}
+
+	message, err := s.chatRepository.GetMessageByID(upToMessageID)
+	if err != nil {
+		return err
+	}
+
+	if message.ChatID != chatID {
+		return repository.ErrMessageNotFound
+	}
+
+	participant, err := s.chatRepository.GetParticipant(chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	if participant.LastReadSeq >= message.Seq {
+		return nil
+	}
+
+	if err := s.chatRepository.MarkRead(chatID, userID, message.Seq, s.now()); err != nil {
+		return err
+	}
+
+	s.publish(model.EventMessageRead, chatID, userID, message)
+	return nil
+}
+
+// ListMyChats возвращает чаты пользователя с числом непрочитанных
+// и последним сообщением, от недавно активных к давно неактивным.
+// Личные чаты называются именем собеседника. Архивные чаты возвращаются
+// только при includeArchived.
+func (s *ChatService) ListMyChats(ctx context.Context, userID string, includeArchived bool) ([]*repository.ChatSummary, error) {
+	summaries, err := s.chatRepository.GetUserChats(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !includeArchived {
+		summaries = slices.DeleteFunc(summaries, func(summary *repository.ChatSummary) bool {
+			return summary.Chat.IsArchived()
+		})
+	}
+
+	s.fillDirectChatNames(ctx, userID, summaries)
+	return summaries, nil
+}
+
+// GetReadReceipts возвращает участников, прочитавших сообщение (кроме автора)
+func (s *ChatService) GetReadReceipts(messageID, userID string) ([]*ReadReceipt, error) {
+	message, err := s.chatRepository.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireParticipant(message.ChatID, userID); err != nil {
+		return nil, err
+	}
+
+	participants, err := s.chatRepository.GetParticipants(message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := []*ReadReceipt{}
+	for _, participant := range participants {
+		if participant.UserID != message.UserID && participant.LastReadSeq >= message.Seq {
+			receipts = append(receipts, &ReadReceipt{UserID: participant.UserID, ReadAt: participant.LastReadAt})
+		}
+	}
+
+	return receipts, nil
+}
+
+// fillReadCounts заполняет ReadCount: сколько участников, кроме автора,
+// дочитали чат до сообщения
+func (s *ChatService) fillReadCounts(chatID string, messages []*model.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	participants, err := s.chatRepository.GetParticipants(chatID)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		message.ReadCount = 0
+		for _, participant := range participants {
+			if participant.UserID != message.UserID && participant.LastReadSeq >= message.Seq {
+				message.ReadCount++
+			}
+		}
+	}
+
+	return nil
+}
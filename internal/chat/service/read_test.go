@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_MarkRead(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice", "bob"}})
+
+	first, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "first"})
+	second, _ := s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "second"})
+
+	summaries, _ := s.ListMyChats(context.Background(), "alice", false)
+	if len(summaries) != 1 || summaries[0].UnreadCount != 2 {
+		t.Fatalf("Expected 2 unread messages, got %+v", summaries)
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "owner")
+	defer sub.Close()
+
+	if err := s.MarkRead(chat.ID, "alice", first.ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+
+	event := <-sub.Events()
+	if event.Type != model.EventMessageRead || event.UserID != "alice" || event.Message.ID != first.ID {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// Отметка не сдвигается назад и не рассылает событие повторно
+	s.MarkRead(chat.ID, "alice", second.ID)
+	<-sub.Events()
+	if err := s.MarkRead(chat.ID, "alice", first.ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("Unexpected event %+v", event)
+	default:
+	}
+
+	summaries, _ = s.ListMyChats(context.Background(), "alice", false)
+	if summaries[0].UnreadCount != 0 || summaries[0].LastMessage.ID != second.ID {
+		t.Errorf("Unexpected summary %+v", summaries[0])
+	}
+
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	if messages[0].ReadCount != 1 || messages[1].ReadCount != 1 {
+		t.Errorf("Expected each message read by alice only, got %d and %d", messages[0].ReadCount, messages[1].ReadCount)
+	}
+
+	receipts, err := s.GetReadReceipts(second.ID, "bob")
+	if err != nil || len(receipts) != 1 || receipts[0].UserID != "alice" || receipts[0].ReadAt == nil {
+		t.Errorf("Unexpected receipts %+v, %v", receipts, err)
+	}
+
+	other, _ := s.CreateChat(CreateChatInput{Name: "other", CreatedBy: "alice"})
+	if err := s.MarkRead(other.ID, "alice", first.ID); !errors.Is(err, repository.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound for message from another chat, got %v", err)
+	}
+}
+
+func TestChatService_ListMyChatsOrder(t *testing.T) {
+	s := newTestChatService()
+
+	older, _ := s.CreateChat(CreateChatInput{Name: "older", CreatedBy: "alice"})
+	newer, _ := s.CreateChat(CreateChatInput{Name: "newer", CreatedBy: "alice"})
+	s.CreateChat(CreateChatInput{Name: "foreign", CreatedBy: "bob"})
+
+	s.SendMessage(context.Background(), SendMessageInput{ChatID: older.ID, UserID: "alice", Content: "bump"})
+
+	summaries, err := s.ListMyChats(context.Background(), "alice", false)
+	if err != nil {
+		t.Fatalf("ListMyChats failed: %v", err)
+	}
+
+	if len(summaries) != 2 || summaries[0].Chat.ID != older.ID || summaries[1].Chat.ID != newer.ID {
+		t.Errorf("Expected chat with recent message first, got %+v", summaries)
+	}
+
+	// Свои сообщения не считаются непрочитанными
+	if summaries[0].UnreadCount != 0 {
+		t.Errorf("Expected own message to be read, got %d unread", summaries[0].UnreadCount)
+	}
+}
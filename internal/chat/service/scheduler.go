@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	maxScheduleAhead   = 365 * 24 * time.Hour
+	maxExpiresAfter    = 365 * 24 * time.Hour
+	schedulerInterval  = time.Second // Период runScheduler в Run
+	schedulerBatchSize = 100
+)
+
+var (
+	ErrInvalidSendAt       = errors.New("send_at must be in the future and at most 365 days ahead")
+	ErrInvalidExpiresAfter = errors.New("expires_after must be at most 365 days")
+	ErrBotScheduling       = errors.New("bots cannot schedule messages")
+)
+
+// ScheduleMessage откладывает отправку сообщения до sendAt. Проверки и лимиты
+// те же, что у SendMessage, и выполняются сразу; при отправке повторно
+// проверяется только, что автор все еще может писать в чат. Лимиты отправки
+// и медленный режим расходуются при планировании: наступившее сообщение
+// уходит без них, даже если медленный режим включен позже. Повтор с тем же
+// ClientMessageID, как у SendMessage, проверяется до лимитов: пока сообщение
+// ожидает отправки, возвращается оно же, а после отправки - его копия
+// с SendAt, равным времени отправки. Боты откладывать сообщения не могут.
+func (s *ChatService) ScheduleMessage(ctx context.Context, input SendMessageInput, sendAt time.Time) (*model.ScheduledMessage, error) {
+	if now := s.now(); !sendAt.After(now) || sendAt.Sub(now) > maxScheduleAhead {
+		return nil, ErrInvalidSendAt
+	}
+
+	isBot, err := s.users.IsBot(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if isBot {
+		return nil, ErrBotScheduling
+	}
+
+	if input.Poll != nil || input.Type == model.MessageTypePoll {
+		return nil, ErrScheduledPoll
+	}
+
+	if parseCommandLine(&input) != nil {
+		return nil, ErrScheduledCommand
+	}
+
+	if input.ClientMessageID != "" {
+		if scheduled, err := s.repeatedScheduledMessage(input); scheduled != nil || err != nil {
+			return scheduled, err
+		}
+	}
+
+	_, message, err := s.prepareMessage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := &model.ScheduledMessage{
+		ID:           message.ID,
+		ChatID:       message.ChatID,
+		UserID:       message.UserID,
+		Type:         message.Type,
+		Content:      message.Content,
+		Metadata:     message.Metadata,
+		ReplyTo:      message.ReplyTo,
+		ExpiresAfter: input.ExpiresAfter,
+		SendAt:       sendAt,
+		CreatedAt:    message.CreatedAt,
+	}
+
+	if input.ClientMessageID != "" {
+		scheduled.ClientMessageID = &input.ClientMessageID
+	}
+
+	scheduled, _, err = s.chatRepository.CreateScheduledMessage(scheduled)
+	return scheduled, err
+}
+
+// repeatedScheduledMessage ищет сообщение, уже отложенное или отправленное
+// с ключом input.ClientMessageID; nil без ошибки - повтора нет
+func (s *ChatService) repeatedScheduledMessage(input SendMessageInput) (*model.ScheduledMessage, error) {
+	scheduled, err := s.chatRepository.GetScheduledMessageByKey(input.ChatID, input.UserID, input.ClientMessageID)
+	if err == nil {
+		return scheduled, nil
+	}
+	if !errors.Is(err, repository.ErrScheduledMessageNotFound) {
+		return nil, err
+	}
+
+	since := s.now().Add(-s.config.MessageKeyRetention)
+	stored, err := s.chatRepository.GetMessageByKey(input.ChatID, input.UserID, input.ClientMessageID, since)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ScheduledMessage{
+		ID:              stored.ID,
+		ChatID:          stored.ChatID,
+		UserID:          stored.UserID,
+		Type:            stored.Type,
+		Content:         stored.Content,
+		Metadata:        stored.Metadata,
+		ReplyTo:         stored.ReplyTo,
+		ClientMessageID: &input.ClientMessageID,
+		SendAt:          stored.CreatedAt,
+		CreatedAt:       stored.CreatedAt,
+	}, nil
+}
+
+// ListScheduledMessages возвращает ожидающие отправки сообщения пользователя
+// в порядке отправки; chatID (необязательно) ограничивает выборку одним чатом
+func (s *ChatService) ListScheduledMessages(userID, chatID string) ([]*model.ScheduledMessage, error) {
+	return s.chatRepository.GetScheduledMessages(userID, chatID)
+}
+
+// CancelScheduledMessage отменяет отложенное сообщение. Отменить его может
+// только автор; для остальных сообщение не существует.
+func (s *ChatService) CancelScheduledMessage(id, userID string) error {
+	scheduled, err := s.chatRepository.GetScheduledMessage(id)
+	if err != nil {
+		return err
+	}
+
+	if scheduled.UserID != userID {
+		return repository.ErrScheduledMessageNotFound
+	}
+
+	return s.chatRepository.DeleteScheduledMessage(id)
+}
+
+// runScheduler отправляет наступившие отложенные сообщения, удаляет истекшие
+// и закрывает опросы с наступившим временем закрытия.
+// Очередь хранится в репозитории, поэтому после перезапуска сервиса
+// просроченные сообщения уходят при первом же вызове.
+func (s *ChatService) runScheduler(ctx context.Context) {
+	if err := s.deliverScheduledMessages(ctx); err != nil {
+		log.Printf("Failed to deliver scheduled messages: %v", err)
+	}
+
+	if err := s.expireMessages(); err != nil {
+		log.Printf("Failed to expire messages: %v", err)
+	}
+
+	if err := s.closeDuePolls(); err != nil {
+		log.Printf("Failed to close polls: %v", err)
+	}
+}
+
+// deliverScheduledMessages отправляет наступившие сообщения в порядке send_at.
+// Сообщение, которое не удалось отправить, пропускается до следующего цикла,
+// чтобы не задерживать остальные. Пропущенные остаются в начале очереди,
+// поэтому после пачки с ошибками следующая пачка не запрашивается.
+func (s *ChatService) deliverScheduledMessages(ctx context.Context) error {
+	for {
+		due, err := s.chatRepository.GetDueScheduledMessages(s.now(), schedulerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, scheduled := range due {
+			if err := s.deliverScheduledMessage(ctx, scheduled); err != nil {
+				log.Printf("Failed to deliver scheduled message %s: %v", scheduled.ID, err)
+				failed++
+			}
+		}
+
+		if len(due) < schedulerBatchSize || failed > 0 {
+			return nil
+		}
+	}
+}
+
+// deliverScheduledMessage создает сообщение из отложенного. Если автор
+// больше не может писать в чат, сообщение отменяется.
+func (s *ChatService) deliverScheduledMessage(ctx context.Context, scheduled *model.ScheduledMessage) error {
+	chat, err := s.requireParticipant(scheduled.ChatID, scheduled.UserID)
+	if err == nil {
+		err = requireActive(chat)
+	}
+	if err == nil && chat.IsAnnouncement && participantRank(chat, scheduled.UserID) < rankAdmin {
+		err = ErrAnnouncementOnly
+	}
+
+	switch {
+	case errors.Is(err, ErrNotParticipant), errors.Is(err, ErrChatArchived),
+		errors.Is(err, ErrAnnouncementOnly), errors.Is(err, repository.ErrChatNotFound):
+		log.Printf("Dropping scheduled message %s: %v", scheduled.ID, err)
+		err := s.chatRepository.DeleteScheduledMessage(scheduled.ID)
+		if errors.Is(err, repository.ErrScheduledMessageNotFound) {
+			return nil
+		}
+		return err
+	case err != nil:
+		return err
+	}
+
+	message := &model.Message{
+		ID:        scheduled.ID,
+		ChatID:    scheduled.ChatID,
+		UserID:    scheduled.UserID,
+		Type:      scheduled.Type,
+		Content:   scheduled.Content,
+		Metadata:  scheduled.Metadata,
+		ReplyTo:   scheduled.ReplyTo,
+		CreatedAt: s.now(),
+	}
+
+	if scheduled.ExpiresAfter > 0 {
+		expiresAt := message.CreatedAt.Add(scheduled.ExpiresAfter)
+		message.ExpiresAt = &expiresAt
+	}
+
+	message.Mentions = s.resolveMentions(ctx, chat, message.UserID, message.Content)
+
+	var clientMessageID string
+	if scheduled.ClientMessageID != nil {
+		clientMessageID = *scheduled.ClientMessageID
+	}
+
+	since := message.CreatedAt.Add(-s.config.MessageKeyRetention)
+	created, err := s.chatRepository.DeliverScheduledMessage(message, clientMessageID, since)
+	if err != nil {
+		if errors.Is(err, repository.ErrScheduledMessageNotFound) {
+			return nil // Отменено, пока сообщение готовилось к отправке
+		}
+		return err
+	}
+
+	if !created {
+		// Ключ уже занят сообщением, отправленным напрямую через SendMessage
+		log.Printf("Dropping scheduled message %s: client message %q is already sent", message.ID, clientMessageID)
+		return nil
+	}
+
+	return s.messageCreated(message)
+}
+
+// expireMessages превращает в "надгробия" сообщения с истекшим временем
+// жизни. Как и в deliverScheduledMessages, сообщение, которое не удалось
+// удалить, пропускается до следующего цикла.
+func (s *ChatService) expireMessages() error {
+	for {
+		expired, err := s.chatRepository.GetExpiredMessages(s.now(), schedulerBatchSize)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, message := range expired {
+			if err := s.tombstone(message, message.UserID); err != nil {
+				log.Printf("Failed to expire message %s: %v", message.ID, err)
+				failed++
+			}
+		}
+
+		if len(expired) < schedulerBatchSize || failed > 0 {
+			return nil
+		}
+	}
+}
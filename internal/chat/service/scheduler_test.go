@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+func newSchedulerTestService(repo repository.ChatRepository) (*ChatService, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewChatService(repo, newTestUserDirectory(), ChatServiceConfig{})
+	s.now = clock.Now
+	return s, clock
+}
+
+func TestChatService_ScheduleMessage(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	if _, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "late"}, clock.Now()); !errors.Is(err, ErrInvalidSendAt) {
+		t.Errorf("Expected ErrInvalidSendAt for the current time, got %v", err)
+	}
+	if _, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "far"}, clock.Now().Add(maxScheduleAhead+time.Hour)); !errors.Is(err, ErrInvalidSendAt) {
+		t.Errorf("Expected ErrInvalidSendAt too far ahead, got %v", err)
+	}
+	if _, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "bob", Content: "hi"}, clock.Now().Add(time.Minute)); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+
+	// Запланированные не по порядку сообщения уходят в порядке send_at
+	second, err := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "second"}, clock.Now().Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("ScheduleMessage failed: %v", err)
+	}
+	first, _ := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "first"}, clock.Now().Add(time.Minute))
+
+	scheduled, _ := s.ListScheduledMessages("owner", chat.ID)
+	if len(scheduled) != 2 || scheduled[0].ID != first.ID || scheduled[1].ID != second.ID {
+		t.Fatalf("Expected scheduled messages in send_at order, got %d", len(scheduled))
+	}
+	if others, _ := s.ListScheduledMessages("alice", ""); len(others) != 0 {
+		t.Errorf("Expected scheduled messages to be visible only to the author, got %d", len(others))
+	}
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	defer sub.Close()
+
+	clock.Advance(30 * time.Second)
+	s.runScheduler(ctx)
+	if event := nextEvent(sub); event != nil {
+		t.Fatalf("Expected nothing to be delivered early, got %+v", event)
+	}
+
+	clock.Advance(5 * time.Minute)
+	s.runScheduler(ctx)
+
+	for _, want := range []string{first.ID, second.ID} {
+		event := nextEvent(sub)
+		if event == nil || event.Type != model.EventMessageCreated || event.Message.ID != want {
+			t.Fatalf("Expected message.created for %s, got %+v", want, event)
+		}
+		if !event.Message.CreatedAt.Equal(clock.Now()) {
+			t.Errorf("Expected created_at of the delivery time, got %v", event.Message.CreatedAt)
+		}
+	}
+
+	messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID})
+	if len(messages) != 2 || messages[0].Content != "first" || messages[1].Content != "second" {
+		t.Errorf("Expected both messages in the history, got %d", len(messages))
+	}
+
+	if scheduled, _ := s.ListScheduledMessages("owner", ""); len(scheduled) != 0 {
+		t.Errorf("Expected no pending messages after delivery, got %d", len(scheduled))
+	}
+}
+
+func TestChatService_ScheduleMessageIdempotent(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	if _, err := s.SetSlowMode(chat.ID, "owner", 30); err != nil {
+		t.Fatalf("SetSlowMode failed: %v", err)
+	}
+
+	input := SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "reminder", ClientMessageID: "key"}
+	scheduled, err := s.ScheduleMessage(ctx, input, clock.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleMessage failed: %v", err)
+	}
+
+	// Повтор проверяется до медленного режима и не упирается в него
+	retry, err := s.ScheduleMessage(ctx, input, clock.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleMessage retry failed: %v", err)
+	}
+	if retry.ID != scheduled.ID || !retry.SendAt.Equal(scheduled.SendAt) {
+		t.Errorf("Expected the retry to return the pending message, got %+v", retry)
+	}
+
+	if pending, _ := s.ListScheduledMessages("alice", ""); len(pending) != 1 {
+		t.Errorf("Expected 1 pending message, got %d", len(pending))
+	}
+
+	clock.Advance(2 * time.Hour)
+	s.runScheduler(ctx)
+
+	// Отправка запоминает ключ: повторы после нее возвращают отправленное сообщение
+	sent, err := s.SendMessage(ctx, input)
+	if err != nil {
+		t.Fatalf("SendMessage retry failed: %v", err)
+	}
+	if sent.ID != scheduled.ID {
+		t.Errorf("Expected the retry to return the delivered message, got %s", sent.ID)
+	}
+
+	retry, err = s.ScheduleMessage(ctx, input, clock.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleMessage retry failed: %v", err)
+	}
+	if retry.ID != scheduled.ID || !retry.SendAt.Equal(sent.CreatedAt) {
+		t.Errorf("Expected the retry to return the delivered message, got %+v", retry)
+	}
+
+	if pending, _ := s.ListScheduledMessages("alice", ""); len(pending) != 0 {
+		t.Errorf("Expected no pending messages, got %d", len(pending))
+	}
+	if messages, _, _ := s.GetMessages("alice", repository.MessageQuery{ChatID: chat.ID}); len(messages) != 1 {
+		t.Errorf("Expected a single message in the history, got %d", len(messages))
+	}
+}
+
+func TestChatService_CancelScheduledMessage(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	scheduled, _ := s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "oops"}, clock.Now().Add(time.Minute))
+
+	if err := s.CancelScheduledMessage(scheduled.ID, "alice"); !errors.Is(err, repository.ErrScheduledMessageNotFound) {
+		t.Errorf("Expected ErrScheduledMessageNotFound for another user, got %v", err)
+	}
+
+	if err := s.CancelScheduledMessage(scheduled.ID, "owner"); err != nil {
+		t.Fatalf("CancelScheduledMessage failed: %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	s.runScheduler(context.Background())
+
+	if messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID}); len(messages) != 0 {
+		t.Errorf("Expected the cancelled message not to be sent, got %d messages", len(messages))
+	}
+
+	if err := s.CancelScheduledMessage(scheduled.ID, "owner"); !errors.Is(err, repository.ErrScheduledMessageNotFound) {
+		t.Errorf("Expected ErrScheduledMessageNotFound after cancel, got %v", err)
+	}
+}
+
+func TestChatService_ScheduledMessagesSurviveRestart(t *testing.T) {
+	repo := repository.NewInMemoryChatRepository()
+	s, clock := newSchedulerTestService(repo)
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	scheduled, _ := s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "@alice good morning"}, clock.Now().Add(8*time.Hour))
+
+	// Сервис перезапущен уже после наступления send_at
+	restarted, clock := newSchedulerTestService(repo)
+	clock.Advance(9 * time.Hour)
+	restarted.runScheduler(context.Background())
+
+	message, err := restarted.chatRepository.GetMessageByID(scheduled.ID)
+	if err != nil {
+		t.Fatalf("Expected the overdue message to be delivered after restart: %v", err)
+	}
+	if message.Seq != 1 || message.Content != "@alice good morning" {
+		t.Errorf("Unexpected delivered message: %+v", message)
+	}
+
+	mentions, _, _ := restarted.ListMentions(repository.MentionQuery{UserID: "alice"})
+	if len(mentions) != 1 || mentions[0].Message.ID != scheduled.ID {
+		t.Errorf("Expected mentions to be resolved at delivery, got %d", len(mentions))
+	}
+}
+
+func TestChatService_ScheduledMessageDropped(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "bye"}, clock.Now().Add(time.Minute))
+
+	if err := s.LeaveChat(chat.ID, "alice"); err != nil {
+		t.Fatalf("LeaveChat failed: %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	s.runScheduler(context.Background())
+
+	messages, _, _ := s.GetMessages("owner", repository.MessageQuery{ChatID: chat.ID})
+	for _, message := range messages {
+		if message.UserID == "alice" && message.Type != model.MessageTypeSystem {
+			t.Errorf("Expected the message of a former participant to be dropped, got %+v", message)
+		}
+	}
+
+	if pending, _ := s.ListScheduledMessages("alice", ""); len(pending) != 0 {
+		t.Errorf("Expected the dropped message to leave the queue, got %d", len(pending))
+	}
+}
+
+// failingDeliveryRepository не может отправить отложенное сообщение failID
+type failingDeliveryRepository struct {
+	repository.ChatRepository
+	failID string
+}
+
+func (r *failingDeliveryRepository) DeliverScheduledMessage(message *model.Message, clientMessageID string, since time.Time) (bool, error) {
+	if message.ID == r.failID {
+		return false, errors.New("database is unavailable")
+	}
+	return r.ChatRepository.DeliverScheduledMessage(message, clientMessageID, since)
+}
+
+func TestChatService_ScheduledMessageFailureSkipped(t *testing.T) {
+	repo := &failingDeliveryRepository{ChatRepository: repository.NewInMemoryChatRepository()}
+	s, clock := newSchedulerTestService(repo)
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	failing, _ := s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "first"}, clock.Now().Add(time.Minute))
+	next, _ := s.ScheduleMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "second"}, clock.Now().Add(2*time.Minute))
+	repo.failID = failing.ID
+
+	clock.Advance(time.Hour)
+	s.runScheduler(context.Background())
+
+	if _, err := s.chatRepository.GetMessageByID(next.ID); err != nil {
+		t.Errorf("Expected the failure not to block the next message: %v", err)
+	}
+	if pending, _ := s.ListScheduledMessages("owner", ""); len(pending) != 1 || pending[0].ID != failing.ID {
+		t.Fatalf("Expected the failed message to stay queued, got %d", len(pending))
+	}
+
+	repo.failID = ""
+	s.runScheduler(context.Background())
+	if _, err := s.chatRepository.GetMessageByID(failing.ID); err != nil {
+		t.Errorf("Expected the failed message to be retried: %v", err)
+	}
+}
+
+// failingUpdateRepository не может изменить сообщение failID
+type failingUpdateRepository struct {
+	repository.ChatRepository
+	failID string
+}
+
+func (r *failingUpdateRepository) UpdateMessage(message *model.Message, revision *model.MessageRevision) error {
+	if message.ID == r.failID {
+		return errors.New("database is unavailable")
+	}
+	return r.ChatRepository.UpdateMessage(message, revision)
+}
+
+func TestChatService_ExpiringMessageFailureSkipped(t *testing.T) {
+	repo := &failingUpdateRepository{ChatRepository: repository.NewInMemoryChatRepository()}
+	s, clock := newSchedulerTestService(repo)
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	failing, _ := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "first", ExpiresAfter: time.Second})
+	other, _ := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "second", ExpiresAfter: 2 * time.Second})
+	repo.failID = failing.ID
+
+	clock.Advance(time.Minute)
+	s.runScheduler(ctx)
+
+	// Ошибка на первом сообщении не задерживает остальные
+	if stored, _ := s.chatRepository.GetMessageByID(other.ID); !stored.IsDeleted() {
+		t.Errorf("Expected the other message to expire, got %+v", stored)
+	}
+	if stored, _ := s.chatRepository.GetMessageByID(failing.ID); stored.IsDeleted() {
+		t.Fatalf("Expected the failed message to stay, got %+v", stored)
+	}
+
+	repo.failID = ""
+	s.runScheduler(ctx)
+	if stored, _ := s.chatRepository.GetMessageByID(failing.ID); !stored.IsDeleted() {
+		t.Errorf("Expected the failed message to be retried, got %+v", stored)
+	}
+}
+
+func TestChatService_ExpiringMessages(t *testing.T) {
+	s, clock := newSchedulerTestService(repository.NewInMemoryChatRepository())
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "x", ExpiresAfter: -time.Second}); !errors.Is(err, ErrInvalidExpiresAfter) {
+		t.Errorf("Expected ErrInvalidExpiresAfter, got %v", err)
+	}
+
+	message, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "secret", ExpiresAfter: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if message.ExpiresAt == nil || !message.ExpiresAt.Equal(clock.Now().Add(10*time.Second)) {
+		t.Fatalf("Unexpected expires_at: %v", message.ExpiresAt)
+	}
+	if _, err := s.PinMessage(message.ID, "owner"); err != nil {
+		t.Fatalf("PinMessage failed: %v", err)
+	}
+
+	// Время жизни отложенного сообщения отсчитывается от отправки
+	scheduled, _ := s.ScheduleMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "later", ExpiresAfter: time.Minute}, clock.Now().Add(5*time.Second))
+
+	sub, _ := s.SubscribeChat(chat.ID, "alice")
+	defer sub.Close()
+
+	clock.Advance(5 * time.Second)
+	s.runScheduler(ctx)
+	if event := nextEvent(sub); event == nil || event.Type != model.EventMessageCreated || event.Message.ID != scheduled.ID {
+		t.Fatalf("Expected the scheduled message to be delivered, got %+v", event)
+	}
+
+	clock.Advance(5 * time.Second)
+	s.runScheduler(ctx)
+
+	for _, want := range []string{model.EventMessageUnpinned, model.EventMessageDeleted} {
+		event := nextEvent(sub)
+		if event == nil || event.Type != want || event.Message.ID != message.ID {
+			t.Fatalf("Expected %s for the expired message, got %+v", want, event)
+		}
+	}
+
+	expired, _ := s.chatRepository.GetMessageByID(message.ID)
+	if !expired.IsDeleted() || expired.Content != "" {
+		t.Errorf("Expected the expired message to be tombstoned, got %+v", expired)
+	}
+
+	delivered, _ := s.chatRepository.GetMessageByID(scheduled.ID)
+	if delivered.IsDeleted() || !delivered.ExpiresAt.Equal(clock.Now().Add(55*time.Second)) {
+		t.Errorf("Expected the delivered message to live a minute from delivery, got %+v", delivered)
+	}
+
+	// Повторный проход не трогает уже удаленное сообщение
+	s.runScheduler(ctx)
+	if event := nextEvent(sub); event != nil {
+		t.Errorf("Expected no more events, got %+v", event)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+
+	"golang-chat/internal/chat/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+var ErrEmptySearchQuery = errors.New("search query must contain at least one word")
+
+// SearchMessages ищет сообщения в чатах, где состоит query.UserID.
+// Если указан query.ChatID, пользователь должен быть его участником.
+// Второй результат сообщает, есть ли следующая страница.
+func (s *ChatService) SearchMessages(query repository.SearchQuery) ([]*repository.SearchHit, bool, error) {
+	if len(repository.SearchTerms(query.Text)) == 0 {
+		return nil, false, ErrEmptySearchQuery
+	}
+
+	if query.ChatID != "" {
+		if _, err := s.requireParticipant(query.ChatID, query.UserID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	query.Limit = min(query.Limit, maxSearchLimit)
+	query.Offset = max(query.Offset, 0)
+
+	// Запрашиваем на один результат больше, чтобы узнать, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+
+	hits, err := s.chatRepository.SearchMessages(query)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+
+	return hits, hasMore, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang-chat/internal/chat/repository"
+)
+
+func TestChatService_SearchMessages(t *testing.T) {
+	s := newTestChatService()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+	private, _ := s.CreateChat(CreateChatInput{Name: "private", CreatedBy: "bob"})
+
+	for i := range 3 {
+		s.SendMessage(context.Background(), SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: fmt.Sprintf("release %d is out", i)})
+	}
+	s.SendMessage(context.Background(), SendMessageInput{ChatID: private.ID, UserID: "bob", Content: "secret release"})
+
+	hits, hasMore, err := s.SearchMessages(repository.SearchQuery{UserID: "alice", Text: "release", Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchMessages failed: %v", err)
+	}
+	if len(hits) != 2 || !hasMore || hits[0].Message.Content != "release 2 is out" {
+		t.Errorf("Unexpected first page: %d hits, hasMore=%v", len(hits), hasMore)
+	}
+
+	hits, hasMore, _ = s.SearchMessages(repository.SearchQuery{UserID: "alice", Text: "release", Limit: 2, Offset: 2})
+	if len(hits) != 1 || hasMore {
+		t.Errorf("Unexpected last page: %d hits, hasMore=%v", len(hits), hasMore)
+	}
+
+	if _, _, err := s.SearchMessages(repository.SearchQuery{UserID: "alice", Text: "release", ChatID: private.ID}); !errors.Is(err, ErrNotParticipant) {
+		t.Errorf("Expected ErrNotParticipant, got %v", err)
+	}
+
+	if _, _, err := s.SearchMessages(repository.SearchQuery{UserID: "alice", Text: " ... "}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Errorf("Expected ErrEmptySearchQuery, got %v", err)
+	}
+}
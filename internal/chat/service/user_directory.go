@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang-chat/proto/auth"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+const (
+	userCacheTTL   = time.Minute // Сколько помнить ответ UserService.Get
+	maxCachedUsers = 10000       // После этого устаревшие записи вычищаются
+)
+
+// UserDirectory - справочник пользователей Auth Service
+type UserDirectory interface {
+	// Username возвращает имя пользователя или ErrUserNotFound
+	Username(ctx context.Context, userID string) (string, error)
+
+	// IsBot сообщает, является ли пользователь ботом, или возвращает ErrUserNotFound
+	IsBot(ctx context.Context, userID string) (bool, error)
+}
+
+// authUserDirectory получает пользователей через UserService.Get и
+// кэширует их на userCacheTTL: разбор упоминаний спрашивает имена
+// участников чата при каждом сообщении
+type authUserDirectory struct {
+	client auth.UserServiceClient
+	now    func() time.Time
+
+	mu    sync.Mutex
+	users map[string]cachedUser
+}
+
+type cachedUser struct {
+	user    *auth.User
+	expires time.Time
+}
+
+// NewAuthUserDirectory создает справочник поверх клиента UserService
+func NewAuthUserDirectory(client auth.UserServiceClient) UserDirectory {
+	return newAuthUserDirectory(client, time.Now)
+}
+
+func newAuthUserDirectory(client auth.UserServiceClient, now func() time.Time) *authUserDirectory {
+	return &authUserDirectory{client: client, now: now, users: make(map[string]cachedUser)}
+}
+
+func (d *authUserDirectory) Username(ctx context.Context, userID string) (string, error) {
+	user, err := d.get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return user.Username, nil
+}
+
+func (d *authUserDirectory) IsBot(ctx context.Context, userID string) (bool, error) {
+	user, err := d.get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.IsBot, nil
+}
+
+func (d *authUserDirectory) get(ctx context.Context, userID string) (*auth.User, error) {
+	if user := d.cached(userID); user != nil {
+		return user, nil
+	}
+
+	resp, err := d.client.Get(ctx, &auth.GetUserRequest{Id: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	// UserService сообщает об отсутствии пользователя только текстом ошибки
+	if resp.Error != "" || resp.User == nil {
+		return nil, ErrUserNotFound
+	}
+
+	d.store(userID, resp.User)
+	return resp.User, nil
+}
+
+func (d *authUserDirectory) cached(userID string) *auth.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.users[userID]
+	if !ok || !d.now().Before(entry.expires) {
+		return nil
+	}
+	return entry.user
+}
+
+func (d *authUserDirectory) store(userID string, user *auth.User) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if len(d.users) >= maxCachedUsers {
+		for id, entry := range d.users {
+			if !now.Before(entry.expires) {
+				delete(d.users, id)
+			}
+		}
+	}
+	if len(d.users) >= maxCachedUsers {
+		d.users = make(map[string]cachedUser)
+	}
+
+	d.users[userID] = cachedUser{user: user, expires: now.Add(userCacheTTL)}
+}
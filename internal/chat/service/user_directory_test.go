@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"golang-chat/proto/auth"
+)
+
+// countingUserClient отвечает на UserService.Get из users и считает запросы
+type countingUserClient struct {
+	auth.UserServiceClient
+	users map[string]*auth.User
+	calls int
+}
+
+func (c *countingUserClient) Get(ctx context.Context, req *auth.GetUserRequest, opts ...grpc.CallOption) (*auth.GetUserResponse, error) {
+	c.calls++
+	user, ok := c.users[req.Id]
+	if !ok {
+		return &auth.GetUserResponse{Error: "user not found"}, nil
+	}
+	return &auth.GetUserResponse{User: user}, nil
+}
+
+func TestAuthUserDirectory_Cache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	client := &countingUserClient{users: map[string]*auth.User{
+		"alice":      {Id: "alice", Username: "Alice"},
+		"deploy-bot": {Id: "deploy-bot", Username: "deploy", IsBot: true},
+	}}
+	d := newAuthUserDirectory(client, clock.Now)
+	ctx := context.Background()
+
+	for range 3 {
+		if username, err := d.Username(ctx, "alice"); err != nil || username != "Alice" {
+			t.Fatalf("Expected Alice, got %q, %v", username, err)
+		}
+	}
+	if isBot, err := d.IsBot(ctx, "deploy-bot"); err != nil || !isBot {
+		t.Errorf("Expected deploy-bot to be a bot, got %v, %v", isBot, err)
+	}
+	if client.calls != 2 {
+		t.Errorf("Expected one request per user, got %d", client.calls)
+	}
+
+	// Отсутствие пользователя не кэшируется
+	for range 2 {
+		if _, err := d.Username(ctx, "nobody"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	}
+	if client.calls != 4 {
+		t.Errorf("Expected missing users to be requested again, got %d requests", client.calls)
+	}
+
+	client.users["alice"] = &auth.User{Id: "alice", Username: "Alicia"}
+	clock.Advance(userCacheTTL)
+	if username, _ := d.Username(ctx, "alice"); username != "Alicia" {
+		t.Errorf("Expected the cache to expire, got %q", username)
+	}
+}
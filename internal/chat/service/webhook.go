@@ -0,0 +1,713 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhooksPerChat     = 10
+	maxWebhookURLLength    = 2048 // chat_webhooks.url VARCHAR(2048)
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 128 // chat_webhooks.secret VARCHAR(128)
+	webhookSecretBytes     = 32
+
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookRetryBase   = 10 * time.Second
+	defaultWebhookTimeout     = 10 * time.Second
+	maxWebhookRetryDelay      = time.Hour
+
+	webhookDispatchInterval = time.Second // Период dispatchWebhooks в runWebhooks
+	webhookBatchSize        = 100
+	webhookWorkers          = 8        // Одновременных запросов к получателям
+	maxWebhookResponseBytes = 64 << 10 // Сколько читать из ответа получателя
+	maxWebhookErrorLength   = 1024
+
+	defaultWebhookDeliveriesLimit = 20
+	maxWebhookDeliveriesLimit     = 100
+
+	// Сколько помнить webhook'и чата в enqueueWebhooks. Свои изменения
+	// сбрасывают кэш сразу; webhook, созданный или удаленный другим
+	// экземпляром сервиса, учитывается не позже чем через этот срок.
+	webhookCacheTTL       = 10 * time.Second
+	maxCachedWebhookChats = 10000 // После этого устаревшие записи вычищаются
+)
+
+var (
+	ErrInvalidWebhookURL    = errors.New("webhook url must be an absolute http(s) url of at most 2048 characters")
+	ErrWebhookAddress       = errors.New("webhook url must resolve to public addresses only")
+	ErrInvalidWebhookSecret = errors.New("webhook secret must be 16-128 characters")
+	ErrInvalidWebhookEvent  = errors.New("unsupported webhook event")
+	ErrWebhookLimit         = errors.New("chat has reached its webhooks limit")
+	ErrDeliveryNotDead      = errors.New("only dead webhook deliveries can be retried")
+)
+
+// Заголовки запроса доставки webhook'а
+const (
+	WebhookEventHeader     = "X-Chat-Event"
+	WebhookDeliveryHeader  = "X-Chat-Delivery"
+	WebhookTimestampHeader = "X-Chat-Timestamp"
+	WebhookSignatureHeader = "X-Chat-Signature"
+)
+
+// WebhookSignature подписывает тело доставки: "sha256=" и HMAC-SHA256
+// по secret от строки "<timestamp>.<body>" в hex. Получатель вычисляет
+// подпись так же и сравнивает с заголовком X-Chat-Signature; по
+// X-Chat-Timestamp он может отклонять повторы старых запросов.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhookInput - параметры регистрации webhook'а
+type CreateWebhookInput struct {
+	ChatID string
+	UserID string // Кто регистрирует webhook
+	URL    string
+	Secret string   // Пусто - сгенерировать
+	Events []string // Пусто - все model.WebhookEventTypes
+}
+
+// CreateWebhook регистрирует webhook чата. Доступно администраторам чата.
+// Секрет возвращается только здесь: списки webhook'ов его не раскрывают.
+func (s *ChatService) CreateWebhook(ctx context.Context, input CreateWebhookInput) (*model.Webhook, error) {
+	host, err := validateWebhookURL(input.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Secret != "" && (len(input.Secret) < minWebhookSecretLength || len(input.Secret) > maxWebhookSecretLength) {
+		return nil, ErrInvalidWebhookSecret
+	}
+
+	events := slices.Compact(slices.Sorted(slices.Values(input.Events)))
+	for _, event := range events {
+		if !slices.Contains(model.WebhookEventTypes, event) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidWebhookEvent, event)
+		}
+	}
+
+	chat, err := s.requireRank(input.ChatID, input.UserID, rankAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireActive(chat); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.chatRepository.GetWebhooks(input.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerChat {
+		return nil, ErrWebhookLimit
+	}
+
+	if err := s.checkWebhookHost(ctx, host); err != nil {
+		return nil, err
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &model.Webhook{
+		ID:        uuid.New().String(),
+		ChatID:    input.ChatID,
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: input.UserID,
+		CreatedAt: s.now(),
+	}
+
+	if err := s.chatRepository.CreateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	s.webhooks.invalidate(webhook.ChatID)
+
+	return webhook, nil
+}
+
+// ListWebhooks возвращает webhook'и чата без секретов. Доступно администраторам чата.
+func (s *ChatService) ListWebhooks(chatID, userID string) ([]*model.Webhook, error) {
+	if _, err := s.requireRank(chatID, userID, rankAdmin); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.chatRepository.GetWebhooks(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет webhook и его журнал доставок; недоставленные
+// события больше не отправляются. Доступно администраторам чата.
+func (s *ChatService) DeleteWebhook(id, userID string) error {
+	webhook, err := s.webhookAdmin(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.chatRepository.DeleteWebhook(webhook.ID); err != nil {
+		return err
+	}
+	s.webhooks.invalidate(webhook.ChatID)
+
+	return nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок webhook'а от новых
+// к старым; с query.Status = dead - список недоставленных событий.
+// Доступно администраторам чата. Второй результат сообщает, есть ли
+// следующая страница.
+func (s *ChatService) ListWebhookDeliveries(userID string, query repository.WebhookDeliveryQuery) ([]*model.WebhookDelivery, bool, error) {
+	if _, err := s.webhookAdmin(query.WebhookID, userID); err != nil {
+		return nil, false, err
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultWebhookDeliveriesLimit
+	}
+	query.Limit = min(query.Limit, maxWebhookDeliveriesLimit)
+	query.Offset = max(query.Offset, 0)
+
+	// Запрашиваем на одну доставку больше, чтобы узнать, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+
+	deliveries, err := s.chatRepository.GetWebhookDeliveries(query)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(deliveries) > limit
+	if hasMore {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, hasMore, nil
+}
+
+// RetryWebhookDelivery возвращает недоставленное событие в очередь
+// с новым счетчиком попыток. Доступно администраторам чата.
+func (s *ChatService) RetryWebhookDelivery(id, userID string) (*model.WebhookDelivery, error) {
+	delivery, err := s.chatRepository.GetWebhookDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.webhookAdmin(delivery.WebhookID, userID); err != nil {
+		return nil, err
+	}
+
+	if delivery.Status != model.WebhookDeliveryDead {
+		return nil, ErrDeliveryNotDead
+	}
+
+	now := s.now()
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+
+	if err := s.chatRepository.UpdateWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// webhookAdmin возвращает webhook, если userID - администратор его чата.
+// Для остальных пользователей webhook не существует.
+func (s *ChatService) webhookAdmin(id, userID string) (*model.Webhook, error) {
+	webhook, err := s.chatRepository.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireRank(webhook.ChatID, userID, rankAdmin); err != nil {
+		if errors.Is(err, ErrPermissionDenied) || errors.Is(err, ErrNotParticipant) {
+			return nil, repository.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// enqueueWebhooks ставит событие в очередь доставки подходящим webhook'ам
+// его чата. Очередь хранится в репозитории, поэтому события не теряются
+// при перезапуске сервиса или недоступности получателя. Webhook'и чата
+// берутся из кэша: у большинства чатов их нет, и запрос к репозиторию
+// на каждое событие был бы лишним.
+func (s *ChatService) enqueueWebhooks(event *model.ChatEvent) {
+	if !slices.Contains(model.WebhookEventTypes, event.Type) {
+		return
+	}
+
+	cached, err := s.chatWebhooks(event.ChatID)
+	if err != nil {
+		log.Printf("Failed to get webhooks of chat %s: %v", event.ChatID, err)
+		return
+	}
+
+	var webhooks []*model.Webhook
+	for _, webhook := range cached {
+		if webhook.Accepts(event.Type) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event for webhooks: %v", event.Type, err)
+		return
+	}
+
+	now := s.now()
+	deliveries := make([]*model.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = &model.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     webhook.ID,
+			ChatID:        event.ChatID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		}
+	}
+
+	if err := s.chatRepository.CreateWebhookDeliveries(deliveries); err != nil {
+		log.Printf("Failed to enqueue %s event for webhooks: %v", event.Type, err)
+	}
+}
+
+// chatWebhooks возвращает webhook'и чата из кэша или репозитория.
+// Результат общий для всех вызовов и не должен изменяться.
+func (s *ChatService) chatWebhooks(chatID string) ([]*model.Webhook, error) {
+	if webhooks, ok := s.webhooks.get(chatID); ok {
+		return webhooks, nil
+	}
+
+	version := s.webhooks.version()
+	webhooks, err := s.chatRepository.GetWebhooks(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.webhooks.store(chatID, webhooks, version)
+	return webhooks, nil
+}
+
+// runWebhooks доставляет события webhook'ам до отмены ctx. Работает
+// отдельно от остальных задач Run, чтобы медленные получатели
+// не задерживали планировщик сообщений.
+func (s *ChatService) runWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(webhookDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.dispatchWebhooks(ctx); err != nil {
+				log.Printf("Failed to dispatch webhooks: %v", err)
+			}
+		}
+	}
+}
+
+// dispatchWebhooks выполняет по одной попытке для наступивших доставок,
+// не больше webhookWorkers запросов одновременно, и ждет их завершения.
+// Доставка гарантируется не менее одного раза: если сервис упадет между
+// ответом получателя и сохранением результата, запрос будет повторен.
+func (s *ChatService) dispatchWebhooks(ctx context.Context) error {
+	due, err := s.chatRepository.GetDueWebhookDeliveries(s.now(), webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	workers := make(chan struct{}, webhookWorkers)
+	webhooks := make(map[string]*model.Webhook)
+
+	for _, delivery := range due {
+		webhook, exists := webhooks[delivery.WebhookID]
+		if !exists {
+			webhook, err = s.chatRepository.GetWebhook(delivery.WebhookID)
+			if errors.Is(err, repository.ErrWebhookNotFound) {
+				// Удален вместе с журналом после выборки или удален другим
+				// экземпляром сервиса, пока был в его кэше
+				s.abandonWebhookDelivery(delivery)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		// Пока попытка идет, доставка не считается наступившей
+		leaseUntil := s.now().Add(2 * s.config.WebhookTimeout)
+		claimed, err := s.chatRepository.ClaimWebhookDelivery(delivery.ID, delivery.Attempts, leaseUntil)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		delivery.Attempts++
+
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			s.attemptWebhookDelivery(ctx, webhook, delivery)
+		}()
+	}
+
+	return nil
+}
+
+// abandonWebhookDelivery переносит доставку удаленного webhook'а
+// в недоставленные, чтобы она не оставалась в очереди
+func (s *ChatService) abandonWebhookDelivery(delivery *model.WebhookDelivery) {
+	delivery.Status = model.WebhookDeliveryDead
+	delivery.NextAttemptAt = nil
+	delivery.LastError = repository.ErrWebhookNotFound.Error()
+
+	err := s.chatRepository.UpdateWebhookDelivery(delivery)
+	if err != nil && !errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+		log.Printf("Failed to drop webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// attemptWebhookDelivery отправляет доставку и сохраняет результат: успех,
+// следующую попытку с экспоненциальной задержкой или, если попытки
+// исчерпаны, перенос в список недоставленных
+func (s *ChatService) attemptWebhookDelivery(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) {
+	status, err := s.postWebhook(ctx, webhook, delivery)
+
+	now := s.now()
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case err == nil:
+		delivery.Status = model.WebhookDeliveryDelivered
+	case delivery.Attempts >= s.config.WebhookMaxAttempts:
+		delivery.Status = model.WebhookDeliveryDead
+	default:
+		next := now.Add(webhookRetryDelay(s.config.WebhookRetryBase, delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err != nil {
+		delivery.LastError = truncateError(err.Error(), maxWebhookErrorLength)
+	}
+
+	err = s.chatRepository.UpdateWebhookDelivery(delivery)
+	if err != nil && !errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+		log.Printf("Failed to save webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// postWebhook отправляет подписанный запрос и возвращает HTTP статус ответа
+// (0, если ответа не было). Ошибкой считается любой ответ, кроме 2xx.
+func (s *ChatService) postWebhook(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.WebhookTimeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, WebhookSignature(webhook.Secret, timestamp, body))
+
+	response, err := s.webhookClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// Дочитываем ответ, чтобы соединение можно было переиспользовать
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxWebhookResponseBytes))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("receiver responded with %s", response.Status)
+	}
+
+	return response.StatusCode, nil
+}
+
+// webhookRetryDelay возвращает задержку перед попыткой attempts+1:
+// base, 2*base, 4*base... но не больше maxWebhookRetryDelay
+func webhookRetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWebhookRetryDelay)
+}
+
+// validateWebhookURL проверяет, что URL абсолютный с http или https,
+// и возвращает его хост
+func validateWebhookURL(rawURL string) (string, error) {
+	if len(rawURL) > maxWebhookURLLength {
+		return "", ErrInvalidWebhookURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", ErrInvalidWebhookURL
+	}
+
+	return parsed.Hostname(), nil
+}
+
+// checkWebhookHost разрешает хост webhook'а и проверяет, что все его адреса
+// публичные. При доставке адрес проверяется еще раз (см. newWebhookClient):
+// DNS может начать отвечать иначе уже после регистрации.
+func (s *ChatService) checkWebhookHost(ctx context.Context, host string) error {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !s.webhookAddressAllowed(ip) {
+			return fmt.Errorf("%w: %s", ErrWebhookAddress, ip)
+		}
+	}
+
+	return nil
+}
+
+// reservedNetworks - специальные диапазоны (RFC 6890), которые не покрывают
+// методы net.IP: за ними тоже может оказаться внутренняя сеть
+var reservedNetworks = parseNetworks(
+	"0.0.0.0/8",       // "Эта" сеть
+	"100.64.0.0/10",   // Carrier-grade NAT
+	"192.0.0.0/24",    // IETF Protocol Assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"198.18.0.0/15",   // Тестирование сетевого оборудования
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"240.0.0.0/4",     // Зарезервировано, включая broadcast
+	"64:ff9b::/96",    // NAT64: адрес IPv4 внутри IPv6
+	"64:ff9b:1::/48",  // NAT64 для локальных сетей
+	"100::/64",        // Discard-only
+	"2001:db8::/32",   // Документация
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// publicAddress сообщает, что ip не относится к loopback, частным,
+// link-local, неуказанным, multicast и reservedNetworks адресам:
+// webhook'и не должны открывать доступ к внутренней сети сервиса
+func publicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+
+	return !slices.ContainsFunc(reservedNetworks, func(network *net.IPNet) bool {
+		return network.Contains(ip)
+	})
+}
+
+// newWebhookClient создает HTTP клиент доставки, который проверяет адрес
+// каждого соединения, в том числе после редиректов и смены DNS ответа.
+// Прокси из окружения не используется: проверялся бы адрес прокси.
+func newWebhookClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Transport: transport}
+}
+
+// newWebhookSecret генерирует случайный секрет подписи
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// truncateError обрезает текст ошибки до limit байт по границе символа
+func truncateError(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+// webhookCache хранит webhook'и чатов на webhookCacheTTL. Версия
+// растет при каждом сбросе, и список, прочитанный из репозитория
+// до сброса, не сохраняется поверх более свежих данных.
+type webhookCache struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	chats   map[string]cachedWebhooks
+	current uint64
+}
+
+type cachedWebhooks struct {
+	webhooks []*model.Webhook
+	expires  time.Time
+}
+
+func newWebhookCache(now func() time.Time) *webhookCache {
+	return &webhookCache{now: now, chats: make(map[string]cachedWebhooks)}
+}
+
+func (c *webhookCache) get(chatID string) ([]*model.Webhook, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.chats[chatID]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.webhooks, true
+}
+
+// version возвращает текущую версию кэша для store
+func (c *webhookCache) version() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.current
+}
+
+// store запоминает webhook'и чата, если с version кэш не сбрасывался
+func (c *webhookCache) store(chatID string, webhooks []*model.Webhook, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.current {
+		return
+	}
+
+	now := c.now()
+	if len(c.chats) >= maxCachedWebhookChats {
+		for id, entry := range c.chats {
+			if !now.Before(entry.expires) {
+				delete(c.chats, id)
+			}
+		}
+	}
+	if len(c.chats) >= maxCachedWebhookChats {
+		c.chats = make(map[string]cachedWebhooks)
+	}
+
+	c.chats[chatID] = cachedWebhooks{webhooks: webhooks, expires: now.Add(webhookCacheTTL)}
+}
+
+// invalidate сбрасывает webhook'и чата после их изменения
+func (c *webhookCache) invalidate(chatID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.chats, chatID)
+	c.current++
+}
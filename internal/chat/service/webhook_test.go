@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-chat/internal/chat/model"
+	"golang-chat/internal/chat/repository"
+)
+
+// webhookReceiver записывает запросы и отвечает статусом status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*receivedWebhook
+}
+
+type receivedWebhook struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) (*webhookReceiver, *httptest.Server) {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, &receivedWebhook{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// take возвращает полученные запросы и очищает список
+func (r *webhookReceiver) take() []*receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := r.requests
+	r.requests = nil
+	return requests
+}
+
+func newWebhookTestService(config ChatServiceConfig) (*ChatService, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewChatService(repository.NewInMemoryChatRepository(), newTestUserDirectory(), config)
+	s.now = clock.Now
+	// Получатель httptest слушает loopback
+	s.webhookAddressAllowed = func(net.IP) bool { return true }
+	return s, clock
+}
+
+func TestChatService_Webhooks(t *testing.T) {
+	s, _ := newWebhookTestService(ChatServiceConfig{})
+	ctx := context.Background()
+	receiver, server := newWebhookReceiver(t)
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner", Participants: []string{"alice"}})
+
+	invalid := []CreateWebhookInput{
+		{ChatID: chat.ID, UserID: "owner", URL: "ftp://example.com/hook"},
+		{ChatID: chat.ID, UserID: "owner", URL: "/hook"},
+		{ChatID: chat.ID, UserID: "owner", URL: server.URL, Secret: "short"},
+	}
+	for _, input := range invalid {
+		if _, err := s.CreateWebhook(ctx, input); !errors.Is(err, ErrInvalidWebhookURL) && !errors.Is(err, ErrInvalidWebhookSecret) {
+			t.Errorf("Expected a validation error for %+v, got %v", input, err)
+		}
+	}
+	if _, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: server.URL, Events: []string{model.EventTypingStarted}}); !errors.Is(err, ErrInvalidWebhookEvent) {
+		t.Errorf("Expected ErrInvalidWebhookEvent, got %v", err)
+	}
+	if _, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "alice", URL: server.URL}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied for a member, got %v", err)
+	}
+
+	all, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: server.URL + "/all"})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	if len(all.Secret) < minWebhookSecretLength {
+		t.Errorf("Expected a generated secret, got %q", all.Secret)
+	}
+
+	joins, err := s.CreateWebhook(ctx, CreateWebhookInput{
+		ChatID: chat.ID,
+		UserID: "owner",
+		URL:    server.URL + "/joins",
+		Secret: "joins-secret-0123456789",
+		Events: []string{model.EventParticipantJoined},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+
+	webhooks, _ := s.ListWebhooks(chat.ID, "owner")
+	if len(webhooks) != 2 || webhooks[0].Secret != "" || webhooks[1].Secret != "" {
+		t.Errorf("Expected 2 webhooks without secrets, got %+v", webhooks)
+	}
+
+	message, _ := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "hello"})
+	if err := s.ConnectChat(chat.ID, "bob"); err != nil {
+		t.Fatalf("ConnectChat failed: %v", err)
+	}
+
+	if err := s.dispatchWebhooks(ctx); err != nil {
+		t.Fatalf("dispatchWebhooks failed: %v", err)
+	}
+
+	received := make(map[string][]*receivedWebhook)
+	for _, request := range receiver.take() {
+		received[request.header.Get(WebhookEventHeader)] = append(received[request.header.Get(WebhookEventHeader)], request)
+	}
+
+	// Сообщение alice и системное сообщение о вступлении bob
+	created := received[model.EventMessageCreated]
+	if len(created) != 2 || created[0].path != "/all" || created[1].path != "/all" {
+		t.Fatalf("Expected message.created to reach only the unfiltered webhook, got %d", len(created))
+	}
+
+	var request *receivedWebhook
+	var event model.ChatEvent
+	for _, candidate := range created {
+		if err := json.Unmarshal(candidate.body, &event); err != nil {
+			t.Fatalf("Failed to decode the payload: %v", err)
+		}
+		if event.Message != nil && event.Message.ID == message.ID {
+			request = candidate
+			break
+		}
+	}
+	if request == nil || event.Type != model.EventMessageCreated || event.UserID != "alice" || event.Message.Content != "hello" {
+		t.Fatalf("Expected the payload of the sent message, got %+v", event)
+	}
+	if request.header.Get(WebhookDeliveryHeader) == "" {
+		t.Error("Expected a delivery id header")
+	}
+
+	want := WebhookSignature(all.Secret, request.header.Get(WebhookTimestampHeader), request.body)
+	if got := request.header.Get(WebhookSignatureHeader); got != want {
+		t.Errorf("Expected signature %s, got %s", want, got)
+	}
+
+	joined := received[model.EventParticipantJoined]
+	if len(joined) != 2 {
+		t.Fatalf("Expected participant.joined to reach both webhooks, got %d", len(joined))
+	}
+	secrets := map[string]string{"/all": all.Secret, "/joins": joins.Secret}
+	for _, request := range joined {
+		want := WebhookSignature(secrets[request.path], request.header.Get(WebhookTimestampHeader), request.body)
+		if got := request.header.Get(WebhookSignatureHeader); got != want {
+			t.Errorf("Expected signature %s for %s, got %s", want, request.path, got)
+		}
+	}
+
+	deliveries, hasMore, err := s.ListWebhookDeliveries("owner", repository.WebhookDeliveryQuery{WebhookID: joins.ID})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 1 || hasMore || deliveries[0].Status != model.WebhookDeliveryDelivered || deliveries[0].ResponseStatus != http.StatusOK {
+		t.Errorf("Expected 1 delivered delivery in the log, got %+v", deliveries)
+	}
+
+	if _, _, err := s.ListWebhookDeliveries("alice", repository.WebhookDeliveryQuery{WebhookID: joins.ID}); !errors.Is(err, repository.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound for a member, got %v", err)
+	}
+
+	if err := s.DeleteWebhook(all.ID, "owner"); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "alice", Content: "anyone?"})
+	s.dispatchWebhooks(ctx)
+	if requests := receiver.take(); len(requests) != 0 {
+		t.Errorf("Expected no deliveries after delete, got %d", len(requests))
+	}
+}
+
+func TestChatService_WebhookRetries(t *testing.T) {
+	s, clock := newWebhookTestService(ChatServiceConfig{WebhookMaxAttempts: 3, WebhookRetryBase: 10 * time.Second})
+	ctx := context.Background()
+	receiver, server := newWebhookReceiver(t)
+	receiver.setStatus(http.StatusInternalServerError)
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	webhook, _ := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: server.URL, Events: []string{model.EventMessageCreated}})
+	s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hello"})
+
+	// Попытки через 0, 10 и еще 20 секунд; третья неудачная переносит
+	// доставку в список недоставленных
+	for i, wait := range []time.Duration{0, 10 * time.Second, 20 * time.Second} {
+		clock.Advance(wait - time.Second/2)
+		s.dispatchWebhooks(ctx)
+		if i > 0 && len(receiver.take()) != 0 {
+			t.Fatalf("Expected attempt %d to wait for the backoff", i+1)
+		}
+
+		clock.Advance(time.Second / 2)
+		s.dispatchWebhooks(ctx)
+		if requests := receiver.take(); len(requests) != 1 {
+			t.Fatalf("Expected attempt %d, got %d requests", i+1, len(requests))
+		}
+	}
+
+	clock.Advance(time.Hour)
+	s.dispatchWebhooks(ctx)
+	if requests := receiver.take(); len(requests) != 0 {
+		t.Fatalf("Expected no attempts after the last one, got %d", len(requests))
+	}
+
+	dead, _, err := s.ListWebhookDeliveries("owner", repository.WebhookDeliveryQuery{WebhookID: webhook.ID, Status: model.WebhookDeliveryDead})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].ResponseStatus != http.StatusInternalServerError || dead[0].LastError == "" {
+		t.Fatalf("Expected 1 dead delivery after 3 attempts, got %+v", dead)
+	}
+
+	if _, err := s.RetryWebhookDelivery(dead[0].ID, "owner"); err != nil {
+		t.Fatalf("RetryWebhookDelivery failed: %v", err)
+	}
+	if _, err := s.RetryWebhookDelivery(dead[0].ID, "owner"); !errors.Is(err, ErrDeliveryNotDead) {
+		t.Errorf("Expected ErrDeliveryNotDead for a pending delivery, got %v", err)
+	}
+
+	receiver.setStatus(http.StatusNoContent)
+	s.dispatchWebhooks(ctx)
+	if requests := receiver.take(); len(requests) != 1 || requests[0].header.Get(WebhookDeliveryHeader) != dead[0].ID {
+		t.Fatalf("Expected the retried delivery to be sent, got %d requests", len(requests))
+	}
+
+	delivery, _ := s.chatRepository.GetWebhookDelivery(dead[0].ID)
+	if delivery.Status != model.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.LastError != "" {
+		t.Errorf("Unexpected delivery after redrive: %+v", delivery)
+	}
+}
+
+func TestChatService_WebhookAddresses(t *testing.T) {
+	s := newTestChatService()
+	ctx := context.Background()
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+
+	internal := []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://172.16.0.1/hook",
+		"https://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://224.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[::]/hook",
+		"http://[ff02::1]/hook",
+		"http://0.1.2.3/hook",
+		"http://100.64.0.1/hook",
+		"http://198.18.0.1/hook",
+		"http://240.0.0.1/hook",
+		"http://[::ffff:100.100.100.200]/hook",
+		"http://[64:ff9b::a00:1]/hook",
+	}
+	for _, url := range internal {
+		if _, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: url}); !errors.Is(err, ErrWebhookAddress) {
+			t.Errorf("Expected ErrWebhookAddress for %s, got %v", url, err)
+		}
+	}
+
+	if _, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: "https://93.184.216.34/hook"}); err != nil {
+		t.Errorf("Expected a public address to be accepted, got %v", err)
+	}
+
+	// Клиент доставки проверяет адрес соединения: имя могло начать указывать
+	// во внутренний адрес уже после регистрации
+	_, server := newWebhookReceiver(t)
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+	if _, err := s.webhookClient.Do(request); !errors.Is(err, ErrWebhookAddress) {
+		t.Errorf("Expected the client to refuse a loopback connection, got %v", err)
+	}
+}
+
+// countingWebhookRepository считает запросы webhook'ов чата
+type countingWebhookRepository struct {
+	repository.ChatRepository
+	calls int
+}
+
+func (r *countingWebhookRepository) GetWebhooks(chatID string) ([]*model.Webhook, error) {
+	r.calls++
+	return r.ChatRepository.GetWebhooks(chatID)
+}
+
+func TestChatService_WebhookCache(t *testing.T) {
+	repo := &countingWebhookRepository{ChatRepository: repository.NewInMemoryChatRepository()}
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewChatService(repo, newTestUserDirectory(), ChatServiceConfig{})
+	s.now = clock.Now
+	s.webhooks.now = clock.Now
+	s.webhookAddressAllowed = func(net.IP) bool { return true }
+	ctx := context.Background()
+	receiver, server := newWebhookReceiver(t)
+
+	chat, _ := s.CreateChat(CreateChatInput{Name: "general", CreatedBy: "owner"})
+	send := func() {
+		t.Helper()
+		if _, err := s.SendMessage(ctx, SendMessageInput{ChatID: chat.ID, UserID: "owner", Content: "hello"}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+
+	// Отсутствие webhook'ов тоже кэшируется
+	send()
+	send()
+	if repo.calls != 1 {
+		t.Errorf("Expected webhooks to be loaded once, got %d queries", repo.calls)
+	}
+
+	// Новый webhook получает события сразу, без ожидания webhookCacheTTL
+	webhook, err := s.CreateWebhook(ctx, CreateWebhookInput{ChatID: chat.ID, UserID: "owner", URL: server.URL})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	send()
+	if err := s.dispatchWebhooks(ctx); err != nil {
+		t.Fatalf("dispatchWebhooks failed: %v", err)
+	}
+	if received := receiver.take(); len(received) != 1 {
+		t.Errorf("Expected the new webhook to get 1 event, got %d", len(received))
+	}
+
+	// Webhook, удаленный другим экземпляром сервиса, получает события
+	// до истечения кэша, но его доставки не остаются в очереди
+	if err := repo.DeleteWebhook(webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	send()
+	due, _ := repo.GetDueWebhookDeliveries(clock.Now(), 10)
+	if len(due) != 1 {
+		t.Fatalf("Expected a delivery from the cached webhook, got %d", len(due))
+	}
+	if err := s.dispatchWebhooks(ctx); err != nil {
+		t.Fatalf("dispatchWebhooks failed: %v", err)
+	}
+	if delivery, _ := repo.GetWebhookDelivery(due[0].ID); delivery.Status != model.WebhookDeliveryDead {
+		t.Errorf("Expected the orphaned delivery to leave the queue, got %s", delivery.Status)
+	}
+
+	clock.Advance(webhookCacheTTL)
+	calls := repo.calls
+	send()
+	if repo.calls != calls+1 {
+		t.Errorf("Expected the cache to expire after %v", webhookCacheTTL)
+	}
+	if due, _ := repo.GetDueWebhookDeliveries(clock.Now(), 10); len(due) != 0 {
+		t.Errorf("Expected no deliveries after the cache expired, got %d", len(due))
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	base := 10 * time.Second
+	cases := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		30: maxWebhookRetryDelay,
+	}
+	for attempts, want := range cases {
+		if got := webhookRetryDelay(base, attempts); got != want {
+			t.Errorf("webhookRetryDelay(%v, %d) = %v, want %v", base, attempts, got, want)
+		}
+	}
+}
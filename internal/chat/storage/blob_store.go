@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Ошибки хранилища
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrInvalidKey   = errors.New("invalid blob key")
+)
+
+// BlobStore хранит содержимое вложений. Ключ - hex SHA-256 содержимого,
+// поэтому одинаковые файлы хранятся один раз, а повторный Put с тем же
+// ключом безопасен.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64) error
+	// Get возвращает содержимое блоба; вызывающий обязан закрыть его
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey проверяет, что ключ - hex SHA-256 в нижнем регистре.
+// Это же исключает выход за пределы каталога хранилища.
+func validKey(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func keyOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// forEachStore запускает тест для локального хранилища и S3 поверх fakeS3
+func forEachStore(t *testing.T, test func(t *testing.T, store BlobStore)) {
+	t.Run("local", func(t *testing.T) {
+		store, err := NewLocalBlobStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewLocalBlobStore failed: %v", err)
+		}
+		test(t, store)
+	})
+	t.Run("s3", func(t *testing.T) {
+		server := httptest.NewServer(newFakeS3(t, "chat-blobs"))
+		defer server.Close()
+
+		store, err := NewS3BlobStore(S3Config{
+			Endpoint:  server.URL,
+			Bucket:    "chat-blobs",
+			AccessKey: "access",
+			SecretKey: "secret",
+			Prefix:    "attachments/",
+		}, server.Client())
+		if err != nil {
+			t.Fatalf("NewS3BlobStore failed: %v", err)
+		}
+		test(t, store)
+	})
+}
+
+func TestBlobStore_PutGetDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		ctx := context.Background()
+		content := "hello, attachments"
+		key := keyOf(content)
+
+		if exists, err := store.Exists(ctx, key); err != nil || exists {
+			t.Fatalf("Expected missing blob, got %v, %v", exists, err)
+		}
+
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Expected ErrBlobNotFound, got %v", err)
+		}
+
+		if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+
+		// Повторная запись того же содержимого безопасна
+		if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+			t.Fatalf("Repeated Put failed: %v", err)
+		}
+
+		if exists, err := store.Exists(ctx, key); err != nil || !exists {
+			t.Fatalf("Expected blob to exist, got %v, %v", exists, err)
+		}
+
+		reader, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+
+		if string(data) != content {
+			t.Errorf("Expected %q, got %q", content, data)
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Errorf("Deleting missing blob should succeed, got %v", err)
+		}
+		if exists, _ := store.Exists(ctx, key); exists {
+			t.Error("Expected blob to be deleted")
+		}
+	})
+}
+
+func TestBlobStore_RejectsInvalidKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		for _, key := range []string{"", "../../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63)} {
+			if err := store.Put(context.Background(), key, strings.NewReader("x"), 1); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Expected ErrInvalidKey for %q, got %v", key, err)
+			}
+		}
+	})
+}
+
+// fakeS3 - минимальная замена S3 для тестов: хранит объекты одного бакета
+// в памяти и проверяет подпись запросов
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) *fakeS3 {
+	return &fakeS3{t: t, bucket: bucket, objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.validSignature(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			http.Error(w, "MissingContentLength", http.StatusLengthRequired)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case http.MethodGet, http.MethodHead:
+		data, exists := f.objects[key]
+		if !exists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			io.Copy(w, bytes.NewReader(data))
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// validSignature независимо пересчитывает подпись SigV4 с известным секретом
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || len(amzDate) != 16 {
+		return false
+	}
+
+	canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		r.Header.Get("X-Amz-Content-Sha256")
+	scope := amzDate[:8] + "/us-east-1/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4secret")
+	for _, part := range []string{amzDate[:8], "us-east-1", "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	return strings.HasSuffix(auth, "Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
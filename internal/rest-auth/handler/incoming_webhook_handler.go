@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	authmodel "golang-chat/internal/auth/model"
+	"golang-chat/internal/chat/middleware"
+	"golang-chat/internal/rest-auth/model"
+	"golang-chat/internal/rest-auth/validation"
+	"golang-chat/proto/chat"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// incomingWebhookTimeout - сколько ждать ответа Chat Service
+const incomingWebhookTimeout = 10 * time.Second
+
+// IncomingWebhookHandler публикует в чаты сообщения внешних систем
+// от имени ботов. Права бота проверяет Chat Service: бот должен быть
+// участником чата, куда он пишет.
+type IncomingWebhookHandler struct {
+	chatClient chat.ChatServiceClient
+	validator  *validation.Validation
+}
+
+// NewIncomingWebhookHandler создает новый экземпляр IncomingWebhookHandler
+func NewIncomingWebhookHandler(chatClient chat.ChatServiceClient, validator *validation.Validation) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{
+		chatClient: chatClient,
+		validator:  validator,
+	}
+}
+
+// Post публикует сообщение в чат :chat_id. Бот передает свой токен
+// в заголовке "Authorization: Bearer bot_...".
+func (h *IncomingWebhookHandler) Post(c *fiber.Ctx) error {
+	// 1. Токен бота
+	token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.HasPrefix(token, authmodel.BotTokenPrefix) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized: Bot token required",
+		})
+	}
+
+	// 2. Парсинг и валидация JSON
+	var req model.IncomingWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.validator.ValidateIncomingWebhookRequest(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Validation error: " + err.Error(),
+		})
+	}
+
+	// 3. Отправка сообщения от имени бота
+	ctx, cancel := context.WithTimeout(c.UserContext(), incomingWebhookTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, middleware.AuthorizationHeader, "Bearer "+token)
+
+	resp, err := h.chatClient.SendMessage(ctx, &chat.SendMessageRequest{
+		ChatId:          c.Params("chat_id"),
+		Content:         req.Text,
+		ClientMessageId: req.ClientMessageID,
+	})
+	if err != nil {
+		return chatServiceError(c, err)
+	}
+
+	// Ошибки Chat Service: бот не участник, чат в архиве, лимиты и т.п.
+	if resp.Error != "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": resp.Error,
+		})
+	}
+
+	// Ответ без сообщения означает команду, выполненную без него. Ботам
+	// такие команды (/topic, /invite) Chat Service не разрешает, но
+	// обработчик не полагается на это.
+	if resp.Message == nil {
+		c.Status(fiber.StatusAccepted)
+		return nil
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.IncomingWebhookResponse{
+		MessageID: resp.Message.Id,
+		ChatID:    resp.Message.ChatId,
+		Seq:       resp.Message.Seq,
+		CreatedAt: resp.Message.CreatedAt,
+	})
+}
+
+// chatServiceError переводит статус gRPC ответа Chat Service в HTTP ответ
+func chatServiceError(c *fiber.Ctx, err error) error {
+	st := status.Convert(err)
+
+	switch st.Code() {
+	case codes.Unauthenticated:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized: Invalid bot token",
+		})
+	case codes.PermissionDenied:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden: " + st.Message(),
+		})
+	case codes.InvalidArgument:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": st.Message(),
+		})
+	case codes.NotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": st.Message(),
+		})
+	case codes.ResourceExhausted:
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(st)))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": st.Message(),
+		})
+	default:
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Chat service unavailable",
+		})
+	}
+}
+
+// retryAfterSeconds возвращает время ожидания из деталей RetryInfo,
+// округленное вверх до целых секунд; без деталей - одну секунду
+func retryAfterSeconds(st *status.Status) int {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			return max(int(math.Ceil(info.RetryDelay.AsDuration().Seconds())), 1)
+		}
+	}
+	return 1
+}
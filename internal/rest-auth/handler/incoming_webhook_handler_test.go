@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-chat/internal/rest-auth/model"
+	"golang-chat/internal/rest-auth/validation"
+	"golang-chat/proto/chat"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// fakeChatClient отвечает на SendMessage заданными resp и err
+type fakeChatClient struct {
+	chat.ChatServiceClient
+	resp *chat.SendMessageResponse
+	err  error
+
+	authorization string
+	request       *chat.SendMessageRequest
+}
+
+func (c *fakeChatClient) SendMessage(ctx context.Context, req *chat.SendMessageRequest, opts ...grpc.CallOption) (*chat.SendMessageResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		c.authorization = values[0]
+	}
+	c.request = req
+	return c.resp, c.err
+}
+
+func postIncomingWebhook(t *testing.T, client *fakeChatClient, authorization, body string) *http.Response {
+	t.Helper()
+
+	app := fiber.New()
+	app.Post("/api/webhooks/incoming/:chat_id", NewIncomingWebhookHandler(client, validation.NewCustomValidator()).Post)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/incoming/chat-1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test failed: %v", err)
+	}
+	return resp
+}
+
+func TestIncomingWebhookHandler_Post(t *testing.T) {
+	message := &chat.Message{Id: "message-1", ChatId: "chat-1", Seq: 7, CreatedAt: "2024-01-01T12:00:00Z"}
+	client := &fakeChatClient{resp: &chat.SendMessageResponse{Message: message}}
+
+	resp := postIncomingWebhook(t, client, "Bearer bot_secret", `{"text":"build passed","client_message_id":"build-42"}`)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	var body model.IncomingWebhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.MessageID != "message-1" || body.ChatID != "chat-1" || body.Seq != 7 {
+		t.Errorf("Unexpected response: %+v", body)
+	}
+	if client.authorization != "Bearer bot_secret" {
+		t.Errorf("Expected the bot token to be forwarded, got %q", client.authorization)
+	}
+	if client.request.ChatId != "chat-1" || client.request.Content != "build passed" || client.request.ClientMessageId != "build-42" {
+		t.Errorf("Unexpected SendMessage request: %+v", client.request)
+	}
+}
+
+func TestIncomingWebhookHandler_CommandWithoutMessage(t *testing.T) {
+	client := &fakeChatClient{resp: &chat.SendMessageResponse{}}
+
+	resp := postIncomingWebhook(t, client, "Bearer bot_secret", `{"text":"/topic Release 2.0"}`)
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Errorf("Expected 202 for a command without a message, got %d", resp.StatusCode)
+	}
+	if body, _ := io.ReadAll(resp.Body); len(body) != 0 {
+		t.Errorf("Expected an empty body, got %q", body)
+	}
+}
+
+func TestIncomingWebhookHandler_Unauthorized(t *testing.T) {
+	for _, authorization := range []string{"", "Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig", "bot_secret"} {
+		client := &fakeChatClient{}
+		resp := postIncomingWebhook(t, client, authorization, `{"text":"hi"}`)
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401 for %q, got %d", authorization, resp.StatusCode)
+		}
+		if client.request != nil {
+			t.Errorf("Expected no call to Chat Service for %q", authorization)
+		}
+	}
+
+	resp := postIncomingWebhook(t, &fakeChatClient{}, "Bearer bot_secret", `{"text":""}`)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty text, got %d", resp.StatusCode)
+	}
+}
+
+func TestIncomingWebhookHandler_ChatServiceErrors(t *testing.T) {
+	limited, _ := status.New(codes.ResourceExhausted, "rate limited").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(2500 * time.Millisecond)})
+
+	tests := []struct {
+		name       string
+		resp       *chat.SendMessageResponse
+		err        error
+		statusCode int
+		retryAfter string
+	}{
+		{name: "invalid token", err: status.Error(codes.Unauthenticated, "invalid bot token"), statusCode: fiber.StatusUnauthorized},
+		{name: "not allowed", err: status.Error(codes.PermissionDenied, "bots cannot call this method"), statusCode: fiber.StatusForbidden},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "chat_id is required"), statusCode: fiber.StatusBadRequest},
+		{name: "not found", err: status.Error(codes.NotFound, "chat not found"), statusCode: fiber.StatusNotFound},
+		{name: "rate limited", err: limited.Err(), statusCode: fiber.StatusTooManyRequests, retryAfter: "3"},
+		{name: "rate limited without details", err: status.Error(codes.ResourceExhausted, "rate limited"), statusCode: fiber.StatusTooManyRequests, retryAfter: "1"},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "connection refused"), statusCode: fiber.StatusBadGateway},
+		{name: "not a gRPC error", err: errors.New("boom"), statusCode: fiber.StatusBadGateway},
+		{name: "rejected message", resp: &chat.SendMessageResponse{Error: "user is not a participant of this chat"}, statusCode: fiber.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := postIncomingWebhook(t, &fakeChatClient{resp: test.resp, err: test.err}, "Bearer bot_secret", `{"text":"hi"}`)
+			if resp.StatusCode != test.statusCode {
+				t.Errorf("Expected %d, got %d", test.statusCode, resp.StatusCode)
+			}
+			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != test.retryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", test.retryAfter, retryAfter)
+			}
+		})
+	}
+}
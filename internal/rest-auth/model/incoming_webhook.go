@@ -0,0 +1,17 @@
+package model
+
+// IncomingWebhookRequest - сообщение, которое внешняя система публикует
+// в чат от имени бота через входящий webhook
+type IncomingWebhookRequest struct {
+	Text string `json:"text" validate:"required"`
+	// Необязательно: повтор запроса с тем же ключом не создает дубликат
+	ClientMessageID string `json:"client_message_id" validate:"omitempty,max=64"`
+}
+
+// IncomingWebhookResponse - опубликованное сообщение
+type IncomingWebhookResponse struct {
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
+	Seq       int64  `json:"seq"`
+	CreatedAt string `json:"created_at"`
+}
@@ -82,6 +82,14 @@ func (v *Validation) ValidateRefreshTokenRequest(req *model.RefreshTokenRequest)
 	return nil
 }
 
+// Метод для валидации IncomingWebhookRequest
+func (v *Validation) ValidateIncomingWebhookRequest(req *model.IncomingWebhookRequest) error {
+	if err := v.validator.Struct(req); err != nil {
+		return v.translateValidationErrors(err)
+	}
+	return nil
+}
+
 // translateValidationErrors преобразует ошибки валидации в понятные сообщения
 func (v *Validation) translateValidationErrors(err error) error {
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
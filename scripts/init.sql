@@ -48,7 +48,7 @@ CREATE TABLE IF NOT EXISTS messages (
     chat_id UUID REFERENCES chats(id) ON DELETE CASCADE,
     user_id UUID REFERENCES users(id) ON DELETE SET NULL,
     content TEXT NOT NULL,
-    message_type VARCHAR(20) DEFAULT 'text' CHECK (message_type IN ('text', 'image', 'file', 'system')),
+    message_type VARCHAR(20) DEFAULT 'text' CHECK (message_type IN ('text', 'image', 'file', 'poll', 'action', 'command', 'system')),
     metadata JSONB, -- для дополнительных данных (размер файла, тип и т.д.)
     reply_to UUID REFERENCES messages(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT NOW(),